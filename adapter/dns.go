@@ -0,0 +1,90 @@
+package adapter
+
+import (
+	"net/netip"
+	"time"
+
+	"github.com/sagernet/sing-dns"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/sing/common/observable"
+)
+
+type DNSGroupTransport interface {
+	dns.Transport
+	UpstreamStatistics() []DNSUpstreamStatistics
+}
+
+type DNSUpstreamStatistics struct {
+	Server         string
+	Queries        int64
+	Wins           int64
+	Failures       int64
+	LastLatency    time.Duration
+	AverageLatency time.Duration
+}
+
+type DNSCacheStorage interface {
+	DNSCacheEntries() map[string][]byte
+	DNSCacheUpdate(entries map[string][]byte, deletedKeys []string) error
+	DNSCacheReset() error
+}
+
+type DNSQueryLog interface {
+	observable.Observable[DNSQueryRecord]
+	Records() []DNSQueryRecord
+	Statistics() DNSQueryStatistics
+	Reset()
+}
+
+type DNSQueryRecord struct {
+	Time      time.Time
+	Inbound   string
+	Client    M.Socksaddr
+	Domain    string
+	QueryType string
+	RuleIndex int
+	Rule      string
+	Transport string
+	Latency   time.Duration
+	RCode     string
+	Cached    bool
+	Answers   []string
+	Error     string
+}
+
+type DNSQueryStatistics struct {
+	Total      DNSQueryCounter
+	Transports map[string]DNSQueryCounter
+	Rules      map[string]DNSQueryCounter
+}
+
+type DNSQueryCounter struct {
+	Queries        int64
+	Cached         int64
+	Failures       int64
+	AverageLatency time.Duration
+}
+
+type DNSConnectionTransport interface {
+	dns.Transport
+	ConnectionStatistics() DNSConnectionStatistics
+}
+
+type DNSConnectionStatistics struct {
+	Connections   int64
+	Queries       int64
+	ReusedQueries int64
+	Failures      int64
+}
+
+type DHCPTransport interface {
+	dns.Transport
+	DHCPInformation() DHCPInformation
+}
+
+type DHCPInformation struct {
+	Interface     string
+	Servers       []netip.Addr
+	SearchDomains []string
+	Routers       []netip.Addr
+}
@@ -16,6 +16,8 @@ type ClashServer interface {
 	Mode() string
 	StoreSelected() bool
 	StoreFakeIP() bool
+	StoreDNS() bool
+	StoreLimit() bool
 	CacheFile() ClashCacheFile
 	HistoryStorage() *urltest.HistoryStorage
 	RoutedConnection(ctx context.Context, conn net.Conn, metadata InboundContext, matchedRule Rule) (net.Conn, Tracker)
@@ -26,6 +28,8 @@ type ClashCacheFile interface {
 	LoadSelected(group string) string
 	StoreSelected(group string, selected string) error
 	FakeIPStorage
+	DNSCacheStorage
+	LimitStorage
 }
 
 type Tracker interface {
@@ -67,6 +71,7 @@ type V2RayServer interface {
 type V2RayStatsService interface {
 	RoutedConnection(inbound string, outbound string, user string, conn net.Conn) net.Conn
 	RoutedPacketConnection(inbound string, outbound string, user string, conn N.PacketConn) N.PacketConn
+	LimitReached(inbound string, user string, limit string)
 }
 
 func RealOutbound(router Router, outbound Outbound) (Outbound, error) {
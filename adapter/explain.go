@@ -0,0 +1,19 @@
+package adapter
+
+type RouteExplanation struct {
+	Metadata     InboundContext
+	ResolveError error
+	Rules        []RuleExplanation
+	MatchedRule  int
+	Outbound     string
+	RealOutbound string
+}
+
+type RuleExplanation struct {
+	Index    int
+	Type     string
+	Rule     string
+	Outbound string
+	Matched  bool
+	Reason   string
+}
@@ -10,8 +10,10 @@ import (
 type FakeIPStore interface {
 	Service
 	Contains(address netip.Addr) bool
+	Excluded(domain string) bool
 	Create(domain string, isIPv6 bool) (netip.Addr, error)
 	Lookup(address netip.Addr) (string, bool)
+	Mappings() map[netip.Addr]string
 	Reset() error
 }
 
@@ -22,6 +24,7 @@ type FakeIPStorage interface {
 	FakeIPStoreAsync(address netip.Addr, domain string, logger logger.Logger)
 	FakeIPLoad(address netip.Addr) (string, bool)
 	FakeIPLoadDomain(domain string, isIPv6 bool) (netip.Addr, bool)
+	FakeIPMappings() map[netip.Addr]string
 	FakeIPReset() error
 }
 
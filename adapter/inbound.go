@@ -4,6 +4,7 @@ import (
 	"context"
 	"net"
 	"net/netip"
+	"time"
 
 	"github.com/sagernet/sing-box/common/process"
 	"github.com/sagernet/sing-box/option"
@@ -24,6 +25,30 @@ type InjectableInbound interface {
 	NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata InboundContext) error
 }
 
+// UserManager is implemented by multi-user inbounds that accept user changes at runtime.
+type UserManager interface {
+	Inbound
+	Users() any
+	AddUsers(content []byte) error
+	RemoveUsers(names []string) error
+}
+
+// UserStore persists users changed at runtime.
+type UserStore interface {
+	StoreUsers(inboundTag string, users any) error
+}
+
+// LimitStorage persists the traffic quota state of inbound users.
+type LimitStorage interface {
+	LimitQuotas(inboundTag string) map[string]LimitQuota
+	StoreLimitQuotas(inboundTag string, quotas map[string]LimitQuota) error
+}
+
+type LimitQuota struct {
+	Used    uint64
+	ResetAt time.Time
+}
+
 type InboundContext struct {
 	Inbound     string
 	InboundType string
@@ -50,7 +75,8 @@ type InboundContext struct {
 
 	// dns cache
 
-	QueryType uint16
+	QueryType                    uint16
+	IgnoreDestinationIPCIDRMatch bool
 }
 
 type inboundContextKey struct{}
@@ -0,0 +1,15 @@
+package adapter
+
+import "net/netip"
+
+type NetworkState struct {
+	InterfaceName string
+	InterfaceType string
+	Gateways      []netip.Addr
+	WIFIState
+}
+
+type WIFIState struct {
+	SSID  string
+	BSSID string
+}
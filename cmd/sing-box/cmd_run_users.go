@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/badjson"
+	"github.com/sagernet/sing-box/common/json"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+var _ adapter.UserStore = (*configUserStore)(nil)
+
+// configUserStore writes users changed at runtime back to the configuration file declaring the inbound.
+type configUserStore struct {
+	access sync.Mutex
+}
+
+func (s *configUserStore) StoreUsers(inboundTag string, users any) error {
+	s.access.Lock()
+	defer s.access.Unlock()
+	paths, err := configFilePaths()
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		stored, err := storeUsersAt(path, inboundTag, users)
+		if err != nil {
+			return E.Cause(err, "store users at ", path)
+		}
+		if stored {
+			return nil
+		}
+	}
+	return E.New("inbound ", inboundTag, " not found in configuration files")
+}
+
+func configFilePaths() ([]string, error) {
+	var paths []string
+	for _, path := range configPaths {
+		if path == "stdin" {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	for _, directory := range configDirectories {
+		entries, err := os.ReadDir(directory)
+		if err != nil {
+			return nil, E.Cause(err, "read config directory at ", directory)
+		}
+		for _, entry := range entries {
+			if !strings.HasSuffix(entry.Name(), ".json") || entry.IsDir() {
+				continue
+			}
+			paths = append(paths, filepath.Join(directory, entry.Name()))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func storeUsersAt(path string, inboundTag string, users any) (bool, error) {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	originContent, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	content, err := io.ReadAll(json.NewCommentFilter(bytes.NewReader(originContent)))
+	if err != nil {
+		return false, err
+	}
+	var config badjson.JSONObject
+	err = config.UnmarshalJSON(content)
+	if err != nil {
+		return false, E.Cause(err, "decode config")
+	}
+	inbounds, _ := config.Get("inbounds")
+	inboundArray, _ := inbounds.(badjson.JSONArray)
+	var inbound *badjson.JSONObject
+	for _, rawInbound := range inboundArray {
+		inboundObject, isObject := rawInbound.(*badjson.JSONObject)
+		if !isObject {
+			continue
+		}
+		if tag, _ := inboundObject.Get("tag"); tag == inboundTag {
+			inbound = inboundObject
+			break
+		}
+	}
+	if inbound == nil {
+		return false, nil
+	}
+	// comments can not be preserved by re-encoding
+	if !bytes.Equal(content, originContent) {
+		return false, E.New("configuration file with comments is not rewritten")
+	}
+	inbound.Put("users", users)
+	content, err = json.Marshal(config)
+	if err != nil {
+		return false, E.Cause(err, "encode config")
+	}
+	buffer := new(bytes.Buffer)
+	err = json.Indent(buffer, content, "", "  ")
+	if err != nil {
+		return false, E.Cause(err, "encode config")
+	}
+	buffer.WriteByte('\n')
+	err = writeFileAtomic(path, buffer.Bytes(), fileInfo.Mode().Perm())
+	if err != nil {
+		return false, E.Cause(err, "write config")
+	}
+	return true, nil
+}
+
+func writeFileAtomic(path string, content []byte, perm os.FileMode) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tempPath := file.Name()
+	_, err = file.Write(content)
+	if err == nil {
+		err = file.Chmod(perm)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/process"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	commandRouteFlagSource      string
+	commandRouteFlagNetwork     string
+	commandRouteFlagInbound     string
+	commandRouteFlagProcessPath string
+	commandRouteFlagPackageName string
+	commandRouteFlagUser        string
+	commandRouteFlagUserID      int32
+	commandRouteFlagResolve     bool
+)
+
+var commandRoute = &cobra.Command{
+	Use:   "route [destination]",
+	Short: "Explain how a connection to the destination will be routed",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := explainRoute(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandRoute.Flags().StringVarP(&commandRouteFlagSource, "source", "s", "", "source address")
+	commandRoute.Flags().StringVarP(&commandRouteFlagNetwork, "network", "n", "tcp", "network type")
+	commandRoute.Flags().StringVarP(&commandRouteFlagInbound, "inbound", "i", "", "inbound tag")
+	commandRoute.Flags().StringVar(&commandRouteFlagProcessPath, "process-path", "", "process path")
+	commandRoute.Flags().StringVar(&commandRouteFlagPackageName, "package-name", "", "android package name")
+	commandRoute.Flags().StringVar(&commandRouteFlagUser, "user", "", "user name")
+	commandRoute.Flags().Int32Var(&commandRouteFlagUserID, "user-id", -1, "user id")
+	commandRoute.Flags().BoolVar(&commandRouteFlagResolve, "resolve", true, "resolve domain destination for ip rules")
+	commandTools.AddCommand(commandRoute)
+}
+
+func explainRoute(destination string) error {
+	metadata := adapter.InboundContext{
+		Inbound:     commandRouteFlagInbound,
+		Network:     commandRouteFlagNetwork,
+		Destination: M.ParseSocksaddr(destination),
+	}
+	if commandRouteFlagSource != "" {
+		metadata.Source = M.ParseSocksaddr(commandRouteFlagSource)
+		if !metadata.Source.IsIP() {
+			return E.New("invalid source address: ", commandRouteFlagSource)
+		}
+	}
+	if commandRouteFlagProcessPath != "" || commandRouteFlagPackageName != "" || commandRouteFlagUser != "" || commandRouteFlagUserID != -1 {
+		metadata.ProcessInfo = &process.Info{
+			ProcessPath: commandRouteFlagProcessPath,
+			PackageName: commandRouteFlagPackageName,
+			User:        commandRouteFlagUser,
+			UserId:      commandRouteFlagUserID,
+		}
+	}
+	instance, err := createPreStartedClient()
+	if err != nil {
+		return err
+	}
+	defer instance.Close()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, C.DNSTimeout)
+	defer cancel()
+	explanation, err := instance.Router().ExplainRoute(ctx, metadata, commandRouteFlagResolve)
+	if err != nil {
+		return err
+	}
+	var output strings.Builder
+	output.WriteString(F.ToString("destination: ", explanation.Metadata.Destination, "\n"))
+	if explanation.Metadata.Domain != "" && explanation.Metadata.Domain != explanation.Metadata.Destination.Fqdn {
+		output.WriteString(F.ToString("domain: ", explanation.Metadata.Domain, "\n"))
+	}
+	if explanation.ResolveError != nil {
+		output.WriteString(F.ToString("resolve failed: ", explanation.ResolveError, "\n"))
+	} else if len(explanation.Metadata.DestinationAddresses) > 0 {
+		output.WriteString(F.ToString("resolved: ", strings.Join(F.MapToString(explanation.Metadata.DestinationAddresses), " "), "\n"))
+	}
+	for _, rule := range explanation.Rules {
+		if rule.Matched && rule.Reason == "" {
+			output.WriteString(F.ToString("match[", rule.Index, "] ", rule.Rule, " => ", rule.Outbound, "\n"))
+		} else {
+			output.WriteString(F.ToString("skip[", rule.Index, "] ", rule.Rule, ": ", rule.Reason, "\n"))
+		}
+	}
+	if explanation.MatchedRule == -1 {
+		output.WriteString(F.ToString("no rule matched, using default outbound\n"))
+	}
+	output.WriteString(F.ToString("outbound: ", explanation.Outbound, "\n"))
+	if explanation.RealOutbound != explanation.Outbound {
+		output.WriteString(F.ToString("real outbound: ", explanation.RealOutbound, "\n"))
+	}
+	_, err = os.Stdout.WriteString(output.String())
+	return err
+}
@@ -7,6 +7,7 @@ var (
 	Unmarshal  = json.Unmarshal
 	NewEncoder = json.NewEncoder
 	NewDecoder = json.NewDecoder
+	Indent     = json.Indent
 )
 
 type (
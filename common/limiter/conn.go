@@ -0,0 +1,198 @@
+package limiter
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"sync"
+
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"golang.org/x/time/rate"
+)
+
+// Session is an accepted connection of an inbound.
+type Session struct {
+	limiter *Limiter
+	user    *userLimiter
+	source  netip.Addr
+	once    sync.Once
+}
+
+type sessionKey struct{}
+
+// ContextWithSession marks connections carried by a limited connection, such as multiplexed streams, so that they are
+// not limited again.
+func ContextWithSession(ctx context.Context, session *Session) context.Context {
+	return context.WithValue(ctx, (*sessionKey)(nil), session)
+}
+
+func SessionFromContext(ctx context.Context) *Session {
+	session, _ := ctx.Value((*sessionKey)(nil)).(*Session)
+	return session
+}
+
+func (s *Session) Release() {
+	s.once.Do(func() {
+		s.limiter.release(s.user, s.source)
+	})
+}
+
+func (s *Session) uploadLimiters() []*rate.Limiter {
+	var limiters []*rate.Limiter
+	if s.limiter.upload != nil {
+		limiters = append(limiters, s.limiter.upload)
+	}
+	if s.user != nil && s.user.upload != nil {
+		limiters = append(limiters, s.user.upload)
+	}
+	return limiters
+}
+
+func (s *Session) downloadLimiters() []*rate.Limiter {
+	var limiters []*rate.Limiter
+	if s.limiter.download != nil {
+		limiters = append(limiters, s.limiter.download)
+	}
+	if s.user != nil && s.user.download != nil {
+		limiters = append(limiters, s.user.download)
+	}
+	return limiters
+}
+
+func (s *Session) account(ctx context.Context, limiters []*rate.Limiter, n int) error {
+	if s.user != nil {
+		limitErr := s.user.addTraffic(n)
+		if s.user.quota > 0 {
+			s.limiter.queueSave()
+		}
+		if limitErr != nil {
+			s.limiter.handler(limitErr)
+			return limitErr
+		}
+	}
+	for _, limiter := range limiters {
+		for remaining := n; remaining > 0; {
+			chunk := remaining
+			if burst := limiter.Burst(); chunk > burst {
+				chunk = burst
+			}
+			err := limiter.WaitN(ctx, chunk)
+			if err != nil {
+				return err
+			}
+			remaining -= chunk
+		}
+	}
+	return nil
+}
+
+// NewConn limits the connection, reads are uplink and writes are downlink traffic.
+func (s *Session) NewConn(ctx context.Context, conn net.Conn) net.Conn {
+	upload, download := s.uploadLimiters(), s.downloadLimiters()
+	if len(upload) == 0 && len(download) == 0 && s.user == nil {
+		return conn
+	}
+	return &limitedConn{
+		ExtendedConn: bufio.NewExtendedConn(conn),
+		ctx:          ctx,
+		session:      s,
+		upload:       upload,
+		download:     download,
+	}
+}
+
+func (s *Session) NewPacketConn(ctx context.Context, conn N.PacketConn) N.PacketConn {
+	upload, download := s.uploadLimiters(), s.downloadLimiters()
+	if len(upload) == 0 && len(download) == 0 && s.user == nil {
+		return conn
+	}
+	return &limitedPacketConn{
+		PacketConn: conn,
+		ctx:        ctx,
+		session:    s,
+		upload:     upload,
+		download:   download,
+	}
+}
+
+type limitedConn struct {
+	N.ExtendedConn
+	ctx      context.Context
+	session  *Session
+	upload   []*rate.Limiter
+	download []*rate.Limiter
+}
+
+func (c *limitedConn) Read(p []byte) (n int, err error) {
+	n, err = c.ExtendedConn.Read(p)
+	if n > 0 {
+		accountErr := c.session.account(c.ctx, c.upload, n)
+		if accountErr != nil {
+			return 0, accountErr
+		}
+	}
+	return
+}
+
+func (c *limitedConn) ReadBuffer(buffer *buf.Buffer) error {
+	err := c.ExtendedConn.ReadBuffer(buffer)
+	if err != nil {
+		return err
+	}
+	return c.session.account(c.ctx, c.upload, buffer.Len())
+}
+
+func (c *limitedConn) Write(p []byte) (n int, err error) {
+	err = c.session.account(c.ctx, c.download, len(p))
+	if err != nil {
+		return
+	}
+	return c.ExtendedConn.Write(p)
+}
+
+func (c *limitedConn) WriteBuffer(buffer *buf.Buffer) error {
+	err := c.session.account(c.ctx, c.download, buffer.Len())
+	if err != nil {
+		buffer.Release()
+		return err
+	}
+	return c.ExtendedConn.WriteBuffer(buffer)
+}
+
+func (c *limitedConn) Upstream() any {
+	return c.ExtendedConn
+}
+
+type limitedPacketConn struct {
+	N.PacketConn
+	ctx      context.Context
+	session  *Session
+	upload   []*rate.Limiter
+	download []*rate.Limiter
+}
+
+func (c *limitedPacketConn) ReadPacket(buffer *buf.Buffer) (destination M.Socksaddr, err error) {
+	destination, err = c.PacketConn.ReadPacket(buffer)
+	if err != nil {
+		return
+	}
+	err = c.session.account(c.ctx, c.upload, buffer.Len())
+	return
+}
+
+func (c *limitedPacketConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	err := c.session.account(c.ctx, c.download, buffer.Len())
+	if err != nil {
+		buffer.Release()
+		return err
+	}
+	return c.PacketConn.WritePacket(buffer, destination)
+}
+
+func (c *limitedPacketConn) Upstream() any {
+	return c.PacketConn
+}
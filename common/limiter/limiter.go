@@ -0,0 +1,307 @@
+package limiter
+
+import (
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/atomic"
+	"github.com/sagernet/sing/common/logger"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	LimitExpire      = "expire"
+	LimitQuota       = "quota"
+	LimitConnections = "connections"
+	LimitIPs         = "ips"
+)
+
+const (
+	minBurst       = 64 * 1024
+	quotaSaveDelay = 10 * time.Second
+)
+
+// LimitError is returned when a connection is rejected or closed by a limit.
+type LimitError struct {
+	User  string
+	Limit string
+}
+
+func (e *LimitError) Error() string {
+	var message string
+	switch e.Limit {
+	case LimitExpire:
+		message = "expired"
+	case LimitQuota:
+		message = "traffic quota exceeded"
+	case LimitConnections:
+		message = "too many connections"
+	case LimitIPs:
+		message = "too many IPs"
+	default:
+		message = e.Limit + " limit reached"
+	}
+	if e.User == "" {
+		return "inbound: " + message
+	}
+	return "user " + e.User + ": " + message
+}
+
+// Limiter enforces the limits of an inbound and of its users.
+type Limiter struct {
+	upload         *rate.Limiter
+	download       *rate.Limiter
+	maxConnections int
+	handler        func(err *LimitError)
+
+	access      sync.Mutex
+	connections int
+	users       map[string]*userLimiter
+
+	storage     adapter.LimitStorage
+	storageTag  string
+	logger      logger.Logger
+	saveAccess  sync.Mutex
+	writeAccess sync.Mutex
+	saveTimer   *time.Timer
+	closed      bool
+}
+
+type userLimiter struct {
+	name               string
+	upload             *rate.Limiter
+	download           *rate.Limiter
+	quota              uint64
+	quotaResetInterval time.Duration
+	expireAt           time.Time
+	maxConnections     int
+	maxIPs             int
+
+	connections int
+	ips         map[netip.Addr]int
+
+	used         atomic.Uint64
+	quotaAccess  sync.Mutex
+	quotaResetAt time.Time
+}
+
+// New creates a limiter, handler is called every time a connection is rejected or closed by a limit.
+func New(options option.InboundLimitOptions, handler func(err *LimitError)) *Limiter {
+	limiter := &Limiter{
+		upload:         newRateLimiter(options.UpMbps),
+		download:       newRateLimiter(options.DownMbps),
+		maxConnections: options.MaxConnections,
+		handler:        handler,
+		users:          make(map[string]*userLimiter),
+	}
+	now := time.Now()
+	for _, userOptions := range options.Users {
+		for _, name := range userOptions.Name {
+			user := &userLimiter{
+				name:               name,
+				upload:             newRateLimiter(userOptions.UpMbps),
+				download:           newRateLimiter(userOptions.DownMbps),
+				quota:              userOptions.QuotaMB * 1024 * 1024,
+				quotaResetInterval: time.Duration(userOptions.QuotaResetInterval),
+				maxConnections:     userOptions.MaxConnections,
+				maxIPs:             userOptions.MaxIPs,
+				ips:                make(map[netip.Addr]int),
+			}
+			if userOptions.ExpireAt != nil {
+				user.expireAt = *userOptions.ExpireAt
+			}
+			if user.quotaResetInterval > 0 {
+				user.quotaResetAt = now.Add(user.quotaResetInterval)
+			}
+			limiter.users[name] = user
+		}
+	}
+	return limiter
+}
+
+// SetStorage restores the quota state of users from storage and saves it back periodically,
+// it must be called before the limiter is used.
+func (l *Limiter) SetStorage(storage adapter.LimitStorage, inboundTag string, logger logger.Logger) {
+	l.storage = storage
+	l.storageTag = inboundTag
+	l.logger = logger
+	quotas := storage.LimitQuotas(inboundTag)
+	now := time.Now()
+	for name, user := range l.users {
+		quota, loaded := quotas[name]
+		if !loaded || user.quota == 0 {
+			continue
+		}
+		user.used.Store(quota.Used)
+		// a stored reset time beyond a shortened interval is not kept
+		if user.quotaResetInterval > 0 && !quota.ResetAt.IsZero() && !quota.ResetAt.After(now.Add(user.quotaResetInterval)) {
+			user.quotaResetAt = quota.ResetAt
+		}
+	}
+}
+
+func newRateLimiter(mbps int) *rate.Limiter {
+	if mbps <= 0 {
+		return nil
+	}
+	bytesPerSecond := mbps * 125000
+	burst := bytesPerSecond
+	if burst < minBurst {
+		burst = minBurst
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), burst)
+}
+
+// Acquire checks the limits for a new connection of the user from source,
+// the returned session must be released when the connection is closed.
+func (l *Limiter) Acquire(user string, source netip.Addr) (*Session, error) {
+	l.access.Lock()
+	defer l.access.Unlock()
+	userLimiter := l.users[user]
+	if userLimiter != nil {
+		err := userLimiter.check(time.Now())
+		if err != nil {
+			l.handler(err)
+			return nil, err
+		}
+		if userLimiter.maxConnections > 0 && userLimiter.connections >= userLimiter.maxConnections {
+			return nil, l.limitError(user, LimitConnections)
+		}
+		if userLimiter.maxIPs > 0 && source.IsValid() && userLimiter.ips[source] == 0 && len(userLimiter.ips) >= userLimiter.maxIPs {
+			return nil, l.limitError(user, LimitIPs)
+		}
+	}
+	if l.maxConnections > 0 && l.connections >= l.maxConnections {
+		return nil, l.limitError("", LimitConnections)
+	}
+	l.connections++
+	if userLimiter != nil {
+		userLimiter.connections++
+		if source.IsValid() {
+			userLimiter.ips[source]++
+		}
+	}
+	return &Session{
+		limiter: l,
+		user:    userLimiter,
+		source:  source,
+	}, nil
+}
+
+func (l *Limiter) limitError(user string, limit string) *LimitError {
+	err := &LimitError{User: user, Limit: limit}
+	l.handler(err)
+	return err
+}
+
+func (l *Limiter) release(user *userLimiter, source netip.Addr) {
+	l.access.Lock()
+	defer l.access.Unlock()
+	l.connections--
+	if user != nil {
+		user.connections--
+		if source.IsValid() {
+			if user.ips[source] <= 1 {
+				delete(user.ips, source)
+			} else {
+				user.ips[source]--
+			}
+		}
+	}
+}
+
+// queueSave writes the quota state to storage after quotaSaveDelay.
+func (l *Limiter) queueSave() {
+	if l.storage == nil {
+		return
+	}
+	l.saveAccess.Lock()
+	defer l.saveAccess.Unlock()
+	if l.saveTimer == nil && !l.closed {
+		l.saveTimer = time.AfterFunc(quotaSaveDelay, l.save)
+	}
+}
+
+func (l *Limiter) save() {
+	l.writeAccess.Lock()
+	defer l.writeAccess.Unlock()
+	l.saveAccess.Lock()
+	if l.saveTimer != nil {
+		l.saveTimer.Stop()
+		l.saveTimer = nil
+	}
+	l.saveAccess.Unlock()
+	quotas := make(map[string]adapter.LimitQuota)
+	for name, user := range l.users {
+		if user.quota == 0 {
+			continue
+		}
+		user.quotaAccess.Lock()
+		quotas[name] = adapter.LimitQuota{
+			Used:    user.used.Load(),
+			ResetAt: user.quotaResetAt,
+		}
+		user.quotaAccess.Unlock()
+	}
+	err := l.storage.StoreLimitQuotas(l.storageTag, quotas)
+	if err != nil {
+		l.logger.Warn("save limit quota: ", err)
+	}
+}
+
+// Close writes the quota state to storage, later traffic is not saved.
+func (l *Limiter) Close() error {
+	if l.storage == nil {
+		return nil
+	}
+	l.saveAccess.Lock()
+	l.closed = true
+	l.saveAccess.Unlock()
+	l.save()
+	return nil
+}
+
+func (u *userLimiter) check(now time.Time) *LimitError {
+	if !u.expireAt.IsZero() && now.After(u.expireAt) {
+		return &LimitError{User: u.name, Limit: LimitExpire}
+	}
+	if u.quota > 0 {
+		u.resetQuota(now)
+		if u.used.Load() >= u.quota {
+			return &LimitError{User: u.name, Limit: LimitQuota}
+		}
+	}
+	return nil
+}
+
+func (u *userLimiter) resetQuota(now time.Time) {
+	if u.quotaResetInterval <= 0 {
+		return
+	}
+	u.quotaAccess.Lock()
+	defer u.quotaAccess.Unlock()
+	if now.Before(u.quotaResetAt) {
+		return
+	}
+	for !now.Before(u.quotaResetAt) {
+		u.quotaResetAt = u.quotaResetAt.Add(u.quotaResetInterval)
+	}
+	u.used.Store(0)
+}
+
+// addTraffic accounts n bytes to the quota and checks the limits of an established connection.
+func (u *userLimiter) addTraffic(n int) *LimitError {
+	if !u.expireAt.IsZero() || u.quota > 0 {
+		now := time.Now()
+		if u.quota > 0 {
+			u.resetQuota(now)
+			u.used.Add(uint64(n))
+		}
+		return u.check(now)
+	}
+	return nil
+}
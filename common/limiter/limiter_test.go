@@ -0,0 +1,159 @@
+package limiter
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/logger"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLimiter(options option.InboundLimitOptions) (*Limiter, *[]*LimitError) {
+	var limitErrors []*LimitError
+	return New(options, func(err *LimitError) {
+		limitErrors = append(limitErrors, err)
+	}), &limitErrors
+}
+
+func TestAcquireRelease(t *testing.T) {
+	t.Parallel()
+	limiter, limitErrors := newTestLimiter(option.InboundLimitOptions{
+		MaxConnections: 3,
+		Users: []option.UserLimitOptions{
+			{
+				Name:           []string{"sekai"},
+				MaxConnections: 2,
+				MaxIPs:         1,
+			},
+		},
+	})
+	source := netip.MustParseAddr("10.0.0.1")
+	other := netip.MustParseAddr("10.0.0.2")
+
+	first, err := limiter.Acquire("sekai", source)
+	require.NoError(t, err)
+	_, err = limiter.Acquire("sekai", other)
+	require.Equal(t, &LimitError{User: "sekai", Limit: LimitIPs}, err)
+	second, err := limiter.Acquire("sekai", source)
+	require.NoError(t, err)
+	_, err = limiter.Acquire("sekai", source)
+	require.Equal(t, &LimitError{User: "sekai", Limit: LimitConnections}, err)
+	third, err := limiter.Acquire("", other)
+	require.NoError(t, err)
+	_, err = limiter.Acquire("", other)
+	require.Equal(t, &LimitError{Limit: LimitConnections}, err)
+	require.Len(t, *limitErrors, 3)
+
+	first.Release()
+	first.Release()
+	require.Equal(t, 2, limiter.connections)
+	require.Equal(t, 1, limiter.users["sekai"].ips[source])
+	second.Release()
+	require.Empty(t, limiter.users["sekai"].ips)
+	third.Release()
+	require.Zero(t, limiter.connections)
+	require.Zero(t, limiter.users["sekai"].connections)
+
+	session, err := limiter.Acquire("sekai", other)
+	require.NoError(t, err)
+	session.Release()
+}
+
+func TestResetQuota(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	user := &userLimiter{
+		name:               "sekai",
+		quota:              1024,
+		quotaResetInterval: time.Hour,
+		quotaResetAt:       now.Add(time.Hour),
+	}
+	user.used.Store(1024)
+	user.resetQuota(now)
+	require.Equal(t, uint64(1024), user.used.Load())
+	require.Equal(t, &LimitError{User: "sekai", Limit: LimitQuota}, user.check(now))
+
+	user.resetQuota(now.Add(3*time.Hour + time.Minute))
+	require.Zero(t, user.used.Load())
+	require.Equal(t, now.Add(4*time.Hour), user.quotaResetAt)
+	require.Nil(t, user.check(now.Add(3*time.Hour+time.Minute)))
+
+	user.quotaResetInterval = 0
+	user.used.Store(1024)
+	user.resetQuota(now.Add(10 * time.Hour))
+	require.Equal(t, uint64(1024), user.used.Load())
+}
+
+func TestAddTraffic(t *testing.T) {
+	t.Parallel()
+	user := &userLimiter{
+		name:  "sekai",
+		quota: 1024,
+	}
+	require.Nil(t, user.addTraffic(1023))
+	require.Equal(t, &LimitError{User: "sekai", Limit: LimitQuota}, user.addTraffic(1))
+	require.Equal(t, uint64(1024), user.used.Load())
+
+	expired := &userLimiter{
+		name:     "expired",
+		expireAt: time.Now().Add(-time.Second),
+	}
+	require.Equal(t, &LimitError{User: "expired", Limit: LimitExpire}, expired.addTraffic(1))
+	require.Zero(t, expired.used.Load())
+
+	unlimited := &userLimiter{name: "unlimited"}
+	require.Nil(t, unlimited.addTraffic(1<<30))
+	require.Zero(t, unlimited.used.Load())
+}
+
+type testLimitStorage map[string]map[string]adapter.LimitQuota
+
+func (s testLimitStorage) LimitQuotas(inboundTag string) map[string]adapter.LimitQuota {
+	return s[inboundTag]
+}
+
+func (s testLimitStorage) StoreLimitQuotas(inboundTag string, quotas map[string]adapter.LimitQuota) error {
+	s[inboundTag] = quotas
+	return nil
+}
+
+func TestStoreQuota(t *testing.T) {
+	t.Parallel()
+	options := option.InboundLimitOptions{
+		Users: []option.UserLimitOptions{
+			{
+				Name:               []string{"sekai"},
+				QuotaMB:            1,
+				QuotaResetInterval: option.Duration(time.Hour),
+			},
+			{
+				Name:           []string{"unlimited"},
+				MaxConnections: 1,
+			},
+		},
+	}
+	storage := make(testLimitStorage)
+	limiter, _ := newTestLimiter(options)
+	limiter.SetStorage(storage, "in", logger.NOP())
+	resetAt := limiter.users["sekai"].quotaResetAt
+	require.Nil(t, limiter.users["sekai"].addTraffic(1024))
+	require.NoError(t, limiter.Close())
+	require.Equal(t, map[string]adapter.LimitQuota{
+		"sekai": {Used: 1024, ResetAt: resetAt},
+	}, storage["in"])
+
+	restored, _ := newTestLimiter(options)
+	restored.SetStorage(storage, "in", logger.NOP())
+	require.Equal(t, uint64(1024), restored.users["sekai"].used.Load())
+	require.Equal(t, resetAt, restored.users["sekai"].quotaResetAt)
+
+	options.Users[0].QuotaResetInterval = option.Duration(time.Minute)
+	shortened, _ := newTestLimiter(options)
+	shortened.SetStorage(storage, "in", logger.NOP())
+	require.Equal(t, uint64(1024), shortened.users["sekai"].used.Load())
+	require.True(t, shortened.users["sekai"].quotaResetAt.Before(resetAt))
+}
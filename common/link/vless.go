@@ -75,6 +75,12 @@ func (vl *Vless) Outbound() (*option.Outbound, error) {
 		opt.WebsocketOptions.Headers = map[string]option.Listable[string]{
 			"Host": {vl.TransportHost},
 		}
+	case C.V2RayTransportTypeHTTPUpgrade:
+		opt.HTTPUpgradeOptions.Host = vl.TransportHost
+		opt.HTTPUpgradeOptions.Path = vl.TransportPath
+	case C.V2RayTransportTypeSplitHTTP:
+		opt.SplitHTTPOptions.Host = vl.TransportHost
+		opt.SplitHTTPOptions.Path = vl.TransportPath
 	case C.V2RayTransportTypeGRPC:
 		opt.GRPCOptions.ServiceName = vl.TransportPath
 	}
@@ -93,7 +99,7 @@ func (vl *Vless) URL() (string, error) {
 	switch vl.Transport {
 	case C.V2RayTransportTypeHTTP:
 		queries.Set("path", vl.TransportPath)
-	case C.V2RayTransportTypeWebsocket:
+	case C.V2RayTransportTypeWebsocket, C.V2RayTransportTypeHTTPUpgrade, C.V2RayTransportTypeSplitHTTP:
 		queries.Set("path", vl.TransportPath)
 	case C.V2RayTransportTypeGRPC:
 		queries.Set("serviceName", vl.TransportPath)
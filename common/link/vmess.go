@@ -74,6 +74,12 @@ func (v *Vmess) Outbound() (*option.Outbound, error) {
 		opt.WebsocketOptions.Headers = map[string]option.Listable[string]{
 			"Host": {v.TransportHost},
 		}
+	case C.V2RayTransportTypeHTTPUpgrade:
+		opt.HTTPUpgradeOptions.Host = v.TransportHost
+		opt.HTTPUpgradeOptions.Path = v.TransportPath
+	case C.V2RayTransportTypeSplitHTTP:
+		opt.SplitHTTPOptions.Host = v.TransportHost
+		opt.SplitHTTPOptions.Path = v.TransportPath
 	case C.V2RayTransportTypeQUIC:
 		// do nothing
 	case C.V2RayTransportTypeGRPC:
@@ -1,21 +1,160 @@
 package mux
 
 import (
+	"context"
+	"net"
+	"sync"
+
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-mux"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 )
 
-func NewClientWithOptions(dialer N.Dialer, options option.MultiplexOptions) (*Client, error) {
+type Client struct {
+	*mux.Client
+	logger       logger.Logger
+	brutal       BrutalOptions
+	brutalDialer *brutalDialer
+}
+
+func NewClientWithOptions(dialer N.Dialer, logger logger.Logger, options option.MultiplexOptions) (*Client, error) {
 	if !options.Enabled {
 		return nil, nil
 	}
-	return mux.NewClient(mux.Options{
+	client := &Client{
+		logger: logger,
+	}
+	clientOptions := mux.Options{
 		Dialer:         dialer,
 		Protocol:       options.Protocol,
 		MaxConnections: options.MaxConnections,
 		MinStreams:     options.MinStreams,
 		MaxStreams:     options.MaxStreams,
 		Padding:        options.Padding,
-	})
+	}
+	if options.Brutal != nil && options.Brutal.Enabled {
+		if options.Brutal.UpMbps <= 0 || options.Brutal.DownMbps <= 0 {
+			return nil, E.New("invalid brutal options: up_mbps and down_mbps must be set")
+		}
+		client.brutal = BrutalOptions{
+			Enabled:    true,
+			SendBPS:    uint64(options.Brutal.UpMbps) * MbpsToBps,
+			ReceiveBPS: uint64(options.Brutal.DownMbps) * MbpsToBps,
+		}
+		if client.brutal.SendBPS < BrutalMinSpeedBPS || client.brutal.ReceiveBPS < BrutalMinSpeedBPS {
+			return nil, E.New("invalid brutal options: bandwidth must be at least ", BrutalMinSpeedBPS, " bps")
+		}
+		// brutal sets the congestion control of a whole session, so all streams share one connection
+		client.brutalDialer = &brutalDialer{Dialer: dialer}
+		clientOptions.Dialer = client.brutalDialer
+		clientOptions.MaxConnections = 1
+		clientOptions.MinStreams = 0
+		clientOptions.MaxStreams = 0
+	}
+	var err error
+	client.Client, err = mux.NewClient(clientOptions)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func (c *Client) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	conn, err := c.Client.DialContext(ctx, network, destination)
+	if err != nil {
+		return nil, err
+	}
+	err = c.checkBrutal(ctx)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (c *Client) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	conn, err := c.Client.ListenPacket(ctx, destination)
+	if err != nil {
+		return nil, err
+	}
+	err = c.checkBrutal(ctx)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// checkBrutal runs the bandwidth exchange once the client has dialed a new session.
+func (c *Client) checkBrutal(ctx context.Context) error {
+	if c.brutalDialer == nil {
+		return nil
+	}
+	sessionConn := c.brutalDialer.loadNew()
+	if sessionConn == nil {
+		return nil
+	}
+	err := c.brutalExchange(ctx, sessionConn)
+	if err != nil {
+		c.Client.Reset()
+		return E.Cause(err, "brutal exchange")
+	}
+	return nil
+}
+
+func (c *Client) brutalExchange(ctx context.Context, sessionConn net.Conn) error {
+	conn, err := c.Client.DialContext(ctx, N.NetworkTCP, M.Socksaddr{Fqdn: BrutalExchangeDomain})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = WriteBrutalRequest(conn, c.brutal.ReceiveBPS)
+	if err != nil {
+		return err
+	}
+	serverReceiveBPS, err := ReadBrutalResponse(conn)
+	if err != nil {
+		return err
+	}
+	sendBPS := c.brutal.SendBPS
+	if serverReceiveBPS < sendBPS {
+		sendBPS = serverReceiveBPS
+	}
+	err = SetBrutalOptions(sessionConn, sendBPS)
+	if err != nil {
+		c.logger.Debug(E.Cause(err, "failed to enable TCP Brutal at client"))
+	}
+	return nil
+}
+
+type brutalDialer struct {
+	N.Dialer
+	access  sync.Mutex
+	newConn net.Conn
+}
+
+func (d *brutalDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	conn, err := d.Dialer.DialContext(ctx, network, destination)
+	if err != nil {
+		return nil, err
+	}
+	d.access.Lock()
+	d.newConn = conn
+	d.access.Unlock()
+	return conn, nil
+}
+
+func (d *brutalDialer) loadNew() net.Conn {
+	d.access.Lock()
+	defer d.access.Unlock()
+	conn := d.newConn
+	d.newConn = nil
+	return conn
+}
+
+func (d *brutalDialer) Upstream() any {
+	return d.Dialer
 }
@@ -5,7 +5,7 @@ import (
 )
 
 type (
-	Client = mux.Client
+	ServerHandler = mux.ServerHandler
 )
 
 var (
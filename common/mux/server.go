@@ -0,0 +1,206 @@
+package mux
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-mux"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
+	"github.com/sagernet/sing/common/debug"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+var protocolNames = map[byte]string{
+	mux.ProtocolSmux:  "smux",
+	mux.ProtocolYAMux: "yamux",
+	mux.ProtocolH2Mux: "h2mux",
+}
+
+// Service handles multiplex connections with the server side controls of an inbound.
+type Service struct {
+	protocols   []string
+	maxStreams  int
+	padding     bool
+	idleTimeout time.Duration
+	brutal      BrutalOptions
+}
+
+func NewService(options option.InboundMultiplexOptions) (*Service, error) {
+	service := &Service{
+		maxStreams:  options.MaxStreams,
+		padding:     options.Padding,
+		idleTimeout: time.Duration(options.IdleTimeout),
+	}
+	for _, protocol := range options.Protocol {
+		switch protocol {
+		case "smux", "yamux", "h2mux":
+		default:
+			return nil, E.New("unknown multiplex protocol: ", protocol)
+		}
+		service.protocols = append(service.protocols, protocol)
+	}
+	if options.Brutal != nil && options.Brutal.Enabled {
+		if !BrutalAvailable && !debug.Enabled {
+			return nil, E.New("TCP Brutal is only supported on Linux")
+		}
+		if options.Brutal.UpMbps <= 0 || options.Brutal.DownMbps <= 0 {
+			return nil, E.New("invalid brutal options: up_mbps and down_mbps must be set")
+		}
+		service.brutal = BrutalOptions{
+			Enabled:    true,
+			SendBPS:    uint64(options.Brutal.UpMbps) * MbpsToBps,
+			ReceiveBPS: uint64(options.Brutal.DownMbps) * MbpsToBps,
+		}
+		if service.brutal.SendBPS < BrutalMinSpeedBPS || service.brutal.ReceiveBPS < BrutalMinSpeedBPS {
+			return nil, E.New("invalid brutal options: bandwidth must be at least ", BrutalMinSpeedBPS, " bps")
+		}
+	}
+	return service, nil
+}
+
+func (s *Service) NewConnection(ctx context.Context, handler ServerHandler, logger logger.ContextLogger, conn net.Conn, metadata M.Metadata) error {
+	requestBuffer := buf.New()
+	request, err := mux.ReadRequest(io.TeeReader(conn, requestBuffer))
+	if err != nil {
+		requestBuffer.Release()
+		return E.Cause(err, "read multiplex request")
+	}
+	protocol := protocolNames[request.Protocol]
+	if len(s.protocols) > 0 && !common.Contains(s.protocols, protocol) {
+		requestBuffer.Release()
+		return E.New("multiplex protocol rejected: ", protocol)
+	}
+	if s.padding && !request.Padding {
+		requestBuffer.Release()
+		return E.New("non-padded multiplex connection rejected")
+	}
+	session := &serverSession{
+		Service:     s,
+		handler:     handler,
+		logger:      logger,
+		sessionConn: conn,
+	}
+	conn = bufio.NewCachedConn(conn, requestBuffer)
+	if s.idleTimeout > 0 {
+		session.idleTimer = time.AfterFunc(s.idleTimeout, func() {
+			if session.closeIdle() {
+				conn.Close()
+			}
+		})
+	}
+	err = mux.HandleConnection(ctx, session, logger, conn, metadata)
+	if session.idleTimer != nil {
+		session.idleTimer.Stop()
+	}
+	if session.isIdleClosed() {
+		logger.DebugContext(ctx, "multiplex session closed after idle timeout")
+		return nil
+	}
+	return err
+}
+
+type serverSession struct {
+	*Service
+	handler     ServerHandler
+	logger      logger.ContextLogger
+	sessionConn net.Conn
+	access      sync.Mutex
+	streams     int
+	idleTimer   *time.Timer
+	idleClosed  bool
+}
+
+func (s *serverSession) isIdleClosed() bool {
+	s.access.Lock()
+	defer s.access.Unlock()
+	return s.idleClosed
+}
+
+func (s *serverSession) closeIdle() bool {
+	s.access.Lock()
+	defer s.access.Unlock()
+	if s.streams > 0 {
+		return false
+	}
+	s.idleClosed = true
+	return true
+}
+
+func (s *serverSession) acquireStream() error {
+	s.access.Lock()
+	defer s.access.Unlock()
+	if s.idleClosed {
+		return E.New("multiplex session closed after idle timeout")
+	}
+	if s.maxStreams > 0 && s.streams >= s.maxStreams {
+		return E.New("too many multiplex streams in session, max: ", s.maxStreams)
+	}
+	s.streams++
+	if s.idleTimer != nil {
+		s.idleTimer.Stop()
+	}
+	return nil
+}
+
+func (s *serverSession) releaseStream() {
+	s.access.Lock()
+	defer s.access.Unlock()
+	s.streams--
+	if s.streams == 0 && s.idleTimer != nil {
+		s.idleTimer.Reset(s.idleTimeout)
+	}
+}
+
+func (s *serverSession) NewConnection(ctx context.Context, conn net.Conn, metadata M.Metadata) error {
+	if metadata.Destination.Fqdn == BrutalExchangeDomain {
+		return s.brutalExchange(conn)
+	}
+	err := s.acquireStream()
+	if err != nil {
+		return N.HandshakeFailure(conn, err)
+	}
+	defer s.releaseStream()
+	return s.handler.NewConnection(ctx, conn, metadata)
+}
+
+func (s *serverSession) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata M.Metadata) error {
+	err := s.acquireStream()
+	if err != nil {
+		return N.HandshakeFailure(conn, err)
+	}
+	defer s.releaseStream()
+	return s.handler.NewPacketConnection(ctx, conn, metadata)
+}
+
+func (s *serverSession) NewError(ctx context.Context, err error) {
+	s.handler.NewError(ctx, err)
+}
+
+func (s *serverSession) brutalExchange(conn net.Conn) error {
+	clientReceiveBPS, err := ReadBrutalRequest(conn)
+	if err != nil {
+		return E.Cause(err, "read brutal request")
+	}
+	if !s.brutal.Enabled {
+		return WriteBrutalResponse(conn, 0, false, "brutal is not enabled by the server")
+	}
+	sendBPS := s.brutal.SendBPS
+	if clientReceiveBPS < sendBPS {
+		sendBPS = clientReceiveBPS
+	}
+	err = SetBrutalOptions(s.sessionConn, sendBPS)
+	// ignore error in test
+	if err != nil && !debug.Enabled {
+		return WriteBrutalResponse(conn, 0, false, E.Cause(err, "enable TCP Brutal").Error())
+	}
+	return WriteBrutalResponse(conn, s.brutal.ReceiveBPS, true, "")
+}
@@ -5,4 +5,5 @@ import "time"
 const (
 	DHCPTTL     = time.Hour
 	DHCPTimeout = time.Minute
+	DHCPv6Grace = time.Second
 )
@@ -2,6 +2,8 @@ package clashapi
 
 import (
 	"net/http"
+	"net/netip"
+	"sort"
 
 	"github.com/sagernet/sing-box/adapter"
 
@@ -11,13 +13,51 @@ import (
 
 func cacheRouter(router adapter.Router) http.Handler {
 	r := chi.NewRouter()
+	r.Get("/fakeip", getFakeIPMappings(router))
 	r.Post("/fakeip/flush", flushFakeip(router))
+	r.Post("/dns/flush", flushDNS(router))
 	return r
 }
 
+func getFakeIPMappings(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fakeIPStore := router.FakeIPStore()
+		if fakeIPStore == nil {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, newError("fakeip not enabled"))
+			return
+		}
+		mappings := fakeIPStore.Mappings()
+		addresses := make([]netip.Addr, 0, len(mappings))
+		for address := range mappings {
+			addresses = append(addresses, address)
+		}
+		sort.Slice(addresses, func(i, j int) bool {
+			return addresses[i].Less(addresses[j])
+		})
+		mappingList := make([]render.M, 0, len(addresses))
+		for _, address := range addresses {
+			mappingList = append(mappingList, render.M{
+				"address": address.String(),
+				"domain":  mappings[address],
+			})
+		}
+		render.JSON(w, r, render.M{
+			"mappings": mappingList,
+		})
+	}
+}
+
 func flushFakeip(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if cacheFile := router.ClashServer().CacheFile(); cacheFile != nil {
+		if fakeIPStore := router.FakeIPStore(); fakeIPStore != nil {
+			err := fakeIPStore.Reset()
+			if err != nil {
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, newError(err.Error()))
+				return
+			}
+		} else if cacheFile := router.ClashServer().CacheFile(); cacheFile != nil {
 			err := cacheFile.FakeIPReset()
 			if err != nil {
 				render.Status(r, http.StatusInternalServerError)
@@ -28,3 +68,15 @@ func flushFakeip(router adapter.Router) func(w http.ResponseWriter, r *http.Requ
 		render.NoContent(w, r)
 	}
 }
+
+func flushDNS(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := router.ClearDNSCache()
+		if err != nil {
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		render.NoContent(w, r)
+	}
+}
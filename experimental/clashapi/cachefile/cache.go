@@ -58,7 +58,7 @@ func Open(path string, cacheID string) (*CacheFile, error) {
 				})
 			} else {
 				bucketName := string(name)
-				if !(bucketName == string(bucketSelected) || strings.HasPrefix(bucketName, fakeipBucketPrefix)) {
+				if !(bucketName == string(bucketSelected) || strings.HasPrefix(bucketName, fakeipBucketPrefix) || bucketName == string(bucketDNSCache) || bucketName == string(bucketLimitQuota)) {
 					delErr := tx.DeleteBucket(name)
 					if delErr != nil {
 						return delErr
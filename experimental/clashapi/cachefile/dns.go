@@ -0,0 +1,54 @@
+package cachefile
+
+import (
+	"go.etcd.io/bbolt"
+)
+
+var bucketDNSCache = []byte("dns_cache")
+
+func (c *CacheFile) DNSCacheEntries() map[string][]byte {
+	entries := make(map[string][]byte)
+	_ = c.DB.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketDNSCache)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			entries[string(k)] = append([]byte(nil), v...)
+			return nil
+		})
+	})
+	return entries
+}
+
+func (c *CacheFile) DNSCacheUpdate(entries map[string][]byte, deletedKeys []string) error {
+	return c.DB.Batch(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(bucketDNSCache)
+		if err != nil {
+			return err
+		}
+		for key, value := range entries {
+			err = bucket.Put([]byte(key), value)
+			if err != nil {
+				return err
+			}
+		}
+		for _, key := range deletedKeys {
+			err = bucket.Delete([]byte(key))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (c *CacheFile) DNSCacheReset() error {
+	return c.DB.Batch(func(tx *bbolt.Tx) error {
+		err := tx.DeleteBucket(bucketDNSCache)
+		if err == bbolt.ErrBucketNotFound {
+			return nil
+		}
+		return err
+	})
+}
@@ -63,6 +63,7 @@ func (c *CacheFile) FakeIPStore(address netip.Addr, domain string) error {
 		if err != nil {
 			return err
 		}
+		oldDomain := bucket.Get(address.AsSlice())
 		err = bucket.Put(address.AsSlice(), []byte(domain))
 		if err != nil {
 			return err
@@ -75,6 +76,12 @@ func (c *CacheFile) FakeIPStore(address netip.Addr, domain string) error {
 		if err != nil {
 			return err
 		}
+		if len(oldDomain) > 0 && string(oldDomain) != domain && M.AddrFromIP(bucket.Get(oldDomain)) == address {
+			err = bucket.Delete(oldDomain)
+			if err != nil {
+				return err
+			}
+		}
 		return bucket.Put([]byte(domain), address.AsSlice())
 	})
 }
@@ -155,6 +162,29 @@ func (c *CacheFile) FakeIPLoadDomain(domain string, isIPv6 bool) (netip.Addr, bo
 	return address, address.IsValid()
 }
 
+func (c *CacheFile) FakeIPMappings() map[netip.Addr]string {
+	mappings := make(map[netip.Addr]string)
+	_ = c.DB.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketFakeIP)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(key, value []byte) error {
+			if len(key) != 4 && len(key) != 16 {
+				return nil
+			}
+			mappings[M.AddrFromIP(key)] = string(value)
+			return nil
+		})
+	})
+	c.saveAccess.RLock()
+	for address, domain := range c.saveDomain {
+		mappings[address] = domain
+	}
+	c.saveAccess.RUnlock()
+	return mappings
+}
+
 func (c *CacheFile) FakeIPReset() error {
 	return c.DB.Batch(func(tx *bbolt.Tx) error {
 		err := tx.DeleteBucket(bucketFakeIP)
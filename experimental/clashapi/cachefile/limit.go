@@ -0,0 +1,68 @@
+package cachefile
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+
+	"go.etcd.io/bbolt"
+)
+
+var bucketLimitQuota = []byte("limit_quota")
+
+func (c *CacheFile) LimitQuotas(inboundTag string) map[string]adapter.LimitQuota {
+	quotas := make(map[string]adapter.LimitQuota)
+	_ = c.DB.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketLimitQuota)
+		if bucket == nil {
+			return nil
+		}
+		bucket = bucket.Bucket([]byte(inboundTag))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			if len(v) != 16 {
+				return nil
+			}
+			var quota adapter.LimitQuota
+			quota.Used = binary.BigEndian.Uint64(v)
+			if resetAt := int64(binary.BigEndian.Uint64(v[8:])); resetAt != 0 {
+				quota.ResetAt = time.Unix(0, resetAt)
+			}
+			quotas[string(k)] = quota
+			return nil
+		})
+	})
+	return quotas
+}
+
+func (c *CacheFile) StoreLimitQuotas(inboundTag string, quotas map[string]adapter.LimitQuota) error {
+	return c.DB.Batch(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(bucketLimitQuota)
+		if err != nil {
+			return err
+		}
+		err = bucket.DeleteBucket([]byte(inboundTag))
+		if err != nil && err != bbolt.ErrBucketNotFound {
+			return err
+		}
+		bucket, err = bucket.CreateBucket([]byte(inboundTag))
+		if err != nil {
+			return err
+		}
+		for user, quota := range quotas {
+			value := make([]byte, 16)
+			binary.BigEndian.PutUint64(value, quota.Used)
+			if !quota.ResetAt.IsZero() {
+				binary.BigEndian.PutUint64(value[8:], uint64(quota.ResetAt.UnixNano()))
+			}
+			err = bucket.Put([]byte(user), value)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -5,6 +5,8 @@ var (
 	CtxKeyProviderName = contextKey("provider name")
 	CtxKeyProxy        = contextKey("proxy")
 	CtxKeyProvider     = contextKey("provider")
+	CtxKeyInboundTag   = contextKey("inbound tag")
+	CtxKeyUserManager  = contextKey("user manager")
 )
 
 type contextKey string
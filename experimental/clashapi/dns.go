@@ -1,12 +1,16 @@
 package clashapi
 
 import (
+	"bytes"
 	"context"
 	"net/http"
+	"strconv"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/json"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing/common"
+	"github.com/sagernet/websocket"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
@@ -16,6 +20,12 @@ import (
 func dnsRouter(router adapter.Router) http.Handler {
 	r := chi.NewRouter()
 	r.Get("/query", queryDNS(router))
+	r.Get("/upstreams", getDNSUpstreams(router))
+	r.Get("/connections", getDNSConnections(router))
+	r.Get("/queries", getDNSQueries(router))
+	r.Delete("/queries", resetDNSQueries(router))
+	r.Get("/log", getDNSQueryLog(router))
+	r.Get("/statistics", getDNSStatistics(router))
 	return r
 }
 
@@ -80,3 +90,176 @@ func queryDNS(router adapter.Router) func(w http.ResponseWriter, r *http.Request
 		render.JSON(w, r, responseData)
 	}
 }
+
+func getDNSUpstreams(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groups := make(render.M)
+		for _, transport := range router.DNSTransports() {
+			group, isGroup := transport.(adapter.DNSGroupTransport)
+			if !isGroup {
+				continue
+			}
+			groups[group.Name()] = common.Map(group.UpstreamStatistics(), func(it adapter.DNSUpstreamStatistics) render.M {
+				return render.M{
+					"server":         it.Server,
+					"queries":        it.Queries,
+					"wins":           it.Wins,
+					"failures":       it.Failures,
+					"lastLatency":    it.LastLatency.Milliseconds(),
+					"averageLatency": it.AverageLatency.Milliseconds(),
+				}
+			})
+		}
+		render.JSON(w, r, render.M{
+			"groups": groups,
+		})
+	}
+}
+
+func getDNSConnections(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		transports := make(render.M)
+		for _, transport := range router.DNSTransports() {
+			connectionTransport, loaded := common.Cast[adapter.DNSConnectionTransport](transport)
+			if !loaded {
+				continue
+			}
+			statistics := connectionTransport.ConnectionStatistics()
+			transports[transport.Name()] = render.M{
+				"connections":   statistics.Connections,
+				"queries":       statistics.Queries,
+				"reusedQueries": statistics.ReusedQueries,
+				"failures":      statistics.Failures,
+			}
+		}
+		render.JSON(w, r, render.M{
+			"transports": transports,
+		})
+	}
+}
+
+func dnsQueryRecordToJSON(record adapter.DNSQueryRecord) render.M {
+	answers := record.Answers
+	if answers == nil {
+		answers = []string{}
+	}
+	return render.M{
+		"time":      record.Time,
+		"inbound":   record.Inbound,
+		"client":    record.Client.String(),
+		"domain":    record.Domain,
+		"type":      record.QueryType,
+		"ruleIndex": record.RuleIndex,
+		"rule":      record.Rule,
+		"transport": record.Transport,
+		"latency":   record.Latency.Milliseconds(),
+		"rcode":     record.RCode,
+		"cached":    record.Cached,
+		"answers":   answers,
+		"error":     record.Error,
+	}
+}
+
+func dnsQueryCounterToJSON(counter adapter.DNSQueryCounter) render.M {
+	return render.M{
+		"queries":        counter.Queries,
+		"cached":         counter.Cached,
+		"failures":       counter.Failures,
+		"averageLatency": counter.AverageLatency.Milliseconds(),
+	}
+}
+
+func getDNSQueries(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		records := router.DNSQueryLog().Records()
+		if limitText := r.URL.Query().Get("limit"); limitText != "" {
+			limit, err := strconv.Atoi(limitText)
+			if err != nil || limit < 0 {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, ErrBadRequest)
+				return
+			}
+			if limit < len(records) {
+				records = records[len(records)-limit:]
+			}
+		}
+		render.JSON(w, r, render.M{
+			"queries": common.Map(records, dnsQueryRecordToJSON),
+		})
+	}
+}
+
+func resetDNSQueries(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		router.DNSQueryLog().Reset()
+		render.NoContent(w, r)
+	}
+}
+
+func getDNSStatistics(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		statistics := router.DNSQueryLog().Statistics()
+		transports := make(render.M, len(statistics.Transports))
+		for name, counter := range statistics.Transports {
+			transports[name] = dnsQueryCounterToJSON(counter)
+		}
+		rules := make(render.M, len(statistics.Rules))
+		for name, counter := range statistics.Rules {
+			rules[name] = dnsQueryCounterToJSON(counter)
+		}
+		render.JSON(w, r, render.M{
+			"total":      dnsQueryCounterToJSON(statistics.Total),
+			"transports": transports,
+			"rules":      rules,
+		})
+	}
+}
+
+func getDNSQueryLog(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryLog := router.DNSQueryLog()
+		subscription, done, err := queryLog.Subscribe()
+		if err != nil {
+			render.Status(r, http.StatusNoContent)
+			return
+		}
+		defer queryLog.UnSubscribe(subscription)
+
+		var wsConn *websocket.Conn
+		if websocket.IsWebSocketUpgrade(r) {
+			wsConn, err = upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+		}
+
+		if wsConn == nil {
+			w.Header().Set("Content-Type", "application/json")
+			render.Status(r, http.StatusOK)
+		}
+
+		buf := &bytes.Buffer{}
+		var record adapter.DNSQueryRecord
+		for {
+			select {
+			case <-done:
+				return
+			case record = <-subscription:
+			}
+			buf.Reset()
+			err = json.NewEncoder(buf).Encode(dnsQueryRecordToJSON(record))
+			if err != nil {
+				break
+			}
+			if wsConn == nil {
+				_, err = w.Write(buf.Bytes())
+				w.(http.Flusher).Flush()
+			} else {
+				err = wsConn.WriteMessage(websocket.TextMessage, buf.Bytes())
+			}
+			if err != nil {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package clashapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+func inboundRouter(server *Server) http.Handler {
+	r := chi.NewRouter()
+	r.Route("/{tag}", func(r chi.Router) {
+		r.Use(parseInboundTag, findUserManagerByTag(server.router))
+		r.Get("/users", getUsers)
+		r.Post("/users", addUsers(server))
+		r.Delete("/users/{name}", removeUser(server))
+	})
+	return r
+}
+
+func parseInboundTag(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tag := getEscapeParam(r, "tag")
+		ctx := context.WithValue(r.Context(), CtxKeyInboundTag, tag)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func findUserManagerByTag(router adapter.Router) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tag := r.Context().Value(CtxKeyInboundTag).(string)
+			inbound, exist := router.Inbound(tag)
+			if !exist {
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, ErrNotFound)
+				return
+			}
+			userManager, isUserManager := inbound.(adapter.UserManager)
+			if !isUserManager {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, newError("Must be a multi-user inbound"))
+				return
+			}
+			ctx := context.WithValue(r.Context(), CtxKeyUserManager, userManager)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func getUsers(w http.ResponseWriter, r *http.Request) {
+	userManager := r.Context().Value(CtxKeyUserManager).(adapter.UserManager)
+	render.JSON(w, r, render.M{
+		"users": userManager.Users(),
+	})
+}
+
+func addUsers(server *Server) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userManager := r.Context().Value(CtxKeyUserManager).(adapter.UserManager)
+		content, err := io.ReadAll(r.Body)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, ErrBadRequest)
+			return
+		}
+		userLock := server.userLock(userManager.Tag())
+		userLock.Lock()
+		defer userLock.Unlock()
+		err = userManager.AddUsers(content)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		server.storeUsersFor(userManager)
+		render.NoContent(w, r)
+	}
+}
+
+func removeUser(server *Server) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userManager := r.Context().Value(CtxKeyUserManager).(adapter.UserManager)
+		userLock := server.userLock(userManager.Tag())
+		userLock.Lock()
+		defer userLock.Unlock()
+		err := userManager.RemoveUsers([]string{getEscapeParam(r, "name")})
+		if err != nil {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		server.storeUsersFor(userManager)
+		render.NoContent(w, r)
+	}
+}
+
+// userLock returns the lock serializing user updates of the inbound, so that
+// the users stored are always the users of the latest update.
+func (s *Server) userLock(inboundTag string) *sync.Mutex {
+	s.userAccess.Lock()
+	defer s.userAccess.Unlock()
+	if s.userLocks == nil {
+		s.userLocks = make(map[string]*sync.Mutex)
+	}
+	userLock, loaded := s.userLocks[inboundTag]
+	if !loaded {
+		userLock = new(sync.Mutex)
+		s.userLocks[inboundTag] = userLock
+	}
+	return userLock
+}
+
+func (s *Server) storeUsersFor(userManager adapter.UserManager) {
+	if !s.storeUsers {
+		return
+	}
+	userStore := service.FromContext[adapter.UserStore](s.ctx)
+	if userStore == nil {
+		s.logger.Warn("store users for inbound/", userManager.Type(), "[", userManager.Tag(), "]: no writable configuration")
+		return
+	}
+	err := userStore.StoreUsers(userManager.Tag(), userManager.Users())
+	if err != nil {
+		s.logger.Error(E.Cause(err, "store users for inbound/", userManager.Type(), "[", userManager.Tag(), "]"))
+	}
+}
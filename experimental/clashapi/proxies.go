@@ -92,6 +92,8 @@ func proxyInfo(server *Server, detour adapter.Outbound) *badjson.JSONObject {
 		clashType = "SSH"
 	case C.TypeTUIC:
 		clashType = "TUIC"
+	case C.TypeHysteria2:
+		clashType = "Hysteria2"
 	case C.TypeSelector:
 		clashType = "Selector"
 	case C.TypeURLTest:
@@ -2,40 +2,152 @@ package clashapi
 
 import (
 	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/process"
+	"github.com/sagernet/sing-box/experimental/clashapi/trafficontrol"
+	"github.com/sagernet/sing/common"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 )
 
-func ruleRouter(router adapter.Router) http.Handler {
+func ruleRouter(router adapter.Router, trafficManager *trafficontrol.Manager) http.Handler {
 	r := chi.NewRouter()
-	r.Get("/", getRules(router))
+	r.Get("/", getRules(router, trafficManager))
+	r.Delete("/statistics", resetRuleStatistics(trafficManager))
+	r.Get("/explain", explainRoute(router))
 	return r
 }
 
 type Rule struct {
-	Type    string `json:"type"`
-	Payload string `json:"payload"`
-	Proxy   string `json:"proxy"`
+	Type     string     `json:"type"`
+	Payload  string     `json:"payload"`
+	Proxy    string     `json:"proxy"`
+	Hits     int64      `json:"hits"`
+	LastHit  *time.Time `json:"lastHit,omitempty"`
+	Upload   int64      `json:"upload"`
+	Download int64      `json:"download"`
+}
+
+func newRule(ruleType string, payload string, proxy string, statistics *trafficontrol.RuleStatistics) Rule {
+	rule := Rule{
+		Type:    ruleType,
+		Payload: payload,
+		Proxy:   proxy,
+		Hits:    statistics.Hits(),
+	}
+	if lastHit := statistics.LastHit(); !lastHit.IsZero() {
+		rule.LastHit = &lastHit
+	}
+	rule.Upload, rule.Download = statistics.Total()
+	return rule
 }
 
-func getRules(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+func getRules(router adapter.Router, trafficManager *trafficontrol.Manager) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		rawRules := router.Rules()
 
 		var rules []Rule
 		for _, rule := range rawRules {
-			rules = append(rules, Rule{
-				Type:    rule.Type(),
-				Payload: rule.String(),
-				Proxy:   rule.Outbound(),
-			})
+			rules = append(rules, newRule(rule.Type(), rule.String(), rule.Outbound(), trafficManager.RuleStatistics(rule)))
 		}
 
+		var finalOutbound string
+		if defaultOutbound := router.DefaultOutbound(N.NetworkTCP); defaultOutbound != nil {
+			finalOutbound = defaultOutbound.Tag()
+		}
 		render.JSON(w, r, render.M{
 			"rules": rules,
+			"final": newRule("final", "", finalOutbound, trafficManager.RuleStatistics(nil)),
+		})
+	}
+}
+
+func resetRuleStatistics(trafficManager *trafficontrol.Manager) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		trafficManager.ResetRuleStatistics()
+		render.NoContent(w, r)
+	}
+}
+
+type RuleExplanation struct {
+	Index   int    `json:"index"`
+	Type    string `json:"type"`
+	Payload string `json:"payload"`
+	Proxy   string `json:"proxy"`
+	Matched bool   `json:"matched"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+func explainRoute(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		metadata := adapter.InboundContext{
+			Inbound:     query.Get("inbound"),
+			Network:     query.Get("network"),
+			Destination: M.ParseSocksaddr(query.Get("destination")),
+		}
+		if source := query.Get("source"); source != "" {
+			metadata.Source = M.ParseSocksaddr(source)
+			if !metadata.Source.IsIP() {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, newError("invalid source address"))
+				return
+			}
+		}
+		processInfo := process.Info{
+			ProcessPath: query.Get("processPath"),
+			PackageName: query.Get("packageName"),
+			User:        query.Get("user"),
+			UserId:      -1,
+		}
+		if userIDStr := query.Get("userId"); userIDStr != "" {
+			userID, err := strconv.ParseInt(userIDStr, 10, 32)
+			if err != nil {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, newError("invalid user id"))
+				return
+			}
+			processInfo.UserId = int32(userID)
+		}
+		if processInfo.ProcessPath != "" || processInfo.PackageName != "" || processInfo.User != "" || processInfo.UserId != -1 {
+			metadata.ProcessInfo = &processInfo
+		}
+		resolve := query.Get("resolve") != "false"
+		explanation, err := router.ExplainRoute(r.Context(), metadata, resolve)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		rules := common.Map(explanation.Rules, func(it adapter.RuleExplanation) RuleExplanation {
+			return RuleExplanation{
+				Index:   it.Index,
+				Type:    it.Type,
+				Payload: it.Rule,
+				Proxy:   it.Outbound,
+				Matched: it.Matched,
+				Reason:  it.Reason,
+			}
 		})
+		response := render.M{
+			"destination":  explanation.Metadata.Destination.String(),
+			"host":         explanation.Metadata.Domain,
+			"resolved":     common.Map(explanation.Metadata.DestinationAddresses, func(it netip.Addr) string { return it.String() }),
+			"rules":        rules,
+			"matchedRule":  explanation.MatchedRule,
+			"outbound":     explanation.Outbound,
+			"realOutbound": explanation.RealOutbound,
+		}
+		if explanation.ResolveError != nil {
+			response["resolveError"] = explanation.ResolveError.Error()
+		}
+		render.JSON(w, r, response)
 	}
 }
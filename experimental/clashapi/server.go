@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
@@ -48,6 +49,11 @@ type Server struct {
 	mode           string
 	storeSelected  bool
 	storeFakeIP    bool
+	storeDNS       bool
+	storeLimit     bool
+	storeUsers     bool
+	userAccess     sync.Mutex
+	userLocks      map[string]*sync.Mutex
 	cacheFilePath  string
 	cacheID        string
 	cacheFile      adapter.ClashCacheFile
@@ -72,6 +78,9 @@ func NewServer(ctx context.Context, router adapter.Router, logFactory log.Observ
 		mode:                     strings.ToLower(options.DefaultMode),
 		storeSelected:            options.StoreSelected,
 		storeFakeIP:              options.StoreFakeIP,
+		storeDNS:                 options.StoreDNS,
+		storeLimit:               options.StoreLimit,
+		storeUsers:               options.StoreUsers,
 		externalUIDownloadURL:    options.ExternalUIDownloadURL,
 		externalUIDownloadDetour: options.ExternalUIDownloadDetour,
 	}
@@ -82,7 +91,7 @@ func NewServer(ctx context.Context, router adapter.Router, logFactory log.Observ
 	if server.mode == "" {
 		server.mode = "rule"
 	}
-	if options.StoreSelected || options.StoreFakeIP {
+	if options.StoreSelected || options.StoreFakeIP || options.StoreDNS || options.StoreLimit {
 		cachePath := os.ExpandEnv(options.CacheFile)
 		if cachePath == "" {
 			cachePath = "cache.db"
@@ -110,7 +119,7 @@ func NewServer(ctx context.Context, router adapter.Router, logFactory log.Observ
 		r.Get("/version", version)
 		r.Mount("/configs", configRouter(server, logFactory, server.logger))
 		r.Mount("/proxies", proxyRouter(server, router))
-		r.Mount("/rules", ruleRouter(router))
+		r.Mount("/rules", ruleRouter(router, trafficManager))
 		r.Mount("/connections", connectionRouter(router, trafficManager))
 		r.Mount("/providers/proxies", proxyProviderRouter(server))
 		r.Mount("/providers/rules", ruleProviderRouter())
@@ -118,6 +127,7 @@ func NewServer(ctx context.Context, router adapter.Router, logFactory log.Observ
 		r.Mount("/profile", profileRouter())
 		r.Mount("/cache", cacheRouter(router))
 		r.Mount("/dns", dnsRouter(router))
+		r.Mount("/inbounds", inboundRouter(server))
 
 		server.setupMetaAPI(r)
 	})
@@ -181,6 +191,14 @@ func (s *Server) StoreFakeIP() bool {
 	return s.storeFakeIP
 }
 
+func (s *Server) StoreDNS() bool {
+	return s.storeDNS
+}
+
+func (s *Server) StoreLimit() bool {
+	return s.storeLimit
+}
+
 func (s *Server) CacheFile() adapter.ClashCacheFile {
 	return s.cacheFile
 }
@@ -2,8 +2,10 @@ package trafficontrol
 
 import (
 	"runtime"
+	"sync"
 	"time"
 
+	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/experimental/clashapi/compatible"
 	"github.com/sagernet/sing/common/atomic"
 )
@@ -17,6 +19,9 @@ type Manager struct {
 	downloadTotal atomic.Int64
 
 	connections compatible.Map[string, tracker]
+	ruleAccess  sync.RWMutex
+	rules       map[adapter.Rule]*RuleStatistics
+	finalRule   RuleStatistics
 	ticker      *time.Ticker
 	done        chan struct{}
 	// process     *process.Process
@@ -27,6 +32,7 @@ func NewManager() *Manager {
 	manager := &Manager{
 		ticker: time.NewTicker(time.Second),
 		done:   make(chan struct{}),
+		rules:  make(map[adapter.Rule]*RuleStatistics),
 		// process: &process.Process{Pid: int32(os.Getpid())},
 	}
 	go manager.handle()
@@ -94,6 +100,35 @@ func (m *Manager) ResetStatistic() {
 	m.downloadTotal.Store(0)
 }
 
+func (m *Manager) RuleStatistics(rule adapter.Rule) *RuleStatistics {
+	if rule == nil {
+		return &m.finalRule
+	}
+	m.ruleAccess.RLock()
+	statistics, loaded := m.rules[rule]
+	m.ruleAccess.RUnlock()
+	if loaded {
+		return statistics
+	}
+	m.ruleAccess.Lock()
+	defer m.ruleAccess.Unlock()
+	statistics, loaded = m.rules[rule]
+	if !loaded {
+		statistics = new(RuleStatistics)
+		m.rules[rule] = statistics
+	}
+	return statistics
+}
+
+func (m *Manager) ResetRuleStatistics() {
+	m.ruleAccess.RLock()
+	defer m.ruleAccess.RUnlock()
+	for _, statistics := range m.rules {
+		statistics.reset()
+	}
+	m.finalRule.reset()
+}
+
 func (m *Manager) handle() {
 	var uploadTemp int64
 	var downloadTemp int64
@@ -0,0 +1,42 @@
+package trafficontrol
+
+import (
+	"time"
+
+	"github.com/sagernet/sing/common/atomic"
+)
+
+type RuleStatistics struct {
+	hits          atomic.Int64
+	lastHit       atomic.Int64
+	uploadTotal   atomic.Int64
+	downloadTotal atomic.Int64
+}
+
+func (s *RuleStatistics) hit() {
+	s.hits.Add(1)
+	s.lastHit.Store(time.Now().UnixMilli())
+}
+
+func (s *RuleStatistics) Hits() int64 {
+	return s.hits.Load()
+}
+
+func (s *RuleStatistics) LastHit() time.Time {
+	lastHit := s.lastHit.Load()
+	if lastHit == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(lastHit)
+}
+
+func (s *RuleStatistics) Total() (up int64, down int64) {
+	return s.uploadTotal.Load(), s.downloadTotal.Load()
+}
+
+func (s *RuleStatistics) reset() {
+	s.hits.Store(0)
+	s.lastHit.Store(0)
+	s.uploadTotal.Store(0)
+	s.downloadTotal.Store(0)
+}
@@ -113,13 +113,17 @@ func NewTCPTracker(conn net.Conn, manager *Manager, metadata Metadata, router ad
 
 	upload := new(atomic.Int64)
 	download := new(atomic.Int64)
+	ruleStatistics := manager.RuleStatistics(rule)
+	ruleStatistics.hit()
 
 	t := &tcpTracker{
 		ExtendedConn: bufio.NewCounterConn(conn, []N.CountFunc{func(n int64) {
 			upload.Add(n)
+			ruleStatistics.uploadTotal.Add(n)
 			manager.PushUploaded(n)
 		}}, []N.CountFunc{func(n int64) {
 			download.Add(n)
+			ruleStatistics.downloadTotal.Add(n)
 			manager.PushDownloaded(n)
 		}}),
 		manager: manager,
@@ -200,13 +204,17 @@ func NewUDPTracker(conn N.PacketConn, manager *Manager, metadata Metadata, route
 
 	upload := new(atomic.Int64)
 	download := new(atomic.Int64)
+	ruleStatistics := manager.RuleStatistics(rule)
+	ruleStatistics.hit()
 
 	ut := &udpTracker{
 		PacketConn: bufio.NewCounterPacketConn(conn, []N.CountFunc{func(n int64) {
 			upload.Add(n)
+			ruleStatistics.uploadTotal.Add(n)
 			manager.PushUploaded(n)
 		}}, []N.CountFunc{func(n int64) {
 			download.Add(n)
+			ruleStatistics.downloadTotal.Add(n)
 			manager.PushDownloaded(n)
 		}}),
 		manager: manager,
@@ -8,4 +8,6 @@ const (
 	CommandGroup
 	CommandSelectOutbound
 	CommandURLTest
+	CommandRules
+	CommandResetRuleStatistics
 )
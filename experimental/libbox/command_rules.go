@@ -0,0 +1,188 @@
+package libbox
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+
+	"github.com/sagernet/sing-box/experimental/clashapi"
+	"github.com/sagernet/sing-box/experimental/clashapi/trafficontrol"
+	E "github.com/sagernet/sing/common/exceptions"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/rw"
+)
+
+type Rule struct {
+	Index    int32
+	Type     string
+	Payload  string
+	Outbound string
+	Hits     int64
+	LastHit  int64
+	Upload   int64
+	Download int64
+}
+
+type RuleIterator interface {
+	Next() *Rule
+	HasNext() bool
+}
+
+func (c *CommandClient) GetRules() (RuleIterator, error) {
+	conn, err := c.directConnect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	err = binary.Write(conn, binary.BigEndian, uint8(CommandRules))
+	if err != nil {
+		return nil, err
+	}
+	err = readError(conn)
+	if err != nil {
+		return nil, err
+	}
+	return readRules(conn)
+}
+
+func (c *CommandClient) ResetRuleStatistics() error {
+	conn, err := c.directConnect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = binary.Write(conn, binary.BigEndian, uint8(CommandResetRuleStatistics))
+	if err != nil {
+		return err
+	}
+	return readError(conn)
+}
+
+func (s *CommandServer) handleRules(conn net.Conn) error {
+	defer conn.Close()
+	service, trafficManager, err := s.ruleStatisticsService()
+	if err != nil {
+		return writeError(conn, err)
+	}
+	err = writeError(conn, nil)
+	if err != nil {
+		return err
+	}
+	router := service.instance.Router()
+	rawRules := router.Rules()
+	rules := make([]*Rule, 0, len(rawRules)+1)
+	for i, rawRule := range rawRules {
+		rules = append(rules, newRule(int32(i), rawRule.Type(), rawRule.String(), rawRule.Outbound(), trafficManager.RuleStatistics(rawRule)))
+	}
+	var finalOutbound string
+	if defaultOutbound := router.DefaultOutbound(N.NetworkTCP); defaultOutbound != nil {
+		finalOutbound = defaultOutbound.Tag()
+	}
+	rules = append(rules, newRule(-1, "final", "", finalOutbound, trafficManager.RuleStatistics(nil)))
+	return writeRules(conn, rules)
+}
+
+func (s *CommandServer) handleResetRuleStatistics(conn net.Conn) error {
+	defer conn.Close()
+	_, trafficManager, err := s.ruleStatisticsService()
+	if err != nil {
+		return writeError(conn, err)
+	}
+	trafficManager.ResetRuleStatistics()
+	return writeError(conn, nil)
+}
+
+func (s *CommandServer) ruleStatisticsService() (*BoxService, *trafficontrol.Manager, error) {
+	service := s.service
+	if service == nil {
+		return nil, nil, E.New("service not ready")
+	}
+	clashServer := service.instance.Router().ClashServer()
+	if clashServer == nil {
+		return nil, nil, E.New("Clash API is required for rule statistics")
+	}
+	return service, clashServer.(*clashapi.Server).TrafficManager(), nil
+}
+
+func newRule(index int32, ruleType string, payload string, outbound string, statistics *trafficontrol.RuleStatistics) *Rule {
+	rule := &Rule{
+		Index:    index,
+		Type:     ruleType,
+		Payload:  payload,
+		Outbound: outbound,
+		Hits:     statistics.Hits(),
+	}
+	if lastHit := statistics.LastHit(); !lastHit.IsZero() {
+		rule.LastHit = lastHit.UnixMilli()
+	}
+	rule.Upload, rule.Download = statistics.Total()
+	return rule
+}
+
+func readRules(reader io.Reader) (RuleIterator, error) {
+	var ruleLength uint16
+	err := binary.Read(reader, binary.BigEndian, &ruleLength)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]*Rule, 0, ruleLength)
+	for i := 0; i < int(ruleLength); i++ {
+		var rule Rule
+		err = binary.Read(reader, binary.BigEndian, &rule.Index)
+		if err != nil {
+			return nil, err
+		}
+		rule.Type, err = rw.ReadVString(reader)
+		if err != nil {
+			return nil, err
+		}
+		rule.Payload, err = rw.ReadVString(reader)
+		if err != nil {
+			return nil, err
+		}
+		rule.Outbound, err = rw.ReadVString(reader)
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range []*int64{&rule.Hits, &rule.LastHit, &rule.Upload, &rule.Download} {
+			err = binary.Read(reader, binary.BigEndian, value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		rules = append(rules, &rule)
+	}
+	return newIterator(rules), nil
+}
+
+func writeRules(writer io.Writer, rules []*Rule) error {
+	err := binary.Write(writer, binary.BigEndian, uint16(len(rules)))
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		err = binary.Write(writer, binary.BigEndian, rule.Index)
+		if err != nil {
+			return err
+		}
+		err = rw.WriteVString(writer, rule.Type)
+		if err != nil {
+			return err
+		}
+		err = rw.WriteVString(writer, rule.Payload)
+		if err != nil {
+			return err
+		}
+		err = rw.WriteVString(writer, rule.Outbound)
+		if err != nil {
+			return err
+		}
+		for _, value := range []int64{rule.Hits, rule.LastHit, rule.Upload, rule.Download} {
+			err = binary.Write(writer, binary.BigEndian, value)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -154,6 +154,10 @@ func (s *CommandServer) handleConnection(conn net.Conn) error {
 		return s.handleSelectOutbound(conn)
 	case CommandURLTest:
 		return s.handleURLTest(conn)
+	case CommandRules:
+		return s.handleRules(conn)
+	case CommandResetRuleStatistics:
+		return s.handleResetRuleStatistics(conn)
 	default:
 		return E.New("unknown command: ", command)
 	}
@@ -23,6 +23,7 @@ type Interface interface {
 	UsePlatformInterfaceGetter() bool
 	Interfaces() ([]NetworkInterface, error)
 	UnderNetworkExtension() bool
+	ReadWIFIState() adapter.WIFIState
 	process.Searcher
 	io.Writer
 }
@@ -112,6 +112,25 @@ func (s *StatsService) RoutedPacketConnection(inbound string, outbound string, u
 	return bufio.NewInt64CounterPacketConn(conn, readCounter, writeCounter)
 }
 
+func (s *StatsService) LimitReached(inbound string, user string, limit string) {
+	var name string
+	if user != "" {
+		if !s.users[user] {
+			return
+		}
+		name = "user>>>" + user + ">>>limit>>>" + limit
+	} else {
+		if inbound == "" || !s.inbounds[inbound] {
+			return
+		}
+		name = "inbound>>>" + inbound + ">>>limit>>>" + limit
+	}
+	s.access.Lock()
+	counter := s.loadOrCreateCounter(name)
+	s.access.Unlock()
+	counter.Add(1)
+}
+
 func (s *StatsService) GetStats(ctx context.Context, request *GetStatsRequest) (*GetStatsResponse, error) {
 	s.access.Lock()
 	counter, loaded := s.counters[request.Name]
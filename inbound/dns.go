@@ -0,0 +1,311 @@
+package inbound
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	mDNS "github.com/miekg/dns"
+)
+
+const (
+	dnsMessageContentType = "application/dns-message"
+	dnsTCPMaxQueries      = 64
+)
+
+var _ adapter.Inbound = (*DNS)(nil)
+
+type DNS struct {
+	myInboundAdapter
+	tlsConfig  tls.ServerConfig
+	http       bool
+	path       string
+	httpServer *http.Server
+}
+
+func NewDNS(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.DNSInboundOptions) (*DNS, error) {
+	inbound := &DNS{
+		myInboundAdapter: myInboundAdapter{
+			protocol:      C.TypeDNS,
+			network:       options.Network.Build(),
+			ctx:           ctx,
+			router:        router,
+			logger:        logger,
+			tag:           tag,
+			listenOptions: options.ListenOptions,
+		},
+		http: options.HTTP,
+		path: options.Path,
+	}
+	if options.TLS != nil && options.TLS.Enabled || options.HTTP {
+		if common.Contains(inbound.network, N.NetworkUDP) && options.Network != "" {
+			return nil, E.New("UDP is not supported by DNS over TLS or HTTPS server")
+		}
+		inbound.network = []string{N.NetworkTCP}
+	}
+	if inbound.path == "" {
+		inbound.path = "/dns-query"
+	}
+	if options.TLS != nil {
+		tlsConfig, err := tls.NewServer(ctx, router, logger, common.PtrValueOrDefault(options.TLS))
+		if err != nil {
+			return nil, err
+		}
+		inbound.tlsConfig = tlsConfig
+	}
+	if inbound.listenOptions.ListenPort == 0 {
+		if inbound.http {
+			if inbound.tlsConfig != nil {
+				inbound.listenOptions.ListenPort = 443
+			} else {
+				inbound.listenOptions.ListenPort = 80
+			}
+		} else if inbound.tlsConfig != nil {
+			inbound.listenOptions.ListenPort = 853
+		} else {
+			inbound.listenOptions.ListenPort = 53
+		}
+	}
+	inbound.connHandler = inbound
+	inbound.packetHandler = inbound
+	return inbound, nil
+}
+
+func (d *DNS) Start() error {
+	if d.tlsConfig != nil {
+		err := d.tlsConfig.Start()
+		if err != nil {
+			return E.Cause(err, "create TLS config")
+		}
+	}
+	if !d.http {
+		return d.myInboundAdapter.Start()
+	}
+	tcpListener, err := d.ListenTCP()
+	if err != nil {
+		return err
+	}
+	d.httpServer = &http.Server{
+		Handler: d,
+		BaseContext: func(listener net.Listener) context.Context {
+			return d.ctx
+		},
+	}
+	if d.tlsConfig != nil {
+		d.httpServer.TLSConfig, err = d.tlsConfig.Config()
+		if err != nil {
+			return err
+		}
+	}
+	go func() {
+		var sErr error
+		if d.tlsConfig != nil {
+			sErr = d.httpServer.ServeTLS(tcpListener, "", "")
+		} else {
+			sErr = d.httpServer.Serve(tcpListener)
+		}
+		if sErr != nil && !E.IsClosedOrCanceled(sErr) {
+			d.logger.Error("http server serve error: ", sErr)
+		}
+	}()
+	return nil
+}
+
+func (d *DNS) Close() error {
+	return common.Close(
+		&d.myInboundAdapter,
+		common.PtrOrNil(d.httpServer),
+		d.tlsConfig,
+	)
+}
+
+func (d *DNS) exchange(ctx context.Context, message *mDNS.Msg, metadata adapter.InboundContext) *mDNS.Msg {
+	if len(message.Question) > 0 {
+		d.logger.DebugContext(ctx, "inbound query for ", message.Question[0].Name, " from ", metadata.Source)
+	}
+	response, err := d.router.Exchange(adapter.WithContext(ctx, &metadata), message)
+	if err != nil {
+		d.NewError(ctx, E.Cause(err, "exchange for ", metadata.Source))
+		response = new(mDNS.Msg)
+		response.SetRcode(message, mDNS.RcodeServerFailure)
+	}
+	return response
+}
+
+func (d *DNS) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	if d.tlsConfig != nil {
+		tlsConn, err := tls.ServerHandshake(ctx, conn, d.tlsConfig)
+		if err != nil {
+			return E.Cause(err, "TLS handshake")
+		}
+		conn = tlsConn
+	}
+	metadata.InboundDetour = d.listenOptions.Detour
+	metadata.Destination = M.SocksaddrFromNet(conn.LocalAddr()).Unwrap()
+	var (
+		writeAccess sync.Mutex
+		queries     sync.WaitGroup
+		querySlots  = make(chan struct{}, dnsTCPMaxQueries)
+	)
+	defer conn.Close()
+	// answer queries in flight after the client stops sending
+	defer queries.Wait()
+	for {
+		err := conn.SetReadDeadline(time.Now().Add(C.DNSTimeout))
+		if err != nil {
+			return err
+		}
+		var queryLength uint16
+		err = binary.Read(conn, binary.BigEndian, &queryLength)
+		if err != nil {
+			if E.IsClosedOrCanceled(err) || E.IsTimeout(err) || err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if queryLength == 0 {
+			return dns.RCodeFormatError
+		}
+		buffer := buf.NewSize(int(queryLength))
+		_, err = buffer.ReadFullFrom(conn, int(queryLength))
+		if err != nil {
+			buffer.Release()
+			return err
+		}
+		var message mDNS.Msg
+		err = message.Unpack(buffer.Bytes())
+		buffer.Release()
+		if err != nil {
+			return err
+		}
+		querySlots <- struct{}{}
+		queries.Add(1)
+		go func() {
+			defer func() {
+				<-querySlots
+				queries.Done()
+			}()
+			response := d.exchange(ctx, &message, metadata)
+			responseBuffer := buf.NewPacket()
+			defer responseBuffer.Release()
+			responseBuffer.Resize(2, 0)
+			n, err := response.PackBuffer(responseBuffer.FreeBytes())
+			if err != nil {
+				d.NewError(ctx, E.Cause(err, "pack response"))
+				return
+			}
+			responseBuffer.Truncate(len(n))
+			binary.BigEndian.PutUint16(responseBuffer.ExtendHeader(2), uint16(len(n)))
+			writeAccess.Lock()
+			_, err = conn.Write(responseBuffer.Bytes())
+			writeAccess.Unlock()
+			if err != nil {
+				d.NewError(ctx, E.Cause(err, "write response"))
+			}
+		}()
+	}
+}
+
+func (d *DNS) NewPacket(ctx context.Context, conn N.PacketConn, buffer *buf.Buffer, metadata adapter.InboundContext) error {
+	var message mDNS.Msg
+	err := message.Unpack(buffer.Bytes())
+	if err != nil {
+		return E.Cause(err, "unpack query")
+	}
+	ctx = log.ContextWithNewID(ctx)
+	metadata.InboundDetour = d.listenOptions.Detour
+	metadata.Destination = d.udpAddr
+	go func() {
+		response := d.exchange(ctx, &message, metadata)
+		udpSize := mDNS.MinMsgSize
+		if edns0 := message.IsEdns0(); edns0 != nil && int(edns0.UDPSize()) > udpSize {
+			udpSize = int(edns0.UDPSize())
+		}
+		if response.Len() > udpSize {
+			response = response.Copy()
+			response.Truncate(udpSize)
+		}
+		responseBuffer := buf.NewPacket()
+		responseBytes, err := response.PackBuffer(responseBuffer.FreeBytes())
+		if err != nil {
+			responseBuffer.Release()
+			d.NewError(ctx, E.Cause(err, "pack response"))
+			return
+		}
+		responseBuffer.Truncate(len(responseBytes))
+		err = conn.WritePacket(responseBuffer, metadata.Source)
+		if err != nil {
+			d.NewError(ctx, E.Cause(err, "write response"))
+		}
+	}()
+	return nil
+}
+
+func (d *DNS) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	ctx := log.ContextWithNewID(request.Context())
+	if request.URL.Path != d.path {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var (
+		rawMessage []byte
+		err        error
+	)
+	switch request.Method {
+	case http.MethodGet:
+		rawMessage, err = base64.RawURLEncoding.DecodeString(request.URL.Query().Get("dns"))
+	case http.MethodPost:
+		if request.Header.Get("Content-Type") != dnsMessageContentType {
+			writer.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		rawMessage, err = io.ReadAll(io.LimitReader(request.Body, mDNS.MaxMsgSize))
+	default:
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var message mDNS.Msg
+	if err == nil {
+		err = message.Unpack(rawMessage)
+	}
+	if err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		d.NewError(ctx, E.Cause(err, "bad request from ", request.RemoteAddr))
+		return
+	}
+	var metadata adapter.InboundContext
+	metadata.Inbound = d.tag
+	metadata.InboundType = d.protocol
+	metadata.InboundDetour = d.listenOptions.Detour
+	metadata.InboundOptions = d.listenOptions.InboundOptions
+	metadata.Source = M.ParseSocksaddr(request.RemoteAddr).Unwrap()
+	if localAddr, loaded := request.Context().Value(http.LocalAddrContextKey).(net.Addr); loaded {
+		metadata.Destination = M.SocksaddrFromNet(localAddr).Unwrap()
+	}
+	response := d.exchange(ctx, &message, metadata)
+	responseBytes, err := response.Pack()
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		d.NewError(ctx, E.Cause(err, "pack response"))
+		return
+	}
+	writer.Header().Set("Content-Type", dnsMessageContentType)
+	_, _ = writer.Write(responseBytes)
+}
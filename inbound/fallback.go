@@ -0,0 +1,221 @@
+package inbound
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+const fallbackPeekSize = 2048
+
+// inboundFallback routes connections failing authentication to a fallback server,
+// selected by the HTTP path, the TLS server name or the TLS ALPN.
+type inboundFallback struct {
+	router        adapter.Router
+	logger        log.ContextLogger
+	destination   M.Socksaddr
+	forALPN       map[string]M.Socksaddr
+	forServerName map[string]M.Socksaddr
+	forPath       map[string]M.Socksaddr
+}
+
+func newInboundFallback(router adapter.Router, logger log.ContextLogger, options option.InboundFallbackOptions, tlsEnabled bool) (*inboundFallback, error) {
+	if (options.Fallback == nil || options.Fallback.Server == "") && len(options.FallbackForALPN) == 0 && len(options.FallbackForServerName) == 0 && len(options.FallbackForPath) == 0 {
+		return nil, nil
+	}
+	fallback := &inboundFallback{
+		router: router,
+		logger: logger,
+	}
+	if options.Fallback != nil && options.Fallback.Server != "" {
+		fallback.destination = options.Fallback.Build()
+		if !fallback.destination.IsValid() {
+			return nil, E.New("invalid fallback address: ", fallback.destination)
+		}
+	}
+	if !tlsEnabled {
+		if len(options.FallbackForALPN) > 0 {
+			return nil, E.New("fallback for ALPN is not supported without TLS")
+		}
+		if len(options.FallbackForServerName) > 0 {
+			return nil, E.New("fallback for server name is not supported without TLS")
+		}
+	}
+	var err error
+	fallback.forALPN, err = buildFallbackMap(options.FallbackForALPN, "ALPN")
+	if err != nil {
+		return nil, err
+	}
+	fallback.forServerName, err = buildFallbackMap(options.FallbackForServerName, "server name")
+	if err != nil {
+		return nil, err
+	}
+	fallback.forPath, err = buildFallbackMap(options.FallbackForPath, "path")
+	if err != nil {
+		return nil, err
+	}
+	return fallback, nil
+}
+
+func buildFallbackMap(options map[string]*option.ServerOptions, name string) (map[string]M.Socksaddr, error) {
+	if len(options) == 0 {
+		return nil, nil
+	}
+	destinations := make(map[string]M.Socksaddr)
+	for key, destination := range options {
+		if destination == nil {
+			return nil, E.New("missing fallback address for ", name, " ", key)
+		}
+		fallbackAddr := destination.Build()
+		if !fallbackAddr.IsValid() {
+			return nil, E.New("invalid fallback address for ", name, " ", key, ": ", fallbackAddr)
+		}
+		destinations[key] = fallbackAddr
+	}
+	return destinations, nil
+}
+
+func (f *inboundFallback) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	destination, conn, err := f.match(conn)
+	if err != nil {
+		return err
+	}
+	f.logger.InfoContext(ctx, "fallback connection to ", destination)
+	metadata.Destination = destination
+	return f.router.RouteConnection(ctx, conn, metadata)
+}
+
+func (f *inboundFallback) match(conn net.Conn) (M.Socksaddr, net.Conn, error) {
+	if len(f.forPath) > 0 {
+		var path string
+		path, conn = peekHTTPPath(conn)
+		if path != "" {
+			var (
+				matchedPrefix string
+				destination   M.Socksaddr
+			)
+			for prefix, prefixDestination := range f.forPath {
+				if strings.HasPrefix(path, prefix) && len(prefix) > len(matchedPrefix) {
+					matchedPrefix = prefix
+					destination = prefixDestination
+				}
+			}
+			if destination.IsValid() {
+				return destination, conn, nil
+			}
+		}
+	}
+	if len(f.forServerName) > 0 || len(f.forALPN) > 0 {
+		if tlsConn, loaded := common.Cast[tls.Conn](conn); loaded {
+			connectionState := tlsConn.ConnectionState()
+			if destination, loaded := f.forServerName[connectionState.ServerName]; loaded {
+				return destination, conn, nil
+			}
+			if len(f.forALPN) > 0 && connectionState.NegotiatedProtocol != "" {
+				destination, loaded := f.forALPN[connectionState.NegotiatedProtocol]
+				if !loaded {
+					return M.Socksaddr{}, nil, E.New("fallback disabled for ALPN: ", connectionState.NegotiatedProtocol)
+				}
+				return destination, conn, nil
+			}
+		}
+	}
+	if !f.destination.IsValid() {
+		return M.Socksaddr{}, nil, E.New("fallback disabled by default")
+	}
+	return f.destination, conn, nil
+}
+
+// peekHTTPPath reads the request line of a HTTP/1 request, the returned connection replays the data read.
+func peekHTTPPath(conn net.Conn) (string, net.Conn) {
+	buffer := buf.NewSize(fallbackPeekSize)
+	// do not let a silent client hold the connection, the deadline is set before every read
+	// since a replayed connection ignores it until the recorded data is consumed
+	deadline := time.Now().Add(C.ReadPayloadTimeout)
+	for !buffer.IsFull() && bytes.IndexByte(buffer.Bytes(), '\n') == -1 {
+		conn.SetReadDeadline(deadline)
+		_, err := buffer.ReadOnceFrom(conn)
+		if err != nil {
+			break
+		}
+	}
+	conn.SetReadDeadline(time.Time{})
+	if buffer.IsEmpty() {
+		buffer.Release()
+		return "", conn
+	}
+	var path string
+	if lineEnd := bytes.IndexByte(buffer.Bytes(), '\n'); lineEnd != -1 {
+		requestLine := strings.Fields(string(buffer.To(lineEnd)))
+		if len(requestLine) == 3 && strings.HasPrefix(requestLine[2], "HTTP/1.") {
+			path = requestLine[1]
+		}
+	}
+	return path, bufio.NewCachedConn(conn, buffer)
+}
+
+type fallbackRecordKey struct{}
+
+// fallbackRecordConn records data read before the protocol handshake completes,
+// so that connections failing authentication can be replayed to the fallback.
+type fallbackRecordConn struct {
+	net.Conn
+	recorded []byte
+	done     bool
+}
+
+func contextWithFallbackRecord(ctx context.Context, conn net.Conn) (context.Context, *fallbackRecordConn) {
+	recordConn := &fallbackRecordConn{Conn: conn}
+	return context.WithValue(ctx, fallbackRecordKey{}, recordConn), recordConn
+}
+
+// fallbackHandshakeDone stops recording after the client is authenticated.
+func fallbackHandshakeDone(ctx context.Context) {
+	recordConn, loaded := ctx.Value(fallbackRecordKey{}).(*fallbackRecordConn)
+	if loaded {
+		recordConn.done = true
+		recordConn.recorded = nil
+	}
+}
+
+func (c *fallbackRecordConn) Read(p []byte) (n int, err error) {
+	n, err = c.Conn.Read(p)
+	if n > 0 && !c.done {
+		c.recorded = append(c.recorded, p[:n]...)
+	}
+	return
+}
+
+// Replayable returns if the handshake failed after data has been read.
+func (c *fallbackRecordConn) Replayable() bool {
+	return !c.done && len(c.recorded) > 0
+}
+
+func (c *fallbackRecordConn) Replay() net.Conn {
+	return bufio.NewCachedConn(c.Conn, buf.As(c.recorded))
+}
+
+func (c *fallbackRecordConn) Upstream() any {
+	return c.Conn
+}
+
+func (c *fallbackRecordConn) ReaderReplaceable() bool {
+	return c.done
+}
+
+func (c *fallbackRecordConn) WriterReplaceable() bool {
+	return true
+}
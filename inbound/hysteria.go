@@ -20,18 +20,18 @@ import (
 	F "github.com/sagernet/sing/common/format"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
-
-	"golang.org/x/exp/slices"
 )
 
-var _ adapter.Inbound = (*Hysteria)(nil)
+var (
+	_ adapter.Inbound     = (*Hysteria)(nil)
+	_ adapter.UserManager = (*Hysteria)(nil)
+)
 
 type Hysteria struct {
 	myInboundAdapter
 	quicConfig   *quic.Config
 	tlsConfig    tls.ServerConfig
-	authKey      []string
-	authUser     []string
+	users        *userList[option.HysteriaUser]
 	xplusKey     []byte
 	sendBPS      uint64
 	recvBPS      uint64
@@ -42,6 +42,14 @@ type Hysteria struct {
 	udpDefragger hysteria.Defragger
 }
 
+func hysteriaAuthKey(user option.HysteriaUser) string {
+	if len(user.Auth) > 0 {
+		return string(user.Auth)
+	} else {
+		return user.AuthString
+	}
+}
+
 func NewHysteria(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.HysteriaInboundOptions) (*Hysteria, error) {
 	options.UDPFragmentDefault = true
 	quicConfig := &quic.Config{
@@ -65,16 +73,6 @@ func NewHysteria(ctx context.Context, router adapter.Router, logger log.ContextL
 	if quicConfig.MaxIncomingStreams == 0 {
 		quicConfig.MaxIncomingStreams = hysteria.DefaultMaxIncomingStreams
 	}
-	authKey := common.Map(options.Users, func(it option.HysteriaUser) string {
-		if len(it.Auth) > 0 {
-			return string(it.Auth)
-		} else {
-			return it.AuthString
-		}
-	})
-	authUser := common.Map(options.Users, func(it option.HysteriaUser) string {
-		return it.Name
-	})
 	var xplus []byte
 	if options.Obfs != "" {
 		xplus = []byte(options.Obfs)
@@ -112,9 +110,10 @@ func NewHysteria(ctx context.Context, router adapter.Router, logger log.ContextL
 			tag:           tag,
 			listenOptions: options.ListenOptions,
 		},
-		quicConfig:  quicConfig,
-		authKey:     authKey,
-		authUser:    authUser,
+		quicConfig: quicConfig,
+		users: newUserList(options.Users, func(it option.HysteriaUser) string {
+			return it.Name
+		}),
 		xplusKey:    xplus,
 		sendBPS:     up,
 		recvBPS:     down,
@@ -134,6 +133,25 @@ func NewHysteria(ctx context.Context, router adapter.Router, logger log.ContextL
 	return inbound, nil
 }
 
+func (h *Hysteria) updateUsers(_ []int, users []option.HysteriaUser) error {
+	if len(users) == 0 {
+		return E.New("missing users")
+	}
+	return nil
+}
+
+func (h *Hysteria) Users() any {
+	return h.users.Users()
+}
+
+func (h *Hysteria) AddUsers(content []byte) error {
+	return h.users.Add(content, h.updateUsers)
+}
+
+func (h *Hysteria) RemoveUsers(names []string) error {
+	return h.users.Remove(names, h.updateUsers)
+}
+
 func (h *Hysteria) Start() error {
 	packetConn, err := h.myInboundAdapter.ListenUDP()
 	if err != nil {
@@ -187,15 +205,17 @@ func (h *Hysteria) accept(ctx context.Context, conn quic.Connection) error {
 	if err != nil {
 		return err
 	}
-	if len(h.authKey) > 0 {
-		userIndex := slices.Index(h.authKey, string(clientHello.Auth))
-		if userIndex == -1 {
+	if h.users.Len() > 0 {
+		userIndex, hysteriaUser, loaded := h.users.Find(func(it option.HysteriaUser) bool {
+			return hysteriaAuthKey(it) == string(clientHello.Auth)
+		})
+		if !loaded {
 			err = hysteria.WriteServerHello(controlStream, hysteria.ServerHello{
 				Message: "wrong password",
 			})
 			return E.Errors(E.New("wrong password: ", string(clientHello.Auth)), err)
 		}
-		user := h.authUser[userIndex]
+		user := hysteriaUser.Name
 		if user == "" {
 			user = F.ToString(userIndex)
 		} else {
@@ -13,6 +13,7 @@ import (
 	"github.com/sagernet/sing-shadowsocks/shadowaead"
 	"github.com/sagernet/sing-shadowsocks/shadowaead_2022"
 	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/atomic"
 	"github.com/sagernet/sing/common/auth"
 	"github.com/sagernet/sing/common/buf"
 	E "github.com/sagernet/sing/common/exceptions"
@@ -23,12 +24,14 @@ import (
 var (
 	_ adapter.Inbound           = (*ShadowsocksMulti)(nil)
 	_ adapter.InjectableInbound = (*ShadowsocksMulti)(nil)
+	_ adapter.UserManager       = (*ShadowsocksMulti)(nil)
 )
 
 type ShadowsocksMulti struct {
 	myInboundAdapter
-	service shadowsocks.MultiService[int]
-	users   []option.ShadowsocksUser
+	newService func() (shadowsocks.MultiService[int], error)
+	service    atomic.TypedValue[shadowsocks.MultiService[int]]
+	users      *userList[option.ShadowsocksUser]
 }
 
 func newShadowsocksMulti(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.ShadowsocksInboundOptions) (*ShadowsocksMulti, error) {
@@ -42,6 +45,9 @@ func newShadowsocksMulti(ctx context.Context, router adapter.Router, logger log.
 			tag:           tag,
 			listenOptions: options.ListenOptions,
 		},
+		users: newUserList(options.Users, func(it option.ShadowsocksUser) string {
+			return it.Name
+		}),
 	}
 	inbound.connHandler = inbound
 	inbound.packetHandler = inbound
@@ -51,49 +57,69 @@ func newShadowsocksMulti(ctx context.Context, router adapter.Router, logger log.
 	} else {
 		udpTimeout = int64(C.UDPTimeout.Seconds())
 	}
-	var (
-		service shadowsocks.MultiService[int]
-		err     error
-	)
 	if common.Contains(shadowaead_2022.List, options.Method) {
-		service, err = shadowaead_2022.NewMultiServiceWithPassword[int](
-			options.Method,
-			options.Password,
-			udpTimeout,
-			adapter.NewUpstreamContextHandler(inbound.newConnection, inbound.newPacketConnection, inbound),
-			router.TimeFunc(),
-		)
+		inbound.newService = func() (shadowsocks.MultiService[int], error) {
+			return shadowaead_2022.NewMultiServiceWithPassword[int](
+				options.Method,
+				options.Password,
+				udpTimeout,
+				adapter.NewUpstreamContextHandler(inbound.newConnection, inbound.newPacketConnection, inbound),
+				router.TimeFunc(),
+			)
+		}
 	} else if common.Contains(shadowaead.List, options.Method) {
-		service, err = shadowaead.NewMultiService[int](
-			options.Method,
-			udpTimeout,
-			adapter.NewUpstreamContextHandler(inbound.newConnection, inbound.newPacketConnection, inbound))
+		inbound.newService = func() (shadowsocks.MultiService[int], error) {
+			return shadowaead.NewMultiService[int](
+				options.Method,
+				udpTimeout,
+				adapter.NewUpstreamContextHandler(inbound.newConnection, inbound.newPacketConnection, inbound))
+		}
 	} else {
 		return nil, E.New("unsupported method: " + options.Method)
 	}
+	err := inbound.users.Apply(inbound.updateUsers)
 	if err != nil {
 		return nil, err
 	}
-	err = service.UpdateUsersWithPasswords(common.MapIndexed(options.Users, func(index int, user option.ShadowsocksUser) int {
-		return index
-	}), common.Map(options.Users, func(user option.ShadowsocksUser) string {
-		return user.Password
+	inbound.packetUpstream = inbound.service.Load()
+	return inbound, nil
+}
+
+// updateUsers replaces the service instead of updating its users in place,
+// as the service reads its users without locking.
+func (h *ShadowsocksMulti) updateUsers(ids []int, users []option.ShadowsocksUser) error {
+	service, err := h.newService()
+	if err != nil {
+		return err
+	}
+	err = service.UpdateUsersWithPasswords(ids, common.Map(users, func(it option.ShadowsocksUser) string {
+		return it.Password
 	}))
 	if err != nil {
-		return nil, err
+		return err
 	}
-	inbound.service = service
-	inbound.packetUpstream = service
-	inbound.users = options.Users
-	return inbound, err
+	h.service.Store(service)
+	return nil
+}
+
+func (h *ShadowsocksMulti) Users() any {
+	return h.users.Users()
+}
+
+func (h *ShadowsocksMulti) AddUsers(content []byte) error {
+	return h.users.Add(content, h.updateUsers)
+}
+
+func (h *ShadowsocksMulti) RemoveUsers(names []string) error {
+	return h.users.Remove(names, h.updateUsers)
 }
 
 func (h *ShadowsocksMulti) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
-	return h.service.NewConnection(adapter.WithContext(log.ContextWithNewID(ctx), &metadata), conn, adapter.UpstreamMetadata(metadata))
+	return h.service.Load().NewConnection(adapter.WithContext(log.ContextWithNewID(ctx), &metadata), conn, adapter.UpstreamMetadata(metadata))
 }
 
 func (h *ShadowsocksMulti) NewPacket(ctx context.Context, conn N.PacketConn, buffer *buf.Buffer, metadata adapter.InboundContext) error {
-	return h.service.NewPacket(adapter.WithContext(ctx, &metadata), conn, buffer, adapter.UpstreamMetadata(metadata))
+	return h.service.Load().NewPacket(adapter.WithContext(ctx, &metadata), conn, buffer, adapter.UpstreamMetadata(metadata))
 }
 
 func (h *ShadowsocksMulti) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
@@ -105,7 +131,7 @@ func (h *ShadowsocksMulti) newConnection(ctx context.Context, conn net.Conn, met
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
+	user := h.users.Name(userIndex)
 	if user == "" {
 		user = F.ToString(userIndex)
 	} else {
@@ -120,7 +146,7 @@ func (h *ShadowsocksMulti) newPacketConnection(ctx context.Context, conn N.Packe
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
+	user := h.users.Name(userIndex)
 	if user == "" {
 		user = F.ToString(userIndex)
 	} else {
@@ -0,0 +1,197 @@
+//go:build with_shadowsocksr
+
+package inbound
+
+import (
+	"context"
+	"net"
+	"os"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/clashssr/obfs"
+	"github.com/sagernet/sing-box/transport/clashssr/protocol"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/Dreamacro/clash/transport/shadowsocks/core"
+	"github.com/Dreamacro/clash/transport/shadowsocks/shadowstream"
+)
+
+var (
+	_ adapter.Inbound           = (*ShadowsocksR)(nil)
+	_ adapter.InjectableInbound = (*ShadowsocksR)(nil)
+	_ adapter.UserManager       = (*ShadowsocksR)(nil)
+)
+
+type ShadowsocksR struct {
+	myInboundAdapter
+	cipher   core.Cipher
+	obfs     obfs.ServerObfs
+	protocol protocol.ServerProtocol
+	users    *userList[option.ShadowsocksRUser]
+}
+
+func NewShadowsocksR(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.ShadowsocksRInboundOptions) (*ShadowsocksR, error) {
+	inbound := &ShadowsocksR{
+		myInboundAdapter: myInboundAdapter{
+			protocol:      C.TypeShadowsocksR,
+			network:       []string{N.NetworkTCP},
+			ctx:           ctx,
+			router:        router,
+			logger:        logger,
+			tag:           tag,
+			listenOptions: options.ListenOptions,
+		},
+		users: newUserList(options.Users, func(it option.ShadowsocksRUser) string {
+			return it.Name
+		}),
+	}
+	var cipher string
+	var err error
+	switch options.Method {
+	case "none":
+		cipher = "dummy"
+	default:
+		cipher = options.Method
+	}
+	inbound.cipher, err = core.PickCipher(cipher, nil, options.Password)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		ivSize int
+		key    []byte
+	)
+	if cipher == "dummy" {
+		ivSize = 0
+		key = core.Kdf(options.Password, 16)
+	} else {
+		streamCipher, ok := inbound.cipher.(*core.StreamCipher)
+		if !ok {
+			return nil, E.New(cipher, " is not none or a supported stream cipher in ssr")
+		}
+		ivSize = streamCipher.IVSize()
+		key = streamCipher.Key
+	}
+	obfsName := options.Obfs
+	if obfsName == "" {
+		obfsName = "plain"
+	}
+	serverObfs, obfsOverhead, err := obfs.PickServerObfs(obfsName, &obfs.Base{
+		Key:    key,
+		IVSize: ivSize,
+		Param:  options.ObfsParam,
+	})
+	if err != nil {
+		return nil, E.Cause(err, "initialize obfs")
+	}
+	protocolName := options.Protocol
+	if protocolName == "" {
+		protocolName = "origin"
+	}
+	if len(options.Users) > 0 && protocolName == "origin" {
+		return nil, E.New("users requires an auth protocol")
+	}
+	serverProtocol, err := protocol.PickServerProtocol(protocolName, &protocol.ServerBase{
+		Key:      key,
+		Overhead: obfsOverhead,
+		Users:    inbound.userPassword,
+	})
+	if err != nil {
+		return nil, E.Cause(err, "initialize protocol")
+	}
+	inbound.obfs = serverObfs
+	inbound.protocol = serverProtocol
+	err = inbound.users.Apply(inbound.updateUsers)
+	if err != nil {
+		return nil, err
+	}
+	inbound.connHandler = inbound
+	return inbound, nil
+}
+
+func (h *ShadowsocksR) updateUsers(_ []int, users []option.ShadowsocksRUser) error {
+	userIDs := make(map[uint32]bool)
+	for index, user := range users {
+		if user.Password == "" {
+			return E.New("missing password for user ", index)
+		}
+		if userIDs[user.ID] {
+			return E.New("duplicate user id: ", user.ID)
+		}
+		userIDs[user.ID] = true
+	}
+	return nil
+}
+
+func (h *ShadowsocksR) Users() any {
+	return h.users.Users()
+}
+
+func (h *ShadowsocksR) AddUsers(content []byte) error {
+	return h.users.Add(content, h.updateUsers)
+}
+
+func (h *ShadowsocksR) RemoveUsers(names []string) error {
+	return h.users.Remove(names, h.updateUsers)
+}
+
+// userPassword looks up the user sent in protocol params,
+// connections authenticate with the server password if no users are configured.
+func (h *ShadowsocksR) userPassword(userID uint32) (string, error) {
+	if h.users.Len() == 0 {
+		return "", nil
+	}
+	_, user, loaded := h.users.Find(func(it option.ShadowsocksRUser) bool {
+		return it.ID == userID
+	})
+	if !loaded {
+		return "", E.New("unknown user id: ", userID)
+	}
+	return user.Password, nil
+}
+
+func (h *ShadowsocksR) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	conn = h.cipher.StreamConn(h.obfs.ServerConn(conn))
+	var iv []byte
+	if streamConn, isStreamConn := conn.(*shadowstream.Conn); isStreamConn {
+		var err error
+		iv, err = streamConn.ObtainReadIV()
+		if err != nil {
+			return E.Cause(err, "read iv")
+		}
+	}
+	serverConn := h.protocol.ServerConn(conn, iv)
+	destination, err := M.SocksaddrSerializer.ReadAddrPort(serverConn)
+	if err != nil {
+		return E.Cause(err, "read request")
+	}
+	metadata.Destination = destination
+	var user string
+	if userID, hasUser := serverConn.UserID(); hasUser {
+		_, ssrUser, loaded := h.users.Find(func(it option.ShadowsocksRUser) bool {
+			return it.ID == userID
+		})
+		if loaded && ssrUser.Name != "" {
+			user = ssrUser.Name
+			metadata.User = user
+		} else {
+			user = F.ToString(userID)
+		}
+	}
+	if user != "" {
+		h.logger.InfoContext(ctx, "[", user, "] inbound connection to ", metadata.Destination)
+	} else {
+		h.logger.InfoContext(ctx, "inbound connection to ", metadata.Destination)
+	}
+	return h.router.RouteConnection(ctx, serverConn, metadata)
+}
+
+func (h *ShadowsocksR) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	return os.ErrInvalid
+}
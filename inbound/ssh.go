@@ -0,0 +1,284 @@
+package inbound
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/subtle"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	_ adapter.Inbound           = (*SSH)(nil)
+	_ adapter.InjectableInbound = (*SSH)(nil)
+	_ adapter.UserManager       = (*SSH)(nil)
+)
+
+type SSH struct {
+	myInboundAdapter
+	config     *ssh.ServerConfig
+	users      *userList[option.SSHUser]
+	userAccess sync.RWMutex
+	userMap    map[string]sshUser
+}
+
+type sshUser struct {
+	password       string
+	authorizedKeys [][]byte
+}
+
+func NewSSH(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.SSHInboundOptions) (*SSH, error) {
+	inbound := &SSH{
+		myInboundAdapter: myInboundAdapter{
+			protocol:      C.TypeSSH,
+			network:       []string{N.NetworkTCP},
+			ctx:           ctx,
+			router:        router,
+			logger:        logger,
+			tag:           tag,
+			listenOptions: options.ListenOptions,
+		},
+		users: newUserList(options.Users, func(it option.SSHUser) string {
+			return it.Name
+		}),
+	}
+	inbound.config = &ssh.ServerConfig{
+		PasswordCallback:  inbound.passwordCallback,
+		PublicKeyCallback: inbound.publicKeyCallback,
+		ServerVersion:     options.ServerVersion,
+		MaxAuthTries:      3,
+	}
+	var hostKeys [][]byte
+	for _, hostKey := range options.HostKey {
+		hostKeys = append(hostKeys, []byte(hostKey))
+	}
+	for _, hostKeyPath := range options.HostKeyPath {
+		hostKey, err := os.ReadFile(os.ExpandEnv(hostKeyPath))
+		if err != nil {
+			return nil, E.Cause(err, "read host key")
+		}
+		hostKeys = append(hostKeys, hostKey)
+	}
+	for _, hostKey := range hostKeys {
+		signer, err := ssh.ParsePrivateKey(hostKey)
+		if err != nil {
+			return nil, E.Cause(err, "parse host key")
+		}
+		inbound.config.AddHostKey(signer)
+	}
+	if len(hostKeys) == 0 {
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, E.Cause(err, "generate host key")
+		}
+		signer, err := ssh.NewSignerFromKey(privateKey)
+		if err != nil {
+			return nil, E.Cause(err, "generate host key")
+		}
+		inbound.config.AddHostKey(signer)
+		logger.Warn("host key not configured, a temporary key is used")
+	}
+	err := inbound.users.Apply(inbound.updateUsers)
+	if err != nil {
+		return nil, err
+	}
+	inbound.connHandler = inbound
+	return inbound, nil
+}
+
+func (h *SSH) updateUsers(_ []int, users []option.SSHUser) error {
+	if len(users) == 0 {
+		return E.New("missing users")
+	}
+	userMap := make(map[string]sshUser)
+	for index, user := range users {
+		if user.Name == "" {
+			return E.New("missing name for user ", index)
+		}
+		if _, loaded := userMap[user.Name]; loaded {
+			return E.New("duplicate user name: ", user.Name)
+		}
+		authorizedKeys := []byte(strings.Join(user.AuthorizedKeys, "\n"))
+		if user.AuthorizedKeysPath != "" {
+			content, err := os.ReadFile(os.ExpandEnv(user.AuthorizedKeysPath))
+			if err != nil {
+				return E.Cause(err, "read authorized keys for user ", user.Name)
+			}
+			authorizedKeys = append(append(authorizedKeys, '\n'), content...)
+		}
+		var keys [][]byte
+		for {
+			authorizedKeys = bytes.TrimSpace(authorizedKeys)
+			if len(authorizedKeys) == 0 {
+				break
+			}
+			key, _, _, rest, err := ssh.ParseAuthorizedKey(authorizedKeys)
+			if err != nil {
+				return E.Cause(err, "parse authorized keys for user ", user.Name)
+			}
+			keys = append(keys, key.Marshal())
+			authorizedKeys = rest
+		}
+		if user.Password == "" && len(keys) == 0 {
+			return E.New("missing password or authorized keys for user ", user.Name)
+		}
+		userMap[user.Name] = sshUser{
+			password:       user.Password,
+			authorizedKeys: keys,
+		}
+	}
+	h.userAccess.Lock()
+	h.userMap = userMap
+	h.userAccess.Unlock()
+	return nil
+}
+
+func (h *SSH) Users() any {
+	return h.users.Users()
+}
+
+func (h *SSH) AddUsers(content []byte) error {
+	return h.users.Add(content, h.updateUsers)
+}
+
+func (h *SSH) RemoveUsers(names []string) error {
+	return h.users.Remove(names, h.updateUsers)
+}
+
+func (h *SSH) loadUser(name string) (sshUser, bool) {
+	h.userAccess.RLock()
+	defer h.userAccess.RUnlock()
+	user, loaded := h.userMap[name]
+	return user, loaded
+}
+
+func (h *SSH) passwordCallback(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+	user, loaded := h.loadUser(conn.User())
+	if loaded && user.password != "" && subtle.ConstantTimeCompare([]byte(user.password), password) == 1 {
+		return nil, nil
+	}
+	return nil, E.New("password rejected for ", conn.User())
+}
+
+func (h *SSH) publicKeyCallback(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+	user, loaded := h.loadUser(conn.User())
+	if loaded {
+		publicKey := key.Marshal()
+		for _, authorizedKey := range user.authorizedKeys {
+			if bytes.Equal(authorizedKey, publicKey) {
+				return nil, nil
+			}
+		}
+	}
+	return nil, E.New("public key rejected for ", conn.User())
+}
+
+func (h *SSH) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	err := conn.SetDeadline(time.Now().Add(C.TCPTimeout))
+	if err != nil {
+		return err
+	}
+	serverConn, channels, requests, err := ssh.NewServerConn(conn, h.config)
+	if err != nil {
+		return E.Cause(err, "ssh handshake")
+	}
+	err = conn.SetDeadline(time.Time{})
+	if err != nil {
+		serverConn.Close()
+		return err
+	}
+	defer serverConn.Close()
+	go ssh.DiscardRequests(requests)
+	metadata.User = serverConn.User()
+	h.logger.InfoContext(ctx, "[", metadata.User, "] ssh connection established")
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type: "+newChannel.ChannelType())
+			continue
+		}
+		var request struct {
+			DestinationAddress string
+			DestinationPort    uint32
+			OriginAddress      string
+			OriginPort         uint32
+		}
+		err = ssh.Unmarshal(newChannel.ExtraData(), &request)
+		if err != nil || request.DestinationPort > 65535 {
+			newChannel.Reject(ssh.ConnectionFailed, "invalid direct-tcpip request")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			h.NewError(ctx, E.Cause(err, "accept channel"))
+			continue
+		}
+		go ssh.DiscardRequests(channelRequests)
+		channelMetadata := metadata
+		channelMetadata.Destination = M.ParseSocksaddrHostPort(request.DestinationAddress, uint16(request.DestinationPort))
+		go h.newChannel(log.ContextWithNewID(ctx), &sshChannelConn{
+			Channel:    channel,
+			localAddr:  conn.LocalAddr(),
+			remoteAddr: conn.RemoteAddr(),
+		}, channelMetadata)
+	}
+	return nil
+}
+
+func (h *SSH) newChannel(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) {
+	h.logger.InfoContext(ctx, "[", metadata.User, "] inbound connection to ", metadata.Destination)
+	err := h.router.RouteConnection(ctx, conn, metadata)
+	if err != nil {
+		conn.Close()
+		h.NewError(ctx, E.Cause(err, "process connection from ", metadata.Source))
+	}
+}
+
+func (h *SSH) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	return os.ErrInvalid
+}
+
+// sshChannelConn is a direct-tcpip channel as net.Conn.
+type sshChannelConn struct {
+	ssh.Channel
+	localAddr  net.Addr
+	remoteAddr net.Addr
+}
+
+func (c *sshChannelConn) LocalAddr() net.Addr {
+	return c.localAddr
+}
+
+func (c *sshChannelConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+func (c *sshChannelConn) SetDeadline(t time.Time) error {
+	return os.ErrInvalid
+}
+
+func (c *sshChannelConn) SetReadDeadline(t time.Time) error {
+	return os.ErrInvalid
+}
+
+func (c *sshChannelConn) SetWriteDeadline(t time.Time) error {
+	return os.ErrInvalid
+}
+
+func (c *sshChannelConn) NeedAdditionalReadDeadline() bool {
+	return true
+}
@@ -23,16 +23,16 @@ import (
 var (
 	_ adapter.Inbound           = (*Trojan)(nil)
 	_ adapter.InjectableInbound = (*Trojan)(nil)
+	_ adapter.UserManager       = (*Trojan)(nil)
 )
 
 type Trojan struct {
 	myInboundAdapter
-	service                  *trojan.Service[int]
-	users                    []option.TrojanUser
-	tlsConfig                tls.ServerConfig
-	fallbackAddr             M.Socksaddr
-	fallbackAddrTLSNextProto map[string]M.Socksaddr
-	transport                adapter.V2RayServerTransport
+	service   *trojan.Service[int]
+	users     *userList[option.TrojanUser]
+	tlsConfig tls.ServerConfig
+	fallback  *inboundFallback
+	transport adapter.V2RayServerTransport
 }
 
 func NewTrojan(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.TrojanInboundOptions) (*Trojan, error) {
@@ -46,7 +46,9 @@ func NewTrojan(ctx context.Context, router adapter.Router, logger log.ContextLog
 			tag:           tag,
 			listenOptions: options.ListenOptions,
 		},
-		users: options.Users,
+		users: newUserList(options.Users, func(it option.TrojanUser) string {
+			return it.Name
+		}),
 	}
 	if options.TLS != nil {
 		tlsConfig, err := tls.NewServer(ctx, router, logger, common.PtrValueOrDefault(options.TLS))
@@ -55,36 +57,18 @@ func NewTrojan(ctx context.Context, router adapter.Router, logger log.ContextLog
 		}
 		inbound.tlsConfig = tlsConfig
 	}
+	fallback, err := newInboundFallback(router, logger, options.InboundFallbackOptions, inbound.tlsConfig != nil)
+	if err != nil {
+		return nil, err
+	}
 	var fallbackHandler N.TCPConnectionHandler
-	if options.Fallback != nil && options.Fallback.Server != "" || len(options.FallbackForALPN) > 0 {
-		if options.Fallback != nil && options.Fallback.Server != "" {
-			inbound.fallbackAddr = options.Fallback.Build()
-			if !inbound.fallbackAddr.IsValid() {
-				return nil, E.New("invalid fallback address: ", inbound.fallbackAddr)
-			}
-		}
-		if len(options.FallbackForALPN) > 0 {
-			if inbound.tlsConfig == nil {
-				return nil, E.New("fallback for ALPN is not supported without TLS")
-			}
-			fallbackAddrNextProto := make(map[string]M.Socksaddr)
-			for nextProto, destination := range options.FallbackForALPN {
-				fallbackAddr := destination.Build()
-				if !fallbackAddr.IsValid() {
-					return nil, E.New("invalid fallback address for ALPN ", nextProto, ": ", fallbackAddr)
-				}
-				fallbackAddrNextProto[nextProto] = fallbackAddr
-			}
-			inbound.fallbackAddrTLSNextProto = fallbackAddrNextProto
-		}
+	if fallback != nil {
+		inbound.fallback = fallback
 		fallbackHandler = adapter.NewUpstreamContextHandler(inbound.fallbackConnection, nil, nil)
 	}
 	service := trojan.NewService[int](adapter.NewUpstreamContextHandler(inbound.newConnection, inbound.newPacketConnection, inbound), fallbackHandler)
-	err := service.UpdateUsers(common.MapIndexed(options.Users, func(index int, it option.TrojanUser) int {
-		return index
-	}), common.Map(options.Users, func(it option.TrojanUser) string {
-		return it.Password
-	}))
+	inbound.service = service
+	err = inbound.users.Apply(inbound.updateUsers)
 	if err != nil {
 		return nil, err
 	}
@@ -94,11 +78,28 @@ func NewTrojan(ctx context.Context, router adapter.Router, logger log.ContextLog
 			return nil, E.Cause(err, "create server transport: ", options.Transport.Type)
 		}
 	}
-	inbound.service = service
 	inbound.connHandler = inbound
 	return inbound, nil
 }
 
+func (h *Trojan) updateUsers(ids []int, users []option.TrojanUser) error {
+	return h.service.UpdateUsers(ids, common.Map(users, func(it option.TrojanUser) string {
+		return it.Password
+	}))
+}
+
+func (h *Trojan) Users() any {
+	return h.users.Users()
+}
+
+func (h *Trojan) AddUsers(content []byte) error {
+	return h.users.Add(content, h.updateUsers)
+}
+
+func (h *Trojan) RemoveUsers(names []string) error {
+	return h.users.Remove(names, h.updateUsers)
+}
+
 func (h *Trojan) Start() error {
 	if h.tlsConfig != nil {
 		err := h.tlsConfig.Start()
@@ -169,7 +170,7 @@ func (h *Trojan) newConnection(ctx context.Context, conn net.Conn, metadata adap
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
+	user := h.users.Name(userIndex)
 	if user == "" {
 		user = F.ToString(userIndex)
 	} else {
@@ -180,26 +181,10 @@ func (h *Trojan) newConnection(ctx context.Context, conn net.Conn, metadata adap
 }
 
 func (h *Trojan) fallbackConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
-	var fallbackAddr M.Socksaddr
-	if len(h.fallbackAddrTLSNextProto) > 0 {
-		if tlsConn, loaded := common.Cast[tls.Conn](conn); loaded {
-			connectionState := tlsConn.ConnectionState()
-			if connectionState.NegotiatedProtocol != "" {
-				if fallbackAddr, loaded = h.fallbackAddrTLSNextProto[connectionState.NegotiatedProtocol]; !loaded {
-					return E.New("fallback disabled for ALPN: ", connectionState.NegotiatedProtocol)
-				}
-			}
-		}
+	if h.fallback == nil {
+		return E.New("fallback disabled by default")
 	}
-	if !fallbackAddr.IsValid() {
-		if !h.fallbackAddr.IsValid() {
-			return E.New("fallback disabled by default")
-		}
-		fallbackAddr = h.fallbackAddr
-	}
-	h.logger.InfoContext(ctx, "fallback connection to ", fallbackAddr)
-	metadata.Destination = fallbackAddr
-	return h.router.RouteConnection(ctx, conn, metadata)
+	return h.fallback.NewConnection(ctx, conn, metadata)
 }
 
 func (h *Trojan) newPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
@@ -207,7 +192,7 @@ func (h *Trojan) newPacketConnection(ctx context.Context, conn N.PacketConn, met
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
+	user := h.users.Name(userIndex)
 	if user == "" {
 		user = F.ToString(userIndex)
 	} else {
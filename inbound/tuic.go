@@ -21,12 +21,16 @@ import (
 	"github.com/gofrs/uuid/v5"
 )
 
-var _ adapter.Inbound = (*TUIC)(nil)
+var (
+	_ adapter.Inbound     = (*TUIC)(nil)
+	_ adapter.UserManager = (*TUIC)(nil)
+)
 
 type TUIC struct {
 	myInboundAdapter
 	server    *tuic.Server
 	tlsConfig tls.ServerConfig
+	users     *userList[option.TUICUser]
 }
 
 func NewTUIC(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.TUICInboundOptions) (*TUIC, error) {
@@ -42,16 +46,9 @@ func NewTUIC(ctx context.Context, router adapter.Router, logger log.ContextLogge
 	if err != nil {
 		return nil, err
 	}
-	var users []tuic.User
-	for index, user := range options.Users {
-		if user.UUID == "" {
-			return nil, E.New("missing uuid for user ", index)
-		}
-		userUUID, err := uuid.FromString(user.UUID)
-		if err != nil {
-			return nil, E.Cause(err, "invalid uuid for user ", index)
-		}
-		users = append(users, tuic.User{Name: user.Name, UUID: userUUID, Password: user.Password})
+	users, err := tuicUsers(options.Users)
+	if err != nil {
+		return nil, err
 	}
 	inbound := &TUIC{
 		myInboundAdapter: myInboundAdapter{
@@ -63,6 +60,9 @@ func NewTUIC(ctx context.Context, router adapter.Router, logger log.ContextLogge
 			tag:           tag,
 			listenOptions: options.ListenOptions,
 		},
+		users: newUserList(options.Users, func(it option.TUICUser) string {
+			return it.Name
+		}),
 	}
 	server, err := tuic.NewServer(tuic.ServerOptions{
 		Context:           ctx,
@@ -82,6 +82,41 @@ func NewTUIC(ctx context.Context, router adapter.Router, logger log.ContextLogge
 	return inbound, nil
 }
 
+func tuicUsers(users []option.TUICUser) ([]tuic.User, error) {
+	var serverUsers []tuic.User
+	for index, user := range users {
+		if user.UUID == "" {
+			return nil, E.New("missing uuid for user ", index)
+		}
+		userUUID, err := uuid.FromString(user.UUID)
+		if err != nil {
+			return nil, E.Cause(err, "invalid uuid for user ", index)
+		}
+		serverUsers = append(serverUsers, tuic.User{Name: user.Name, UUID: userUUID, Password: user.Password})
+	}
+	return serverUsers, nil
+}
+
+func (h *TUIC) updateUsers(_ []int, users []option.TUICUser) error {
+	serverUsers, err := tuicUsers(users)
+	if err != nil {
+		return err
+	}
+	return h.server.UpdateUsers(serverUsers)
+}
+
+func (h *TUIC) Users() any {
+	return h.users.Users()
+}
+
+func (h *TUIC) AddUsers(content []byte) error {
+	return h.users.Add(content, h.updateUsers)
+}
+
+func (h *TUIC) RemoveUsers(names []string) error {
+	return h.users.Remove(names, h.updateUsers)
+}
+
 func (h *TUIC) newConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
 	ctx = log.ContextWithNewID(ctx)
 	h.logger.InfoContext(ctx, "inbound connection to ", metadata.Destination)
@@ -0,0 +1,149 @@
+package inbound
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/sagernet/sing-box/common/json"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// userList holds the users of a multi-user inbound under stable ids, so that
+// connections authenticated before a runtime update still resolve their user.
+type userList[U any] struct {
+	access sync.RWMutex
+	nameOf func(U) string
+	ids    []int
+	users  []U
+	nextID int
+}
+
+func newUserList[U any](users []U, nameOf func(U) string) *userList[U] {
+	ids := make([]int, len(users))
+	for i := range ids {
+		ids[i] = i
+	}
+	return &userList[U]{
+		nameOf: nameOf,
+		ids:    ids,
+		users:  users,
+		nextID: len(users),
+	}
+}
+
+// Name returns the name of the user, or empty if the user is unnamed or removed.
+func (l *userList[U]) Name(id int) string {
+	l.access.RLock()
+	defer l.access.RUnlock()
+	index := sort.SearchInts(l.ids, id)
+	if index == len(l.ids) || l.ids[index] != id {
+		return ""
+	}
+	return l.nameOf(l.users[index])
+}
+
+// Find returns the id and the user of the first user matched.
+func (l *userList[U]) Find(match func(U) bool) (int, U, bool) {
+	l.access.RLock()
+	defer l.access.RUnlock()
+	for index, user := range l.users {
+		if match(user) {
+			return l.ids[index], user, true
+		}
+	}
+	var defaultValue U
+	return 0, defaultValue, false
+}
+
+func (l *userList[U]) Len() int {
+	l.access.RLock()
+	defer l.access.RUnlock()
+	return len(l.users)
+}
+
+func (l *userList[U]) Users() []U {
+	l.access.RLock()
+	defer l.access.RUnlock()
+	return append([]U(nil), l.users...)
+}
+
+func (l *userList[U]) Apply(apply func(ids []int, users []U) error) error {
+	l.access.RLock()
+	defer l.access.RUnlock()
+	return apply(l.ids, l.users)
+}
+
+// Add adds users decoded from a JSON array, replacing existing users with the same name.
+func (l *userList[U]) Add(content []byte, apply func(ids []int, users []U) error) error {
+	var newUsers []U
+	err := json.Unmarshal(content, &newUsers)
+	if err != nil {
+		return E.Cause(err, "decode users")
+	}
+	l.access.Lock()
+	defer l.access.Unlock()
+	ids := append([]int(nil), l.ids...)
+	users := append([]U(nil), l.users...)
+	nextID := l.nextID
+	for index, user := range newUsers {
+		name := l.nameOf(user)
+		if name == "" {
+			return E.New("missing name for user ", index)
+		}
+		replaced := false
+		for i := range users {
+			if l.nameOf(users[i]) == name {
+				users[i] = user
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			ids = append(ids, nextID)
+			users = append(users, user)
+			nextID++
+		}
+	}
+	err = apply(ids, users)
+	if err != nil {
+		return err
+	}
+	l.ids = ids
+	l.users = users
+	l.nextID = nextID
+	return nil
+}
+
+func (l *userList[U]) Remove(names []string, apply func(ids []int, users []U) error) error {
+	l.access.Lock()
+	defer l.access.Unlock()
+	removed := make(map[string]bool)
+	for _, name := range names {
+		removed[name] = false
+	}
+	var (
+		ids   []int
+		users []U
+	)
+	for i, user := range l.users {
+		name := l.nameOf(user)
+		if _, loaded := removed[name]; loaded && name != "" {
+			removed[name] = true
+			continue
+		}
+		ids = append(ids, l.ids[i])
+		users = append(users, user)
+	}
+	for name, found := range removed {
+		if !found {
+			return E.New("user not found: ", name)
+		}
+	}
+	err := apply(ids, users)
+	if err != nil {
+		return err
+	}
+	l.ids = ids
+	l.users = users
+	return nil
+}
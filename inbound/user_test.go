@@ -0,0 +1,80 @@
+package inbound
+
+import (
+	"sort"
+	"testing"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testUser struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+func newTestUserList() *userList[testUser] {
+	return newUserList([]testUser{
+		{Name: "a", Password: "a0"},
+		{Name: "b", Password: "b0"},
+	}, func(user testUser) string {
+		return user.Name
+	})
+}
+
+func requireUserIDs(t *testing.T, list *userList[testUser], expected map[int]string) {
+	require.True(t, sort.IntsAreSorted(list.ids))
+	require.Len(t, list.users, len(expected))
+	for id, name := range expected {
+		require.Equal(t, name, list.Name(id))
+	}
+}
+
+func noopApply(ids []int, users []testUser) error {
+	return nil
+}
+
+func TestUserListUpdate(t *testing.T) {
+	t.Parallel()
+	list := newTestUserList()
+	requireUserIDs(t, list, map[int]string{0: "a", 1: "b"})
+
+	require.NoError(t, list.Add([]byte(`[{"name":"c","password":"c0"},{"name":"a","password":"a1"}]`), noopApply))
+	requireUserIDs(t, list, map[int]string{0: "a", 1: "b", 2: "c"})
+	id, user, found := list.Find(func(user testUser) bool {
+		return user.Password == "a1"
+	})
+	require.True(t, found)
+	require.Equal(t, 0, id)
+	require.Equal(t, "a", user.Name)
+
+	require.NoError(t, list.Remove([]string{"a"}, noopApply))
+	requireUserIDs(t, list, map[int]string{1: "b", 2: "c"})
+	require.Empty(t, list.Name(0))
+
+	require.NoError(t, list.Add([]byte(`[{"name":"a","password":"a2"}]`), noopApply))
+	requireUserIDs(t, list, map[int]string{1: "b", 2: "c", 3: "a"})
+	require.Empty(t, list.Name(0))
+}
+
+func TestUserListReject(t *testing.T) {
+	t.Parallel()
+	list := newTestUserList()
+	require.Error(t, list.Add([]byte(`[{"password":"x"}]`), noopApply))
+	require.Error(t, list.Add([]byte(`{}`), noopApply))
+	require.Error(t, list.Remove([]string{"x"}, noopApply))
+	rejectApply := func(ids []int, users []testUser) error {
+		return E.New("rejected")
+	}
+	require.Error(t, list.Add([]byte(`[{"name":"c"}]`), rejectApply))
+	require.Error(t, list.Remove([]string{"a"}, rejectApply))
+	requireUserIDs(t, list, map[int]string{0: "a", 1: "b"})
+	require.Equal(t, 2, list.nextID)
+
+	require.NoError(t, list.Add([]byte(`[{"name":"c"}]`), func(ids []int, users []testUser) error {
+		require.Equal(t, []int{0, 1, 2}, ids)
+		require.Equal(t, "c", users[2].Name)
+		return nil
+	}))
+}
@@ -25,15 +25,17 @@ import (
 var (
 	_ adapter.Inbound           = (*VLESS)(nil)
 	_ adapter.InjectableInbound = (*VLESS)(nil)
+	_ adapter.UserManager       = (*VLESS)(nil)
 )
 
 type VLESS struct {
 	myInboundAdapter
 	ctx       context.Context
-	users     []option.VLESSUser
+	users     *userList[option.VLESSUser]
 	service   *vless.Service[int]
 	tlsConfig tls.ServerConfig
 	transport adapter.V2RayServerTransport
+	fallback  *inboundFallback
 }
 
 func NewVLESS(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.VLESSInboundOptions) (*VLESS, error) {
@@ -47,18 +49,14 @@ func NewVLESS(ctx context.Context, router adapter.Router, logger log.ContextLogg
 			tag:           tag,
 			listenOptions: options.ListenOptions,
 		},
-		ctx:   ctx,
-		users: options.Users,
+		ctx: ctx,
+		users: newUserList(options.Users, func(it option.VLESSUser) string {
+			return it.Name
+		}),
 	}
 	service := vless.NewService[int](logger, adapter.NewUpstreamContextHandler(inbound.newConnection, inbound.newPacketConnection, inbound))
-	service.UpdateUsers(common.MapIndexed(inbound.users, func(index int, _ option.VLESSUser) int {
-		return index
-	}), common.Map(inbound.users, func(it option.VLESSUser) string {
-		return it.UUID
-	}), common.Map(inbound.users, func(it option.VLESSUser) string {
-		return it.Flow
-	}))
 	inbound.service = service
+	common.Must(inbound.users.Apply(inbound.updateUsers))
 	var err error
 	if options.TLS != nil {
 		inbound.tlsConfig, err = tls.NewServer(ctx, router, logger, common.PtrValueOrDefault(options.TLS))
@@ -66,6 +64,10 @@ func NewVLESS(ctx context.Context, router adapter.Router, logger log.ContextLogg
 			return nil, err
 		}
 	}
+	inbound.fallback, err = newInboundFallback(router, logger, options.InboundFallbackOptions, inbound.tlsConfig != nil)
+	if err != nil {
+		return nil, err
+	}
 	if options.Transport != nil {
 		inbound.transport, err = v2ray.NewServerTransport(ctx, common.PtrValueOrDefault(options.Transport), inbound.tlsConfig, (*vlessTransportHandler)(inbound))
 		if err != nil {
@@ -76,6 +78,27 @@ func NewVLESS(ctx context.Context, router adapter.Router, logger log.ContextLogg
 	return inbound, nil
 }
 
+func (h *VLESS) updateUsers(ids []int, users []option.VLESSUser) error {
+	h.service.UpdateUsers(ids, common.Map(users, func(it option.VLESSUser) string {
+		return it.UUID
+	}), common.Map(users, func(it option.VLESSUser) string {
+		return it.Flow
+	}))
+	return nil
+}
+
+func (h *VLESS) Users() any {
+	return h.users.Users()
+}
+
+func (h *VLESS) AddUsers(content []byte) error {
+	return h.users.Add(content, h.updateUsers)
+}
+
+func (h *VLESS) RemoveUsers(names []string) error {
+	return h.users.Remove(names, h.updateUsers)
+}
+
 func (h *VLESS) Start() error {
 	err := common.Start(
 		h.service,
@@ -136,7 +159,16 @@ func (h *VLESS) NewConnection(ctx context.Context, conn net.Conn, metadata adapt
 			return err
 		}
 	}
-	return h.service.NewConnection(adapter.WithContext(log.ContextWithNewID(ctx), &metadata), conn, adapter.UpstreamMetadata(metadata))
+	if h.fallback == nil || h.transport != nil {
+		return h.service.NewConnection(adapter.WithContext(log.ContextWithNewID(ctx), &metadata), conn, adapter.UpstreamMetadata(metadata))
+	}
+	ctx, recordConn := contextWithFallbackRecord(ctx, conn)
+	err = h.service.NewConnection(adapter.WithContext(log.ContextWithNewID(ctx), &metadata), recordConn, adapter.UpstreamMetadata(metadata))
+	if err != nil && recordConn.Replayable() {
+		h.logger.DebugContext(ctx, E.Cause(err, "process connection from ", metadata.Source))
+		return h.fallback.NewConnection(ctx, recordConn.Replay(), metadata)
+	}
+	return err
 }
 
 func (h *VLESS) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
@@ -144,11 +176,12 @@ func (h *VLESS) NewPacketConnection(ctx context.Context, conn N.PacketConn, meta
 }
 
 func (h *VLESS) newConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	fallbackHandshakeDone(ctx)
 	userIndex, loaded := auth.UserFromContext[int](ctx)
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
+	user := h.users.Name(userIndex)
 	if user == "" {
 		user = F.ToString(userIndex)
 	} else {
@@ -158,12 +191,20 @@ func (h *VLESS) newConnection(ctx context.Context, conn net.Conn, metadata adapt
 	return h.router.RouteConnection(ctx, conn, metadata)
 }
 
+func (h *VLESS) fallbackConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	if h.fallback == nil {
+		return os.ErrInvalid
+	}
+	return h.fallback.NewConnection(ctx, conn, metadata)
+}
+
 func (h *VLESS) newPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	fallbackHandshakeDone(ctx)
 	userIndex, loaded := auth.UserFromContext[int](ctx)
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
+	user := h.users.Name(userIndex)
 	if user == "" {
 		user = F.ToString(userIndex)
 	} else {
@@ -191,5 +232,8 @@ func (t *vlessTransportHandler) NewConnection(ctx context.Context, conn net.Conn
 }
 
 func (t *vlessTransportHandler) FallbackConnection(ctx context.Context, conn net.Conn, metadata M.Metadata) error {
-	return os.ErrInvalid
+	return (*VLESS)(t).fallbackConnection(ctx, conn, adapter.InboundContext{
+		Source:      metadata.Source,
+		Destination: metadata.Destination,
+	})
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net"
 	"os"
+	"sync"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/tls"
@@ -14,6 +15,7 @@ import (
 	"github.com/sagernet/sing-vmess"
 	"github.com/sagernet/sing-vmess/packetaddr"
 	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/atomic"
 	"github.com/sagernet/sing/common/auth"
 	E "github.com/sagernet/sing/common/exceptions"
 	F "github.com/sagernet/sing/common/format"
@@ -24,15 +26,20 @@ import (
 var (
 	_ adapter.Inbound           = (*VMess)(nil)
 	_ adapter.InjectableInbound = (*VMess)(nil)
+	_ adapter.UserManager       = (*VMess)(nil)
 )
 
 type VMess struct {
 	myInboundAdapter
-	ctx       context.Context
-	service   *vmess.Service[int]
-	users     []option.VMessUser
-	tlsConfig tls.ServerConfig
-	transport adapter.V2RayServerTransport
+	ctx            context.Context
+	serviceOptions []vmess.ServiceOption
+	serviceAccess  sync.Mutex
+	serviceStarted bool
+	service        atomic.Pointer[vmess.Service[int]]
+	users          *userList[option.VMessUser]
+	tlsConfig      tls.ServerConfig
+	transport      adapter.V2RayServerTransport
+	fallback       *inboundFallback
 }
 
 func NewVMess(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.VMessInboundOptions) (*VMess, error) {
@@ -46,25 +53,18 @@ func NewVMess(ctx context.Context, router adapter.Router, logger log.ContextLogg
 			tag:           tag,
 			listenOptions: options.ListenOptions,
 		},
-		ctx:   ctx,
-		users: options.Users,
+		ctx: ctx,
+		users: newUserList(options.Users, func(it option.VMessUser) string {
+			return it.Name
+		}),
 	}
-	var serviceOptions []vmess.ServiceOption
 	if timeFunc := router.TimeFunc(); timeFunc != nil {
-		serviceOptions = append(serviceOptions, vmess.ServiceWithTimeFunc(timeFunc))
+		inbound.serviceOptions = append(inbound.serviceOptions, vmess.ServiceWithTimeFunc(timeFunc))
 	}
 	if options.Transport != nil && options.Transport.Type != "" {
-		serviceOptions = append(serviceOptions, vmess.ServiceWithDisableHeaderProtection())
+		inbound.serviceOptions = append(inbound.serviceOptions, vmess.ServiceWithDisableHeaderProtection())
 	}
-	service := vmess.NewService[int](adapter.NewUpstreamContextHandler(inbound.newConnection, inbound.newPacketConnection, inbound), serviceOptions...)
-	inbound.service = service
-	err := service.UpdateUsers(common.MapIndexed(options.Users, func(index int, it option.VMessUser) int {
-		return index
-	}), common.Map(options.Users, func(it option.VMessUser) string {
-		return it.UUID
-	}), common.Map(options.Users, func(it option.VMessUser) int {
-		return it.AlterId
-	}))
+	err := inbound.users.Apply(inbound.updateUsers)
 	if err != nil {
 		return nil, err
 	}
@@ -74,6 +74,10 @@ func NewVMess(ctx context.Context, router adapter.Router, logger log.ContextLogg
 			return nil, err
 		}
 	}
+	inbound.fallback, err = newInboundFallback(router, logger, options.InboundFallbackOptions, inbound.tlsConfig != nil)
+	if err != nil {
+		return nil, err
+	}
 	if options.Transport != nil {
 		inbound.transport, err = v2ray.NewServerTransport(ctx, common.PtrValueOrDefault(options.Transport), inbound.tlsConfig, (*vmessTransportHandler)(inbound))
 		if err != nil {
@@ -84,11 +88,54 @@ func NewVMess(ctx context.Context, router adapter.Router, logger log.ContextLogg
 	return inbound, nil
 }
 
+// updateUsers replaces the service instead of updating its users in place,
+// as the service reads its users and regenerates legacy keys without locking.
+func (h *VMess) updateUsers(ids []int, users []option.VMessUser) error {
+	service := vmess.NewService[int](adapter.NewUpstreamContextHandler(h.newConnection, h.newPacketConnection, h), h.serviceOptions...)
+	err := service.UpdateUsers(ids, common.Map(users, func(it option.VMessUser) string {
+		return it.UUID
+	}), common.Map(users, func(it option.VMessUser) int {
+		return it.AlterId
+	}))
+	if err != nil {
+		return err
+	}
+	h.serviceAccess.Lock()
+	defer h.serviceAccess.Unlock()
+	if h.serviceStarted {
+		err = service.Start()
+		if err != nil {
+			return err
+		}
+	}
+	oldService := h.service.Swap(service)
+	if h.serviceStarted && oldService != nil {
+		return oldService.Close()
+	}
+	return nil
+}
+
+func (h *VMess) Users() any {
+	return h.users.Users()
+}
+
+func (h *VMess) AddUsers(content []byte) error {
+	return h.users.Add(content, h.updateUsers)
+}
+
+func (h *VMess) RemoveUsers(names []string) error {
+	return h.users.Remove(names, h.updateUsers)
+}
+
 func (h *VMess) Start() error {
-	err := common.Start(
-		h.service,
-		h.tlsConfig,
-	)
+	h.serviceAccess.Lock()
+	err := h.service.Load().Start()
+	h.serviceStarted = err == nil
+	h.serviceAccess.Unlock()
+	if err != nil {
+		return err
+	}
+	err = common.Start(h.tlsConfig)
 	if err != nil {
 		return err
 	}
@@ -123,12 +170,18 @@ func (h *VMess) Start() error {
 }
 
 func (h *VMess) Close() error {
-	return common.Close(
-		h.service,
+	var err error
+	h.serviceAccess.Lock()
+	if h.serviceStarted {
+		h.serviceStarted = false
+		err = h.service.Load().Close()
+	}
+	h.serviceAccess.Unlock()
+	return E.Errors(err, common.Close(
 		&h.myInboundAdapter,
 		h.tlsConfig,
 		h.transport,
-	)
+	))
 }
 
 func (h *VMess) newTransportConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
@@ -144,7 +197,16 @@ func (h *VMess) NewConnection(ctx context.Context, conn net.Conn, metadata adapt
 			return err
 		}
 	}
-	return h.service.NewConnection(adapter.WithContext(log.ContextWithNewID(ctx), &metadata), conn, adapter.UpstreamMetadata(metadata))
+	if h.fallback == nil || h.transport != nil {
+		return h.service.Load().NewConnection(adapter.WithContext(log.ContextWithNewID(ctx), &metadata), conn, adapter.UpstreamMetadata(metadata))
+	}
+	ctx, recordConn := contextWithFallbackRecord(ctx, conn)
+	err = h.service.Load().NewConnection(adapter.WithContext(log.ContextWithNewID(ctx), &metadata), recordConn, adapter.UpstreamMetadata(metadata))
+	if err != nil && recordConn.Replayable() {
+		h.logger.DebugContext(ctx, E.Cause(err, "process connection from ", metadata.Source))
+		return h.fallback.NewConnection(ctx, recordConn.Replay(), metadata)
+	}
+	return err
 }
 
 func (h *VMess) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
@@ -152,11 +214,12 @@ func (h *VMess) NewPacketConnection(ctx context.Context, conn N.PacketConn, meta
 }
 
 func (h *VMess) newConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	fallbackHandshakeDone(ctx)
 	userIndex, loaded := auth.UserFromContext[int](ctx)
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
+	user := h.users.Name(userIndex)
 	if user == "" {
 		user = F.ToString(userIndex)
 	} else {
@@ -166,12 +229,20 @@ func (h *VMess) newConnection(ctx context.Context, conn net.Conn, metadata adapt
 	return h.router.RouteConnection(ctx, conn, metadata)
 }
 
+func (h *VMess) fallbackConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	if h.fallback == nil {
+		return os.ErrInvalid
+	}
+	return h.fallback.NewConnection(ctx, conn, metadata)
+}
+
 func (h *VMess) newPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	fallbackHandshakeDone(ctx)
 	userIndex, loaded := auth.UserFromContext[int](ctx)
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
+	user := h.users.Name(userIndex)
 	if user == "" {
 		user = F.ToString(userIndex)
 	} else {
@@ -199,5 +270,8 @@ func (t *vmessTransportHandler) NewConnection(ctx context.Context, conn net.Conn
 }
 
 func (t *vmessTransportHandler) FallbackConnection(ctx context.Context, conn net.Conn, metadata M.Metadata) error {
-	return os.ErrInvalid
+	return (*VMess)(t).fallbackConnection(ctx, conn, adapter.InboundContext{
+		Source:      metadata.Source,
+		Destination: metadata.Destination,
+	})
 }
@@ -0,0 +1,224 @@
+//go:build with_wireguard
+
+package inbound
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/wireguard"
+	"github.com/sagernet/sing-tun"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/debug"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/wireguard-go/device"
+)
+
+var _ adapter.Inbound = (*WireGuard)(nil)
+
+type WireGuard struct {
+	myInboundAdapter
+	ipcConf      string
+	workers      int
+	mtu          uint32
+	udpTimeout   int64
+	peers        []wireGuardPeer
+	device       *device.Device
+	serverDevice *wireguard.ServerDevice
+	tunStack     tun.Stack
+}
+
+type wireGuardPeer struct {
+	name       string
+	allowedIPs []netip.Prefix
+}
+
+func NewWireGuard(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.WireGuardInboundOptions) (*WireGuard, error) {
+	inbound := &WireGuard{
+		myInboundAdapter: myInboundAdapter{
+			protocol:      C.TypeWireGuard,
+			network:       []string{N.NetworkUDP},
+			ctx:           ctx,
+			router:        router,
+			logger:        logger,
+			tag:           tag,
+			listenOptions: options.ListenOptions,
+		},
+		workers: options.Workers,
+		mtu:     options.MTU,
+	}
+	if inbound.mtu == 0 {
+		inbound.mtu = 1408
+	}
+	if options.UDPTimeout != 0 {
+		inbound.udpTimeout = options.UDPTimeout
+	} else {
+		inbound.udpTimeout = int64(C.UDPTimeout.Seconds())
+	}
+	var privateKey string
+	{
+		bytes, err := base64.StdEncoding.DecodeString(options.PrivateKey)
+		if err != nil {
+			return nil, E.Cause(err, "decode private key")
+		}
+		privateKey = hex.EncodeToString(bytes)
+	}
+	if len(options.Peers) == 0 {
+		return nil, E.New("missing peers")
+	}
+	ipcConf := "private_key=" + privateKey
+	for i, peer := range options.Peers {
+		var peerPublicKey, preSharedKey string
+		{
+			bytes, err := base64.StdEncoding.DecodeString(peer.PublicKey)
+			if err != nil {
+				return nil, E.Cause(err, "decode public key for peer ", i)
+			}
+			peerPublicKey = hex.EncodeToString(bytes)
+		}
+		if peer.PreSharedKey != "" {
+			bytes, err := base64.StdEncoding.DecodeString(peer.PreSharedKey)
+			if err != nil {
+				return nil, E.Cause(err, "decode pre shared key for peer ", i)
+			}
+			preSharedKey = hex.EncodeToString(bytes)
+		}
+		ipcConf += "\npublic_key=" + peerPublicKey
+		if preSharedKey != "" {
+			ipcConf += "\npreshared_key=" + preSharedKey
+		}
+		if len(peer.AllowedIPs) == 0 {
+			return nil, E.New("missing allowed_ips for peer ", i)
+		}
+		var allowedIPs []netip.Prefix
+		for _, allowedIP := range peer.AllowedIPs {
+			prefix, err := netip.ParsePrefix(allowedIP)
+			if err != nil {
+				return nil, E.Cause(err, "parse allowed_ips for peer ", i)
+			}
+			allowedIPs = append(allowedIPs, prefix.Masked())
+			ipcConf += "\nallowed_ip=" + prefix.Masked().String()
+		}
+		inbound.peers = append(inbound.peers, wireGuardPeer{
+			name:       peer.Name,
+			allowedIPs: allowedIPs,
+		})
+	}
+	inbound.ipcConf = ipcConf
+	serverDevice, err := wireguard.NewServerDevice(inbound.mtu)
+	if err != nil {
+		return nil, E.Cause(err, "create WireGuard device")
+	}
+	tunStack, err := tun.NewStack("gvisor", tun.StackOptions{
+		Context:    ctx,
+		Tun:        serverDevice.Tun(),
+		MTU:        inbound.mtu,
+		UDPTimeout: inbound.udpTimeout,
+		Handler:    inbound,
+		Logger:     logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	inbound.serverDevice = serverDevice
+	inbound.tunStack = tunStack
+	return inbound, nil
+}
+
+func (w *WireGuard) Start() error {
+	packetConn, err := w.myInboundAdapter.ListenUDP()
+	if err != nil {
+		return err
+	}
+	err = w.tunStack.Start()
+	if err != nil {
+		return err
+	}
+	w.device = device.NewDevice(w.serverDevice, wireguard.NewServerBind(packetConn), &device.Logger{
+		Verbosef: func(format string, args ...interface{}) {
+			w.logger.Debug(fmt.Sprintf(strings.ToLower(format), args...))
+		},
+		Errorf: func(format string, args ...interface{}) {
+			w.logger.Error(fmt.Sprintf(strings.ToLower(format), args...))
+		},
+	}, w.workers)
+	if debug.Enabled {
+		w.logger.Trace("created wireguard ipc conf: \n", w.ipcConf)
+	}
+	err = w.device.IpcSet(w.ipcConf)
+	if err != nil {
+		return E.Cause(err, "setup wireguard")
+	}
+	return w.serverDevice.Start()
+}
+
+func (w *WireGuard) Close() error {
+	if w.device != nil {
+		w.device.Close()
+	}
+	return common.Close(
+		w.tunStack,
+		&w.myInboundAdapter,
+	)
+}
+
+// peerName looks up the peer owning the source address, using the same
+// longest prefix match as WireGuard's cryptokey routing.
+func (w *WireGuard) peerName(source netip.Addr) string {
+	var (
+		name string
+		bits = -1
+	)
+	for _, peer := range w.peers {
+		for _, prefix := range peer.allowedIPs {
+			if prefix.Bits() > bits && prefix.Contains(source) {
+				name = peer.name
+				bits = prefix.Bits()
+			}
+		}
+	}
+	return name
+}
+
+func (w *WireGuard) NewConnection(ctx context.Context, conn net.Conn, upstreamMetadata M.Metadata) error {
+	ctx = log.ContextWithNewID(ctx)
+	metadata := w.createMetadata(conn, adapter.InboundContext{
+		Source:      upstreamMetadata.Source.Unwrap(),
+		Destination: upstreamMetadata.Destination.Unwrap(),
+	})
+	metadata.User = w.peerName(metadata.Source.Addr)
+	w.logger.InfoContext(ctx, "inbound connection from ", metadata.Source)
+	w.logger.InfoContext(ctx, "inbound connection to ", metadata.Destination)
+	err := w.router.RouteConnection(ctx, conn, metadata)
+	if err != nil {
+		w.NewError(ctx, err)
+	}
+	return nil
+}
+
+func (w *WireGuard) NewPacketConnection(ctx context.Context, conn N.PacketConn, upstreamMetadata M.Metadata) error {
+	ctx = log.ContextWithNewID(ctx)
+	metadata := w.createPacketMetadata(conn, adapter.InboundContext{
+		Source:      upstreamMetadata.Source.Unwrap(),
+		Destination: upstreamMetadata.Destination.Unwrap(),
+	})
+	metadata.User = w.peerName(metadata.Source.Addr)
+	w.logger.InfoContext(ctx, "inbound packet connection from ", metadata.Source)
+	w.logger.InfoContext(ctx, "inbound packet connection to ", metadata.Destination)
+	err := w.router.RoutePacketConnection(ctx, conn, metadata)
+	if err != nil {
+		w.NewError(ctx, err)
+	}
+	return nil
+}
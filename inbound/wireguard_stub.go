@@ -0,0 +1,16 @@
+//go:build !with_wireguard
+
+package inbound
+
+import (
+	"context"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func NewWireGuard(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.WireGuardInboundOptions) (adapter.Inbound, error) {
+	return nil, E.New(`WireGuard is not included in this build, rebuild with -tags with_wireguard`)
+}
@@ -9,6 +9,9 @@ type ClashAPIOptions struct {
 	DefaultMode              string `json:"default_mode,omitempty"`
 	StoreSelected            bool   `json:"store_selected,omitempty"`
 	StoreFakeIP              bool   `json:"store_fakeip,omitempty"`
+	StoreDNS                 bool   `json:"store_dns,omitempty"`
+	StoreLimit               bool   `json:"store_limit,omitempty"`
+	StoreUsers               bool   `json:"store_users,omitempty"`
 	CacheFile                string `json:"cache_file,omitempty"`
 	CacheID                  string `json:"cache_id,omitempty"`
 }
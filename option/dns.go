@@ -6,17 +6,45 @@ type DNSOptions struct {
 	Final          string             `json:"final,omitempty"`
 	ReverseMapping bool               `json:"reverse_mapping,omitempty"`
 	FakeIP         *DNSFakeIPOptions  `json:"fakeip,omitempty"`
+	Hosts          *DNSHostsOptions   `json:"hosts,omitempty"`
+	ServeStale     bool               `json:"serve_stale,omitempty"`
+	StaleMaxAge    Duration           `json:"stale_max_age,omitempty"`
+	Prefetch       bool               `json:"prefetch,omitempty"`
 	DNSClientOptions
 }
 
 type DNSServerOptions struct {
-	Tag                  string         `json:"tag,omitempty"`
-	Address              string         `json:"address"`
-	AddressResolver      string         `json:"address_resolver,omitempty"`
-	AddressStrategy      DomainStrategy `json:"address_strategy,omitempty"`
-	AddressFallbackDelay Duration       `json:"address_fallback_delay,omitempty"`
-	Strategy             DomainStrategy `json:"strategy,omitempty"`
-	Detour               string         `json:"detour,omitempty"`
+	Tag                  string               `json:"tag,omitempty"`
+	Address              string               `json:"address"`
+	AddressResolver      string               `json:"address_resolver,omitempty"`
+	AddressStrategy      DomainStrategy       `json:"address_strategy,omitempty"`
+	AddressFallbackDelay Duration             `json:"address_fallback_delay,omitempty"`
+	Strategy             DomainStrategy       `json:"strategy,omitempty"`
+	Detour               string               `json:"detour,omitempty"`
+	Upstreams            []DNSUpstream        `json:"upstreams,omitempty"`
+	ClientSubnet         string               `json:"client_subnet,omitempty"`
+	TLS                  *DNSServerTLSOptions `json:"tls,omitempty"`
+	PreferHTTP3          bool                 `json:"prefer_http3,omitempty"`
+	IdleTimeout          Duration             `json:"idle_timeout,omitempty"`
+	MaxConcurrentQueries int                  `json:"max_concurrent_queries,omitempty"`
+}
+
+type DNSServerTLSOptions struct {
+	ServerName            string           `json:"server_name,omitempty"`
+	Insecure              bool             `json:"insecure,omitempty"`
+	Certificate           string           `json:"certificate,omitempty"`
+	CertificatePath       string           `json:"certificate_path,omitempty"`
+	CertificateSHA256     Listable[string] `json:"certificate_sha256,omitempty"`
+	ClientCertificate     string           `json:"client_certificate,omitempty"`
+	ClientCertificatePath string           `json:"client_certificate_path,omitempty"`
+	ClientKey             string           `json:"client_key,omitempty"`
+	ClientKeyPath         string           `json:"client_key_path,omitempty"`
+}
+
+type DNSUpstream struct {
+	Server string           `json:"server"`
+	GeoIP  Listable[string] `json:"geoip,omitempty"`
+	IPCIDR Listable[string] `json:"ip_cidr,omitempty"`
 }
 
 type DNSClientOptions struct {
@@ -27,7 +55,31 @@ type DNSClientOptions struct {
 }
 
 type DNSFakeIPOptions struct {
-	Enabled    bool          `json:"enabled,omitempty"`
-	Inet4Range *ListenPrefix `json:"inet4_range,omitempty"`
-	Inet6Range *ListenPrefix `json:"inet6_range,omitempty"`
+	Enabled              bool             `json:"enabled,omitempty"`
+	Inet4Range           *ListenPrefix    `json:"inet4_range,omitempty"`
+	Inet6Range           *ListenPrefix    `json:"inet6_range,omitempty"`
+	ExcludeDomain        Listable[string] `json:"exclude_domain,omitempty"`
+	ExcludeDomainSuffix  Listable[string] `json:"exclude_domain_suffix,omitempty"`
+	ExcludeDomainKeyword Listable[string] `json:"exclude_domain_keyword,omitempty"`
+	ExcludeDomainRegex   Listable[string] `json:"exclude_domain_regex,omitempty"`
+}
+
+type DNSHostsOptions struct {
+	Path       Listable[string]            `json:"path,omitempty"`
+	Predefined map[string]Listable[string] `json:"predefined,omitempty"`
+}
+
+type DNSPredefinedOptions struct {
+	RCode  string           `json:"rcode,omitempty"`
+	Answer Listable[string] `json:"answer,omitempty"`
+	Ns     Listable[string] `json:"ns,omitempty"`
+	Extra  Listable[string] `json:"extra,omitempty"`
+}
+
+type DNSInboundOptions struct {
+	ListenOptions
+	Network NetworkList        `json:"network,omitempty"`
+	TLS     *InboundTLSOptions `json:"tls,omitempty"`
+	HTTP    bool               `json:"http,omitempty"`
+	Path    string             `json:"path,omitempty"`
 }
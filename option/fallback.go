@@ -0,0 +1,8 @@
+package option
+
+type InboundFallbackOptions struct {
+	Fallback              *ServerOptions            `json:"fallback,omitempty"`
+	FallbackForALPN       map[string]*ServerOptions `json:"fallback_for_alpn,omitempty"`
+	FallbackForServerName map[string]*ServerOptions `json:"fallback_for_server_name,omitempty"`
+	FallbackForPath       map[string]*ServerOptions `json:"fallback_for_path,omitempty"`
+}
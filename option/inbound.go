@@ -7,23 +7,28 @@ import (
 )
 
 type _Inbound struct {
-	Type               string                    `json:"type"`
-	Tag                string                    `json:"tag,omitempty"`
-	TunOptions         TunInboundOptions         `json:"-"`
-	RedirectOptions    RedirectInboundOptions    `json:"-"`
-	TProxyOptions      TProxyInboundOptions      `json:"-"`
-	DirectOptions      DirectInboundOptions      `json:"-"`
-	SocksOptions       SocksInboundOptions       `json:"-"`
-	HTTPOptions        HTTPMixedInboundOptions   `json:"-"`
-	MixedOptions       HTTPMixedInboundOptions   `json:"-"`
-	ShadowsocksOptions ShadowsocksInboundOptions `json:"-"`
-	VMessOptions       VMessInboundOptions       `json:"-"`
-	TrojanOptions      TrojanInboundOptions      `json:"-"`
-	NaiveOptions       NaiveInboundOptions       `json:"-"`
-	HysteriaOptions    HysteriaInboundOptions    `json:"-"`
-	ShadowTLSOptions   ShadowTLSInboundOptions   `json:"-"`
-	VLESSOptions       VLESSInboundOptions       `json:"-"`
-	TUICOptions        TUICInboundOptions        `json:"-"`
+	Type                string                     `json:"type"`
+	Tag                 string                     `json:"tag,omitempty"`
+	TunOptions          TunInboundOptions          `json:"-"`
+	RedirectOptions     RedirectInboundOptions     `json:"-"`
+	TProxyOptions       TProxyInboundOptions       `json:"-"`
+	DirectOptions       DirectInboundOptions       `json:"-"`
+	SocksOptions        SocksInboundOptions        `json:"-"`
+	HTTPOptions         HTTPMixedInboundOptions    `json:"-"`
+	MixedOptions        HTTPMixedInboundOptions    `json:"-"`
+	ShadowsocksOptions  ShadowsocksInboundOptions  `json:"-"`
+	ShadowsocksROptions ShadowsocksRInboundOptions `json:"-"`
+	VMessOptions        VMessInboundOptions        `json:"-"`
+	TrojanOptions       TrojanInboundOptions       `json:"-"`
+	NaiveOptions        NaiveInboundOptions        `json:"-"`
+	HysteriaOptions     HysteriaInboundOptions     `json:"-"`
+	ShadowTLSOptions    ShadowTLSInboundOptions    `json:"-"`
+	VLESSOptions        VLESSInboundOptions        `json:"-"`
+	TUICOptions         TUICInboundOptions         `json:"-"`
+	Hysteria2Options    Hysteria2InboundOptions    `json:"-"`
+	WireGuardOptions    WireGuardInboundOptions    `json:"-"`
+	DNSOptions          DNSInboundOptions          `json:"-"`
+	SSHOptions          SSHInboundOptions          `json:"-"`
 }
 
 type Inbound _Inbound
@@ -47,6 +52,8 @@ func (h Inbound) MarshalJSON() ([]byte, error) {
 		v = h.MixedOptions
 	case C.TypeShadowsocks:
 		v = h.ShadowsocksOptions
+	case C.TypeShadowsocksR:
+		v = h.ShadowsocksROptions
 	case C.TypeVMess:
 		v = h.VMessOptions
 	case C.TypeTrojan:
@@ -61,6 +68,14 @@ func (h Inbound) MarshalJSON() ([]byte, error) {
 		v = h.VLESSOptions
 	case C.TypeTUIC:
 		v = h.TUICOptions
+	case C.TypeHysteria2:
+		v = h.Hysteria2Options
+	case C.TypeWireGuard:
+		v = h.WireGuardOptions
+	case C.TypeDNS:
+		v = h.DNSOptions
+	case C.TypeSSH:
+		v = h.SSHOptions
 	default:
 		return nil, E.New("unknown inbound type: ", h.Type)
 	}
@@ -90,6 +105,8 @@ func (h *Inbound) UnmarshalJSON(bytes []byte) error {
 		v = &h.MixedOptions
 	case C.TypeShadowsocks:
 		v = &h.ShadowsocksOptions
+	case C.TypeShadowsocksR:
+		v = &h.ShadowsocksROptions
 	case C.TypeVMess:
 		v = &h.VMessOptions
 	case C.TypeTrojan:
@@ -104,6 +121,14 @@ func (h *Inbound) UnmarshalJSON(bytes []byte) error {
 		v = &h.VLESSOptions
 	case C.TypeTUIC:
 		v = &h.TUICOptions
+	case C.TypeHysteria2:
+		v = &h.Hysteria2Options
+	case C.TypeWireGuard:
+		v = &h.WireGuardOptions
+	case C.TypeDNS:
+		v = &h.DNSOptions
+	case C.TypeSSH:
+		v = &h.SSHOptions
 	default:
 		return E.New("unknown inbound type: ", h.Type)
 	}
@@ -114,11 +139,61 @@ func (h *Inbound) UnmarshalJSON(bytes []byte) error {
 	return nil
 }
 
+// InboundOptions returns the common inbound options of the inbound.
+func (h Inbound) InboundOptions() InboundOptions {
+	switch h.Type {
+	case C.TypeTun:
+		return h.TunOptions.InboundOptions
+	case C.TypeRedirect:
+		return h.RedirectOptions.InboundOptions
+	case C.TypeTProxy:
+		return h.TProxyOptions.InboundOptions
+	case C.TypeDirect:
+		return h.DirectOptions.InboundOptions
+	case C.TypeSocks:
+		return h.SocksOptions.InboundOptions
+	case C.TypeHTTP:
+		return h.HTTPOptions.InboundOptions
+	case C.TypeMixed:
+		return h.MixedOptions.InboundOptions
+	case C.TypeShadowsocks:
+		return h.ShadowsocksOptions.InboundOptions
+	case C.TypeShadowsocksR:
+		return h.ShadowsocksROptions.InboundOptions
+	case C.TypeVMess:
+		return h.VMessOptions.InboundOptions
+	case C.TypeTrojan:
+		return h.TrojanOptions.InboundOptions
+	case C.TypeNaive:
+		return h.NaiveOptions.InboundOptions
+	case C.TypeHysteria:
+		return h.HysteriaOptions.InboundOptions
+	case C.TypeShadowTLS:
+		return h.ShadowTLSOptions.InboundOptions
+	case C.TypeVLESS:
+		return h.VLESSOptions.InboundOptions
+	case C.TypeTUIC:
+		return h.TUICOptions.InboundOptions
+	case C.TypeHysteria2:
+		return h.Hysteria2Options.InboundOptions
+	case C.TypeWireGuard:
+		return h.WireGuardOptions.InboundOptions
+	case C.TypeDNS:
+		return h.DNSOptions.InboundOptions
+	case C.TypeSSH:
+		return h.SSHOptions.InboundOptions
+	default:
+		return InboundOptions{}
+	}
+}
+
 type InboundOptions struct {
-	SniffEnabled             bool           `json:"sniff,omitempty"`
-	SniffOverrideDestination bool           `json:"sniff_override_destination,omitempty"`
-	SniffTimeout             Duration       `json:"sniff_timeout,omitempty"`
-	DomainStrategy           DomainStrategy `json:"domain_strategy,omitempty"`
+	SniffEnabled             bool                     `json:"sniff,omitempty"`
+	SniffOverrideDestination bool                     `json:"sniff_override_destination,omitempty"`
+	SniffTimeout             Duration                 `json:"sniff_timeout,omitempty"`
+	DomainStrategy           DomainStrategy           `json:"domain_strategy,omitempty"`
+	Limit                    *InboundLimitOptions     `json:"limit,omitempty"`
+	Multiplex                *InboundMultiplexOptions `json:"multiplex,omitempty"`
 }
 
 type ListenOptions struct {
@@ -0,0 +1,21 @@
+package option
+
+import "time"
+
+type InboundLimitOptions struct {
+	UpMbps         int                `json:"up_mbps,omitempty"`
+	DownMbps       int                `json:"down_mbps,omitempty"`
+	MaxConnections int                `json:"max_connections,omitempty"`
+	Users          []UserLimitOptions `json:"users,omitempty"`
+}
+
+type UserLimitOptions struct {
+	Name               Listable[string] `json:"name"`
+	UpMbps             int              `json:"up_mbps,omitempty"`
+	DownMbps           int              `json:"down_mbps,omitempty"`
+	QuotaMB            uint64           `json:"quota_mb,omitempty"`
+	QuotaResetInterval Duration         `json:"quota_reset_interval,omitempty"`
+	ExpireAt           *time.Time       `json:"expire_at,omitempty"`
+	MaxConnections     int              `json:"max_connections,omitempty"`
+	MaxIPs             int              `json:"max_ips,omitempty"`
+}
@@ -0,0 +1,15 @@
+package option
+
+type InboundMultiplexOptions struct {
+	Protocol    Listable[string] `json:"protocol,omitempty"`
+	MaxStreams  int              `json:"max_streams,omitempty"`
+	Padding     bool             `json:"padding,omitempty"`
+	IdleTimeout Duration         `json:"idle_timeout,omitempty"`
+	Brutal      *BrutalOptions   `json:"brutal,omitempty"`
+}
+
+type BrutalOptions struct {
+	Enabled  bool `json:"enabled,omitempty"`
+	UpMbps   int  `json:"up_mbps,omitempty"`
+	DownMbps int  `json:"down_mbps,omitempty"`
+}
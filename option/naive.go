@@ -8,3 +8,13 @@ type NaiveInboundOptions struct {
 	Network NetworkList        `json:"network,omitempty"`
 	TLS     *InboundTLSOptions `json:"tls,omitempty"`
 }
+
+type NaiveOutboundOptions struct {
+	DialerOptions
+	ServerOptions
+	Username string                      `json:"username,omitempty"`
+	Password string                      `json:"password,omitempty"`
+	Headers  map[string]Listable[string] `json:"headers,omitempty"`
+	QUIC     bool                        `json:"quic,omitempty"`
+	TLS      *OutboundTLSOptions         `json:"tls,omitempty"`
+}
@@ -54,32 +54,40 @@ func (r *Rule) UnmarshalJSON(bytes []byte) error {
 }
 
 type DefaultRule struct {
-	Inbound         Listable[string] `json:"inbound,omitempty"`
-	IPVersion       int              `json:"ip_version,omitempty"`
-	Network         Listable[string] `json:"network,omitempty"`
-	AuthUser        Listable[string] `json:"auth_user,omitempty"`
-	Protocol        Listable[string] `json:"protocol,omitempty"`
-	Domain          Listable[string] `json:"domain,omitempty"`
-	DomainSuffix    Listable[string] `json:"domain_suffix,omitempty"`
-	DomainKeyword   Listable[string] `json:"domain_keyword,omitempty"`
-	DomainRegex     Listable[string] `json:"domain_regex,omitempty"`
-	Geosite         Listable[string] `json:"geosite,omitempty"`
-	SourceGeoIP     Listable[string] `json:"source_geoip,omitempty"`
-	GeoIP           Listable[string] `json:"geoip,omitempty"`
-	SourceIPCIDR    Listable[string] `json:"source_ip_cidr,omitempty"`
-	IPCIDR          Listable[string] `json:"ip_cidr,omitempty"`
-	SourcePort      Listable[uint16] `json:"source_port,omitempty"`
-	SourcePortRange Listable[string] `json:"source_port_range,omitempty"`
-	Port            Listable[uint16] `json:"port,omitempty"`
-	PortRange       Listable[string] `json:"port_range,omitempty"`
-	ProcessName     Listable[string] `json:"process_name,omitempty"`
-	ProcessPath     Listable[string] `json:"process_path,omitempty"`
-	PackageName     Listable[string] `json:"package_name,omitempty"`
-	User            Listable[string] `json:"user,omitempty"`
-	UserID          Listable[int32]  `json:"user_id,omitempty"`
-	ClashMode       string           `json:"clash_mode,omitempty"`
-	Invert          bool             `json:"invert,omitempty"`
-	Outbound        string           `json:"outbound,omitempty"`
+	Inbound           Listable[string] `json:"inbound,omitempty"`
+	IPVersion         int              `json:"ip_version,omitempty"`
+	Network           Listable[string] `json:"network,omitempty"`
+	AuthUser          Listable[string] `json:"auth_user,omitempty"`
+	Protocol          Listable[string] `json:"protocol,omitempty"`
+	Domain            Listable[string] `json:"domain,omitempty"`
+	DomainSuffix      Listable[string] `json:"domain_suffix,omitempty"`
+	DomainKeyword     Listable[string] `json:"domain_keyword,omitempty"`
+	DomainRegex       Listable[string] `json:"domain_regex,omitempty"`
+	Geosite           Listable[string] `json:"geosite,omitempty"`
+	SourceGeoIP       Listable[string] `json:"source_geoip,omitempty"`
+	GeoIP             Listable[string] `json:"geoip,omitempty"`
+	SourceIPCIDR      Listable[string] `json:"source_ip_cidr,omitempty"`
+	IPCIDR            Listable[string] `json:"ip_cidr,omitempty"`
+	SourceIPIsPrivate bool             `json:"source_ip_is_private,omitempty"`
+	IPIsPrivate       bool             `json:"ip_is_private,omitempty"`
+	FakeIP            bool             `json:"fakeip,omitempty"`
+	SourcePort        Listable[uint16] `json:"source_port,omitempty"`
+	SourcePortRange   Listable[string] `json:"source_port_range,omitempty"`
+	Port              Listable[uint16] `json:"port,omitempty"`
+	PortRange         Listable[string] `json:"port_range,omitempty"`
+	ProcessName       Listable[string] `json:"process_name,omitempty"`
+	ProcessPath       Listable[string] `json:"process_path,omitempty"`
+	PackageName       Listable[string] `json:"package_name,omitempty"`
+	User              Listable[string] `json:"user,omitempty"`
+	UserID            Listable[int32]  `json:"user_id,omitempty"`
+	NetworkType       Listable[string] `json:"network_type,omitempty"`
+	NetworkInterface  Listable[string] `json:"network_interface,omitempty"`
+	DefaultGateway    Listable[string] `json:"default_gateway,omitempty"`
+	WIFISSID          Listable[string] `json:"wifi_ssid,omitempty"`
+	WIFIBSSID         Listable[string] `json:"wifi_bssid,omitempty"`
+	ClashMode         string           `json:"clash_mode,omitempty"`
+	Invert            bool             `json:"invert,omitempty"`
+	Outbound          string           `json:"outbound,omitempty"`
 }
 
 func (r DefaultRule) IsValid() bool {
@@ -1,5 +1,20 @@
 package option
 
+type SSHInboundOptions struct {
+	ListenOptions
+	Users         []SSHUser        `json:"users,omitempty"`
+	HostKey       Listable[string] `json:"host_key,omitempty"`
+	HostKeyPath   Listable[string] `json:"host_key_path,omitempty"`
+	ServerVersion string           `json:"server_version,omitempty"`
+}
+
+type SSHUser struct {
+	Name               string           `json:"name"`
+	Password           string           `json:"password,omitempty"`
+	AuthorizedKeys     Listable[string] `json:"authorized_keys,omitempty"`
+	AuthorizedKeysPath string           `json:"authorized_keys_path,omitempty"`
+}
+
 type SSHOutboundOptions struct {
 	DialerOptions
 	ServerOptions
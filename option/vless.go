@@ -5,6 +5,7 @@ type VLESSInboundOptions struct {
 	Users     []VLESSUser            `json:"users,omitempty"`
 	TLS       *InboundTLSOptions     `json:"tls,omitempty"`
 	Transport *V2RayTransportOptions `json:"transport,omitempty"`
+	InboundFallbackOptions
 }
 
 type VLESSUser struct {
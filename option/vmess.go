@@ -5,6 +5,7 @@ type VMessInboundOptions struct {
 	Users     []VMessUser            `json:"users,omitempty"`
 	TLS       *InboundTLSOptions     `json:"tls,omitempty"`
 	Transport *V2RayTransportOptions `json:"transport,omitempty"`
+	InboundFallbackOptions
 }
 
 type VMessUser struct {
@@ -1,5 +1,21 @@
 package option
 
+type WireGuardInboundOptions struct {
+	ListenOptions
+	PrivateKey string                 `json:"private_key"`
+	Peers      []WireGuardInboundPeer `json:"peers,omitempty"`
+	Workers    int                    `json:"workers,omitempty"`
+	MTU        uint32                 `json:"mtu,omitempty"`
+	UDPTimeout int64                  `json:"udp_timeout,omitempty"`
+}
+
+type WireGuardInboundPeer struct {
+	Name         string           `json:"name,omitempty"`
+	PublicKey    string           `json:"public_key,omitempty"`
+	PreSharedKey string           `json:"pre_shared_key,omitempty"`
+	AllowedIPs   Listable[string] `json:"allowed_ips,omitempty"`
+}
+
 type WireGuardOutboundOptions struct {
 	DialerOptions
 	SystemInterface bool                   `json:"system_interface,omitempty"`
@@ -0,0 +1,97 @@
+package outbound
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/naive"
+	"github.com/sagernet/sing/common"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+var (
+	_ adapter.Outbound                = (*Naive)(nil)
+	_ adapter.InterfaceUpdateListener = (*Naive)(nil)
+)
+
+type Naive struct {
+	myOutboundAdapter
+	client *naive.Client
+}
+
+func NewNaive(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.NaiveOutboundOptions) (*Naive, error) {
+	if options.TLS == nil || !options.TLS.Enabled {
+		return nil, C.ErrTLSRequired
+	}
+	tlsConfig, err := tls.NewClient(router, options.Server, common.PtrValueOrDefault(options.TLS))
+	if err != nil {
+		return nil, err
+	}
+	var headers http.Header
+	if options.Headers != nil {
+		headers = make(http.Header)
+		for key, values := range options.Headers {
+			headers[key] = values
+		}
+	}
+	client, err := naive.NewClient(naive.ClientOptions{
+		Dialer:    dialer.New(router, options.DialerOptions),
+		Server:    options.ServerOptions.Build(),
+		Username:  options.Username,
+		Password:  options.Password,
+		Headers:   headers,
+		TLSConfig: tlsConfig,
+		QUIC:      options.QUIC,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Naive{
+		myOutboundAdapter: myOutboundAdapter{
+			protocol:     C.TypeNaive,
+			network:      []string{N.NetworkTCP},
+			router:       router,
+			logger:       logger,
+			tag:          tag,
+			dependencies: withDialerDependency(options.DialerOptions),
+		},
+		client: client,
+	}, nil
+}
+
+func (h *Naive) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	ctx, metadata := adapter.AppendContext(ctx)
+	metadata.Outbound = h.tag
+	metadata.Destination = destination
+	h.logger.InfoContext(ctx, "outbound connection to ", destination)
+	return h.client.DialContext(ctx, destination)
+}
+
+func (h *Naive) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	return nil, os.ErrInvalid
+}
+
+func (h *Naive) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	return NewConnection(ctx, h, conn, metadata)
+}
+
+func (h *Naive) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	return os.ErrInvalid
+}
+
+func (h *Naive) InterfaceUpdated() error {
+	return h.client.Close()
+}
+
+func (h *Naive) Close() error {
+	return h.client.Close()
+}
@@ -0,0 +1,332 @@
+package route
+
+import (
+	"encoding/binary"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing/common/atomic"
+	"github.com/sagernet/sing/common/cache"
+	F "github.com/sagernet/sing/common/format"
+
+	mDNS "github.com/miekg/dns"
+)
+
+const (
+	dnsCacheSize          = 4096
+	dnsStaleTTL           = 30
+	dnsDefaultStaleMaxAge = 24 * time.Hour
+	dnsPrefetchMinHits    = 2
+	dnsCacheSaveDelay     = 10 * time.Second
+)
+
+type DNSCache struct {
+	logger      log.ContextLogger
+	independent bool
+	serveStale  bool
+	staleMaxAge time.Duration
+	prefetch    bool
+	cache       *cache.LruCache[dnsCacheKey, *dnsCacheEntry]
+	storage     adapter.DNSCacheStorage
+	saveAccess  sync.Mutex
+	writeAccess sync.Mutex
+	saveTimer   *time.Timer
+	// pending changes of the storage, a nil value deletes the key
+	saveEntries map[string][]byte
+}
+
+// dnsCacheKey has an empty transport name unless the cache is independent.
+type dnsCacheKey struct {
+	question  mDNS.Question
+	transport string
+}
+
+type dnsCacheEntry struct {
+	message    *mDNS.Msg
+	ttl        uint32
+	expireAt   time.Time
+	hits       atomic.Int32
+	refreshing atomic.Bool
+}
+
+func NewDNSCache(logger log.ContextLogger, independent bool, serveStale bool, staleMaxAge time.Duration, prefetch bool) *DNSCache {
+	if staleMaxAge == 0 {
+		staleMaxAge = dnsDefaultStaleMaxAge
+	}
+	dnsCache := &DNSCache{
+		logger:      logger,
+		independent: independent,
+		serveStale:  serveStale,
+		staleMaxAge: staleMaxAge,
+		prefetch:    prefetch,
+	}
+	dnsCache.cache = cache.New(
+		cache.WithSize[dnsCacheKey, *dnsCacheEntry](dnsCacheSize),
+		cache.WithEvict[dnsCacheKey, *dnsCacheEntry](dnsCache.evicted),
+	)
+	return dnsCache
+}
+
+func (c *DNSCache) SetStorage(storage adapter.DNSCacheStorage) {
+	c.storage = storage
+	var (
+		now         = time.Now()
+		expiredKeys []string
+		loaded      int
+	)
+	for key, value := range storage.DNSCacheEntries() {
+		if len(value) < 8 {
+			expiredKeys = append(expiredKeys, key)
+			continue
+		}
+		expireAt := time.Unix(int64(binary.BigEndian.Uint64(value)), 0)
+		if now.After(c.evictAt(expireAt)) {
+			expiredKeys = append(expiredKeys, key)
+			continue
+		}
+		var message mDNS.Msg
+		err := message.Unpack(value[8:])
+		if err != nil || len(message.Question) != 1 {
+			expiredKeys = append(expiredKeys, key)
+			continue
+		}
+		cacheKey, parsed := c.parseStorageKey(key, message.Question[0])
+		if !parsed {
+			// stored with another independent_cache setting
+			expiredKeys = append(expiredKeys, key)
+			continue
+		}
+		entry := &dnsCacheEntry{
+			message:  &message,
+			ttl:      minimumTTL(&message),
+			expireAt: expireAt,
+		}
+		c.cache.StoreWithExpire(cacheKey, entry, c.evictAt(expireAt))
+		loaded++
+	}
+	if len(expiredKeys) > 0 {
+		err := storage.DNSCacheUpdate(nil, expiredKeys)
+		if err != nil {
+			c.logger.Warn("delete expired dns cache: ", err)
+		}
+	}
+	c.logger.Debug("loaded ", loaded, " dns cache entries")
+}
+
+// Load returns a cached response for the message, and whether the caller
+// should refresh the entry in background.
+func (c *DNSCache) Load(message *mDNS.Msg, transportName string) (*mDNS.Msg, bool, bool) {
+	if len(message.Question) != 1 {
+		return nil, false, false
+	}
+	cacheKey := c.cacheKey(message.Question[0], transportName)
+	entry, loaded := c.cache.Load(cacheKey)
+	if !loaded {
+		return nil, false, false
+	}
+	var (
+		now     = time.Now()
+		ttl     uint32
+		refresh bool
+	)
+	if now.After(c.evictAt(entry.expireAt)) {
+		c.cache.Delete(cacheKey)
+		return nil, false, false
+	}
+	if remaining := entry.expireAt.Sub(now); remaining > 0 {
+		ttl = uint32(remaining / time.Second)
+		hits := entry.hits.Add(1)
+		if c.prefetch && hits >= dnsPrefetchMinHits && ttl*10 <= entry.ttl {
+			refresh = entry.refreshing.CompareAndSwap(false, true)
+		}
+	} else if c.serveStale {
+		ttl = dnsStaleTTL
+		refresh = entry.refreshing.CompareAndSwap(false, true)
+	} else {
+		return nil, false, false
+	}
+	response := entry.message.Copy()
+	response.Id = message.Id
+	for _, recordList := range [][]mDNS.RR{response.Answer, response.Ns, response.Extra} {
+		for _, record := range recordList {
+			if record.Header().Rrtype != mDNS.TypeOPT {
+				record.Header().Ttl = ttl
+			}
+		}
+	}
+	return response, refresh, true
+}
+
+func (c *DNSCache) Store(message *mDNS.Msg, transportName string, response *mDNS.Msg) {
+	if len(message.Question) != 1 || response.Truncated {
+		return
+	}
+	switch response.Rcode {
+	case mDNS.RcodeSuccess, mDNS.RcodeNameError:
+	default:
+		return
+	}
+	ttl := minimumTTL(response)
+	if ttl == 0 {
+		return
+	}
+	cacheKey := c.cacheKey(message.Question[0], transportName)
+	expireAt := time.Now().Add(time.Duration(ttl) * time.Second)
+	entry := &dnsCacheEntry{
+		message:  response.Copy(),
+		ttl:      ttl,
+		expireAt: expireAt,
+	}
+	c.cache.StoreWithExpire(cacheKey, entry, c.evictAt(expireAt))
+	if c.storage != nil {
+		packed, err := entry.message.Pack()
+		if err != nil {
+			c.logger.Warn("save dns cache: ", err)
+			return
+		}
+		value := make([]byte, 8+len(packed))
+		binary.BigEndian.PutUint64(value, uint64(entry.expireAt.Unix()))
+		copy(value[8:], packed)
+		c.queueSave(storageKey(cacheKey), value)
+	}
+}
+
+func (c *DNSCache) evicted(cacheKey dnsCacheKey, _ *dnsCacheEntry) {
+	if c.storage != nil {
+		c.queueSave(storageKey(cacheKey), nil)
+	}
+}
+
+// queueSave records a change of the storage, changes are written together after dnsCacheSaveDelay.
+func (c *DNSCache) queueSave(key string, value []byte) {
+	c.saveAccess.Lock()
+	defer c.saveAccess.Unlock()
+	if c.saveEntries == nil {
+		c.saveEntries = make(map[string][]byte)
+	}
+	c.saveEntries[key] = value
+	if c.saveTimer == nil {
+		c.saveTimer = time.AfterFunc(dnsCacheSaveDelay, c.save)
+	}
+}
+
+func (c *DNSCache) save() {
+	// keep batches in order
+	c.writeAccess.Lock()
+	defer c.writeAccess.Unlock()
+	c.saveAccess.Lock()
+	saveEntries := c.saveEntries
+	c.saveEntries = nil
+	if c.saveTimer != nil {
+		c.saveTimer.Stop()
+		c.saveTimer = nil
+	}
+	c.saveAccess.Unlock()
+	if len(saveEntries) == 0 {
+		return
+	}
+	var (
+		entries     = make(map[string][]byte)
+		deletedKeys []string
+	)
+	for key, value := range saveEntries {
+		if value == nil {
+			deletedKeys = append(deletedKeys, key)
+		} else {
+			entries[key] = value
+		}
+	}
+	err := c.storage.DNSCacheUpdate(entries, deletedKeys)
+	if err != nil {
+		c.logger.Warn("save dns cache: ", err)
+	}
+}
+
+func (c *DNSCache) RefreshFailed(message *mDNS.Msg, transportName string) {
+	if len(message.Question) != 1 {
+		return
+	}
+	if entry, loaded := c.cache.Load(c.cacheKey(message.Question[0], transportName)); loaded {
+		entry.refreshing.Store(false)
+	}
+}
+
+func (c *DNSCache) Clear() error {
+	var cacheKeys []dnsCacheKey
+	c.cache.Range(func(cacheKey dnsCacheKey, _ *dnsCacheEntry) {
+		cacheKeys = append(cacheKeys, cacheKey)
+	})
+	for _, cacheKey := range cacheKeys {
+		c.cache.Delete(cacheKey)
+	}
+	if c.storage != nil {
+		c.saveAccess.Lock()
+		c.saveEntries = nil
+		c.saveAccess.Unlock()
+		return c.storage.DNSCacheReset()
+	}
+	return nil
+}
+
+// Close writes pending changes to the storage.
+func (c *DNSCache) Close() error {
+	if c.storage != nil {
+		c.save()
+	}
+	return nil
+}
+
+func (c *DNSCache) evictAt(expireAt time.Time) time.Time {
+	if c.serveStale {
+		return expireAt.Add(c.staleMaxAge)
+	}
+	return expireAt
+}
+
+func (c *DNSCache) cacheKey(question mDNS.Question, transportName string) dnsCacheKey {
+	question.Name = strings.ToLower(question.Name)
+	if !c.independent {
+		transportName = ""
+	}
+	return dnsCacheKey{question, transportName}
+}
+
+func (c *DNSCache) parseStorageKey(key string, question mDNS.Question) (dnsCacheKey, bool) {
+	cacheKey := c.cacheKey(question, "")
+	if !c.independent {
+		return cacheKey, key == storageKey(cacheKey)
+	}
+	prefix := storageKey(cacheKey) + "/"
+	if !strings.HasPrefix(key, prefix) || len(key) == len(prefix) {
+		return dnsCacheKey{}, false
+	}
+	cacheKey.transport = key[len(prefix):]
+	return cacheKey, true
+}
+
+func storageKey(cacheKey dnsCacheKey) string {
+	question := cacheKey.question
+	if cacheKey.transport == "" {
+		return F.ToString(question.Name, "/", question.Qtype, "/", question.Qclass)
+	}
+	return F.ToString(question.Name, "/", question.Qtype, "/", question.Qclass, "/", cacheKey.transport)
+}
+
+func minimumTTL(message *mDNS.Msg) uint32 {
+	var (
+		ttl    uint32
+		hasTTL bool
+	)
+	for _, recordList := range [][]mDNS.RR{message.Answer, message.Ns} {
+		for _, record := range recordList {
+			if !hasTTL || record.Header().Ttl < ttl {
+				ttl = record.Header().Ttl
+				hasTTL = true
+			}
+		}
+	}
+	return ttl
+}
@@ -0,0 +1,108 @@
+package route
+
+import (
+	"context"
+	"net/netip"
+
+	dns "github.com/sagernet/sing-dns"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	mDNS "github.com/miekg/dns"
+)
+
+const clientSubnetStrip = "strip"
+
+// parseClientSubnet parses a client_subnet option value.
+// An invalid prefix is returned for "strip", which removes ECS from queries.
+func parseClientSubnet(value string) (netip.Prefix, error) {
+	if value == clientSubnetStrip {
+		return netip.Prefix{}, nil
+	}
+	prefix, err := netip.ParsePrefix(value)
+	if err == nil {
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, E.New("invalid client subnet: ", value)
+	}
+	if addr.Is4() {
+		return netip.PrefixFrom(addr, 24).Masked(), nil
+	} else {
+		return netip.PrefixFrom(addr.Unmap(), 56).Masked(), nil
+	}
+}
+
+type clientSubnetContextKey struct{}
+
+func contextWithClientSubnet(ctx context.Context, clientSubnet netip.Prefix) context.Context {
+	return context.WithValue(ctx, (*clientSubnetContextKey)(nil), clientSubnet)
+}
+
+func clientSubnetFromContext(ctx context.Context) (netip.Prefix, bool) {
+	clientSubnet, loaded := ctx.Value((*clientSubnetContextKey)(nil)).(netip.Prefix)
+	return clientSubnet, loaded
+}
+
+var _ dns.Transport = (*clientSubnetTransport)(nil)
+
+type clientSubnetTransport struct {
+	dns.Transport
+	clientSubnet *netip.Prefix
+}
+
+func newClientSubnetTransport(transport dns.Transport, clientSubnet *netip.Prefix) *clientSubnetTransport {
+	return &clientSubnetTransport{
+		Transport:    transport,
+		clientSubnet: clientSubnet,
+	}
+}
+
+func (t *clientSubnetTransport) Upstream() any {
+	return t.Transport
+}
+
+func (t *clientSubnetTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	clientSubnet := t.clientSubnet
+	if ruleClientSubnet, loaded := clientSubnetFromContext(ctx); loaded {
+		clientSubnet = &ruleClientSubnet
+	}
+	if clientSubnet != nil {
+		message = setClientSubnet(message, *clientSubnet)
+	}
+	return t.Transport.Exchange(ctx, message)
+}
+
+func setClientSubnet(message *mDNS.Msg, clientSubnet netip.Prefix) *mDNS.Msg {
+	opt := message.IsEdns0()
+	if opt == nil && !clientSubnet.IsValid() {
+		return message
+	}
+	message = message.Copy()
+	opt = message.IsEdns0()
+	if opt == nil {
+		message.SetEdns0(mDNS.DefaultMsgSize, false)
+		opt = message.IsEdns0()
+	}
+	options := opt.Option[:0]
+	for _, option := range opt.Option {
+		if option.Option() != mDNS.EDNS0SUBNET {
+			options = append(options, option)
+		}
+	}
+	if clientSubnet.IsValid() {
+		subnet := &mDNS.EDNS0_SUBNET{
+			Code:          mDNS.EDNS0SUBNET,
+			SourceNetmask: uint8(clientSubnet.Bits()),
+			Address:       clientSubnet.Addr().AsSlice(),
+		}
+		if clientSubnet.Addr().Is4() {
+			subnet.Family = 1
+		} else {
+			subnet.Family = 2
+		}
+		options = append(options, subnet)
+	}
+	opt.Option = options
+	return message
+}
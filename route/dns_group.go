@@ -0,0 +1,196 @@
+package route
+
+import (
+	"context"
+	"net/netip"
+	"os"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common/atomic"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+
+	mDNS "github.com/miekg/dns"
+)
+
+var _ adapter.DNSGroupTransport = (*DNSGroupTransport)(nil)
+
+type DNSGroupTransport struct {
+	name      string
+	client    *dns.Client
+	logger    log.ContextLogger
+	upstreams []*dnsUpstream
+}
+
+type dnsUpstream struct {
+	tag       string
+	transport dns.Transport
+	items     []RuleItem
+
+	queries        atomic.Int64
+	wins           atomic.Int64
+	failures       atomic.Int64
+	lastLatency    atomic.Int64
+	averageLatency atomic.Int64
+}
+
+func NewDNSGroupTransport(router adapter.Router, client *dns.Client, logger log.ContextLogger, name string, options []option.DNSUpstream, transportMap map[string]dns.Transport) (*DNSGroupTransport, error) {
+	if len(options) == 0 {
+		return nil, E.New("missing upstreams")
+	}
+	group := &DNSGroupTransport{
+		name:   name,
+		client: client,
+		logger: logger,
+	}
+	for i, upstreamOptions := range options {
+		transport, loaded := transportMap[upstreamOptions.Server]
+		if !loaded {
+			return nil, E.New("upstream[", i, "]: dns server not found: ", upstreamOptions.Server)
+		}
+		upstream := &dnsUpstream{
+			tag:       upstreamOptions.Server,
+			transport: transport,
+		}
+		if len(upstreamOptions.GeoIP) > 0 {
+			upstream.items = append(upstream.items, NewGeoIPItem(router, logger, false, upstreamOptions.GeoIP))
+		}
+		if len(upstreamOptions.IPCIDR) > 0 {
+			item, err := NewIPCIDRItem(false, upstreamOptions.IPCIDR)
+			if err != nil {
+				return nil, E.Cause(err, "upstream[", i, "]: ip_cidr")
+			}
+			upstream.items = append(upstream.items, item)
+		}
+		group.upstreams = append(group.upstreams, upstream)
+	}
+	return group, nil
+}
+
+func (t *DNSGroupTransport) Name() string {
+	return t.name
+}
+
+func (t *DNSGroupTransport) Start() error {
+	return nil
+}
+
+func (t *DNSGroupTransport) Close() error {
+	return nil
+}
+
+func (t *DNSGroupTransport) Raw() bool {
+	return true
+}
+
+type dnsUpstreamResult struct {
+	upstream *dnsUpstream
+	response *mDNS.Msg
+	err      error
+}
+
+func (t *DNSGroupTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan dnsUpstreamResult, len(t.upstreams))
+	for _, upstream := range t.upstreams {
+		go func(upstream *dnsUpstream) {
+			response, err := t.exchange(ctx, upstream, message.Copy())
+			results <- dnsUpstreamResult{upstream, response, err}
+		}(upstream)
+	}
+	var errors []error
+	for range t.upstreams {
+		result := <-results
+		if result.err == nil {
+			result.upstream.wins.Add(1)
+			t.logger.DebugContext(ctx, "accepted response from ", result.upstream.tag)
+			return result.response, nil
+		}
+		errors = append(errors, E.Cause(result.err, result.upstream.tag))
+	}
+	return nil, E.Errors(errors...)
+}
+
+func (t *DNSGroupTransport) exchange(ctx context.Context, upstream *dnsUpstream, message *mDNS.Msg) (*mDNS.Msg, error) {
+	upstream.queries.Add(1)
+	start := time.Now()
+	response, err := t.client.Exchange(dns.ContextWithDisableCache(ctx, true), upstream.transport, message, dns.DomainStrategyAsIS)
+	if err != nil {
+		if ctx.Err() == nil {
+			upstream.failures.Add(1)
+		}
+		return nil, err
+	}
+	upstream.updateLatency(time.Since(start))
+	switch response.Rcode {
+	case mDNS.RcodeSuccess, mDNS.RcodeNameError:
+	default:
+		upstream.failures.Add(1)
+		return nil, dns.RCodeError(response.Rcode)
+	}
+	if len(upstream.items) > 0 && !upstream.verify(response) {
+		t.logger.DebugContext(ctx, "response from ", upstream.tag, " rejected by verification")
+		return nil, E.New("response rejected")
+	}
+	return response, nil
+}
+
+func (t *DNSGroupTransport) Lookup(ctx context.Context, domain string, strategy dns.DomainStrategy) ([]netip.Addr, error) {
+	return nil, os.ErrInvalid
+}
+
+func (t *DNSGroupTransport) UpstreamStatistics() []adapter.DNSUpstreamStatistics {
+	statistics := make([]adapter.DNSUpstreamStatistics, 0, len(t.upstreams))
+	for _, upstream := range t.upstreams {
+		statistics = append(statistics, adapter.DNSUpstreamStatistics{
+			Server:         upstream.tag,
+			Queries:        upstream.queries.Load(),
+			Wins:           upstream.wins.Load(),
+			Failures:       upstream.failures.Load(),
+			LastLatency:    time.Duration(upstream.lastLatency.Load()),
+			AverageLatency: time.Duration(upstream.averageLatency.Load()),
+		})
+	}
+	return statistics
+}
+
+func (u *dnsUpstream) updateLatency(latency time.Duration) {
+	u.lastLatency.Store(int64(latency))
+	for {
+		average := u.averageLatency.Load()
+		var newAverage int64
+		if average == 0 {
+			newAverage = int64(latency)
+		} else {
+			newAverage = (average*7 + int64(latency)) / 8
+		}
+		if u.averageLatency.CompareAndSwap(average, newAverage) {
+			return
+		}
+	}
+}
+
+// verify requires every address in the answer to match one of the upstream's geoip or ip_cidr items.
+func (u *dnsUpstream) verify(response *mDNS.Msg) bool {
+	for _, address := range MessageToAddresses(response) {
+		metadata := adapter.InboundContext{
+			Destination: M.SocksaddrFrom(address, 0),
+		}
+		var matched bool
+		for _, item := range u.items {
+			if item.Match(&metadata) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
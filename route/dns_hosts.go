@@ -0,0 +1,151 @@
+package route
+
+import (
+	"bufio"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	mDNS "github.com/miekg/dns"
+)
+
+const hostsTTL = 600
+
+type DNSHosts struct {
+	domains  map[string][]netip.Addr
+	suffixes map[string][]netip.Addr
+}
+
+func NewDNSHosts(options option.DNSHostsOptions) (*DNSHosts, error) {
+	hosts := &DNSHosts{
+		domains:  make(map[string][]netip.Addr),
+		suffixes: make(map[string][]netip.Addr),
+	}
+	for _, path := range options.Path {
+		err := hosts.loadFile(path)
+		if err != nil {
+			return nil, E.Cause(err, "read hosts file ", path)
+		}
+	}
+	for domain, rawAddresses := range options.Predefined {
+		for _, rawAddress := range rawAddresses {
+			address, err := netip.ParseAddr(rawAddress)
+			if err != nil {
+				return nil, E.Cause(err, "parse hosts address for ", domain)
+			}
+			hosts.add(domain, address)
+		}
+	}
+	return hosts, nil
+}
+
+func (h *DNSHosts) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if commentIndex := strings.IndexByte(line, '#'); commentIndex != -1 {
+			line = line[:commentIndex]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		address, err := netip.ParseAddr(fields[0])
+		if err != nil {
+			continue
+		}
+		for _, domain := range fields[1:] {
+			h.add(domain, address)
+		}
+	}
+	return scanner.Err()
+}
+
+func (h *DNSHosts) add(domain string, address netip.Addr) {
+	domain = strings.ToLower(fqdnToDomain(domain))
+	address = address.Unmap()
+	if strings.HasPrefix(domain, "*.") {
+		suffix := domain[2:]
+		h.suffixes[suffix] = append(h.suffixes[suffix], address)
+	} else {
+		h.domains[domain] = append(h.domains[domain], address)
+	}
+}
+
+func (h *DNSHosts) Lookup(domain string) ([]netip.Addr, bool) {
+	domain = strings.ToLower(fqdnToDomain(domain))
+	if addresses, loaded := h.domains[domain]; loaded {
+		return addresses, true
+	}
+	for {
+		dotIndex := strings.IndexByte(domain, '.')
+		if dotIndex == -1 {
+			return nil, false
+		}
+		domain = domain[dotIndex+1:]
+		if addresses, loaded := h.suffixes[domain]; loaded {
+			return addresses, true
+		}
+	}
+}
+
+func (h *DNSHosts) Exchange(message *mDNS.Msg) (*mDNS.Msg, bool) {
+	if len(message.Question) != 1 {
+		return nil, false
+	}
+	question := message.Question[0]
+	if question.Qclass != mDNS.ClassINET || question.Qtype != mDNS.TypeA && question.Qtype != mDNS.TypeAAAA {
+		return nil, false
+	}
+	addresses, loaded := h.Lookup(question.Name)
+	if !loaded {
+		return nil, false
+	}
+	response := new(mDNS.Msg)
+	response.SetReply(message)
+	response.RecursionAvailable = true
+	for _, address := range addresses {
+		header := mDNS.RR_Header{
+			Name:   question.Name,
+			Rrtype: question.Qtype,
+			Class:  mDNS.ClassINET,
+			Ttl:    hostsTTL,
+		}
+		if question.Qtype == mDNS.TypeA && address.Is4() {
+			response.Answer = append(response.Answer, &mDNS.A{Hdr: header, A: address.AsSlice()})
+		} else if question.Qtype == mDNS.TypeAAAA && address.Is6() {
+			response.Answer = append(response.Answer, &mDNS.AAAA{Hdr: header, AAAA: address.AsSlice()})
+		}
+	}
+	return response, true
+}
+
+func filterAddressesByStrategy(addresses []netip.Addr, strategy dns.DomainStrategy) []netip.Addr {
+	var response4, response6 []netip.Addr
+	for _, address := range addresses {
+		if address.Is4() {
+			response4 = append(response4, address)
+		} else {
+			response6 = append(response6, address)
+		}
+	}
+	switch strategy {
+	case dns.DomainStrategyUseIPv4:
+		return response4
+	case dns.DomainStrategyUseIPv6:
+		return response6
+	case dns.DomainStrategyPreferIPv6:
+		return append(response6, response4...)
+	default:
+		return append(response4, response6...)
+	}
+}
@@ -0,0 +1,91 @@
+package route
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	mDNS "github.com/miekg/dns"
+)
+
+type predefinedResponse struct {
+	rcode  int
+	answer []mDNS.RR
+	ns     []mDNS.RR
+	extra  []mDNS.RR
+}
+
+func newPredefinedResponse(options option.DNSPredefinedOptions) (*predefinedResponse, error) {
+	response := &predefinedResponse{
+		rcode: mDNS.RcodeSuccess,
+	}
+	if options.RCode != "" {
+		rcode, loaded := mDNS.StringToRcode[strings.ToUpper(options.RCode)]
+		if !loaded {
+			return nil, E.New("unknown rcode: ", options.RCode)
+		}
+		response.rcode = rcode
+	}
+	var err error
+	response.answer, err = parseRecords(options.Answer)
+	if err != nil {
+		return nil, E.Cause(err, "parse answer")
+	}
+	response.ns, err = parseRecords(options.Ns)
+	if err != nil {
+		return nil, E.Cause(err, "parse ns")
+	}
+	response.extra, err = parseRecords(options.Extra)
+	if err != nil {
+		return nil, E.Cause(err, "parse extra")
+	}
+	return response, nil
+}
+
+func parseRecords(records []string) ([]mDNS.RR, error) {
+	var parsed []mDNS.RR
+	for _, record := range records {
+		rr, err := mDNS.NewRR(record)
+		if err != nil {
+			return nil, err
+		}
+		if rr == nil {
+			return nil, E.New("empty record: ", record)
+		}
+		parsed = append(parsed, rr)
+	}
+	return parsed, nil
+}
+
+func (p *predefinedResponse) Response(request *mDNS.Msg) *mDNS.Msg {
+	response := new(mDNS.Msg)
+	response.SetRcode(request, p.rcode)
+	response.RecursionAvailable = true
+	var question mDNS.Question
+	if len(request.Question) > 0 {
+		question = request.Question[0]
+	}
+	for _, record := range p.answer {
+		rrType := record.Header().Rrtype
+		if question.Qtype == rrType || rrType == mDNS.TypeCNAME || question.Qtype == mDNS.TypeANY {
+			response.Answer = append(response.Answer, copyRecord(record, question.Name))
+		}
+	}
+	for _, record := range p.ns {
+		response.Ns = append(response.Ns, copyRecord(record, question.Name))
+	}
+	for _, record := range p.extra {
+		response.Extra = append(response.Extra, copyRecord(record, question.Name))
+	}
+	return response
+}
+
+// copyRecord replaces the owner name `@` (parsed as the root) with the question name.
+func copyRecord(record mDNS.RR, name string) mDNS.RR {
+	record = mDNS.Copy(record)
+	if record.Header().Name == "." && name != "" {
+		record.Header().Name = name
+	}
+	return record
+}
@@ -0,0 +1,115 @@
+package route
+
+import (
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-dns"
+
+	mDNS "github.com/miekg/dns"
+)
+
+// exchangePTR answers reverse lookups for fake IPs and reverse mapped addresses
+// locally, so that they are neither answered with NXDOMAIN nor leaked to upstream.
+func (r *Router) exchangePTR(message *mDNS.Msg) (*mDNS.Msg, string, bool) {
+	if r.fakeIPStore == nil && r.dnsReverseMapping == nil || len(message.Question) != 1 {
+		return nil, "", false
+	}
+	question := message.Question[0]
+	if question.Qclass != mDNS.ClassINET || question.Qtype != mDNS.TypePTR {
+		return nil, "", false
+	}
+	address, loaded := ptrToAddr(question.Name)
+	if !loaded {
+		return nil, "", false
+	}
+	var (
+		domain    string
+		ttl       uint32
+		transport string
+	)
+	if r.fakeIPStore != nil && r.fakeIPStore.Contains(address) {
+		transport = "fakeip"
+		domain, loaded = r.fakeIPStore.Lookup(address)
+		ttl = dns.DefaultTTL
+	} else if r.dnsReverseMapping != nil {
+		transport = "reverse_mapping"
+		domain, ttl, loaded = r.dnsReverseMapping.QueryTTL(address)
+		if !loaded {
+			return nil, "", false
+		}
+	} else {
+		return nil, "", false
+	}
+	response := new(mDNS.Msg)
+	response.SetReply(message)
+	response.RecursionAvailable = true
+	if !loaded {
+		// unallocated fake IPs do not exist upstream either
+		response.Rcode = mDNS.RcodeNameError
+		return response, transport, true
+	}
+	response.Answer = append(response.Answer, &mDNS.PTR{
+		Hdr: mDNS.RR_Header{
+			Name:   question.Name,
+			Rrtype: mDNS.TypePTR,
+			Class:  mDNS.ClassINET,
+			Ttl:    ttl,
+		},
+		Ptr: mDNS.Fqdn(domain),
+	})
+	return response, transport, true
+}
+
+func (m *DNSReverseMapping) QueryTTL(address netip.Addr) (string, uint32, bool) {
+	domain, expiresAt, loaded := m.cache.LoadWithExpire(address)
+	if !loaded {
+		return "", 0, false
+	}
+	ttl := time.Until(expiresAt) / time.Second
+	if ttl < 1 {
+		ttl = 1
+	}
+	return domain, uint32(ttl), true
+}
+
+func ptrToAddr(name string) (netip.Addr, bool) {
+	name = strings.ToLower(mDNS.Fqdn(name))
+	if strings.HasSuffix(name, ".in-addr.arpa.") {
+		labels := strings.Split(strings.TrimSuffix(name, ".in-addr.arpa."), ".")
+		if len(labels) != 4 {
+			return netip.Addr{}, false
+		}
+		var address [4]byte
+		for i, label := range labels {
+			octet, err := strconv.ParseUint(label, 10, 8)
+			if err != nil {
+				return netip.Addr{}, false
+			}
+			address[3-i] = byte(octet)
+		}
+		return netip.AddrFrom4(address), true
+	} else if strings.HasSuffix(name, ".ip6.arpa.") {
+		labels := strings.Split(strings.TrimSuffix(name, ".ip6.arpa."), ".")
+		if len(labels) != 32 {
+			return netip.Addr{}, false
+		}
+		var address [16]byte
+		for i, label := range labels {
+			nibble, err := strconv.ParseUint(label, 16, 4)
+			if err != nil || len(label) != 1 {
+				return netip.Addr{}, false
+			}
+			index := 31 - i
+			if index%2 == 0 {
+				address[index/2] |= byte(nibble) << 4
+			} else {
+				address[index/2] |= byte(nibble)
+			}
+		}
+		return netip.AddrFrom16(address), true
+	}
+	return netip.Addr{}, false
+}
@@ -0,0 +1,147 @@
+package route
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/observable"
+)
+
+const dnsQueryLogSize = 1024
+
+var _ adapter.DNSQueryLog = (*DNSQueryLog)(nil)
+
+type DNSQueryLog struct {
+	access     sync.Mutex
+	records    []adapter.DNSQueryRecord
+	next       int
+	total      dnsQueryCounter
+	transports map[string]*dnsQueryCounter
+	rules      map[string]*dnsQueryCounter
+	subscriber *observable.Subscriber[adapter.DNSQueryRecord]
+	observer   *observable.Observer[adapter.DNSQueryRecord]
+}
+
+type dnsQueryCounter struct {
+	queries  int64
+	cached   int64
+	failures int64
+	latency  time.Duration
+}
+
+func (c *dnsQueryCounter) add(record *adapter.DNSQueryRecord) {
+	c.queries++
+	if record.Cached {
+		c.cached++
+	}
+	if record.Error != "" {
+		c.failures++
+	}
+	c.latency += record.Latency
+}
+
+func (c *dnsQueryCounter) export() adapter.DNSQueryCounter {
+	counter := adapter.DNSQueryCounter{
+		Queries:  c.queries,
+		Cached:   c.cached,
+		Failures: c.failures,
+	}
+	if c.queries > 0 {
+		counter.AverageLatency = c.latency / time.Duration(c.queries)
+	}
+	return counter
+}
+
+func NewDNSQueryLog() *DNSQueryLog {
+	subscriber := observable.NewSubscriber[adapter.DNSQueryRecord](128)
+	return &DNSQueryLog{
+		records:    make([]adapter.DNSQueryRecord, 0, dnsQueryLogSize),
+		transports: make(map[string]*dnsQueryCounter),
+		rules:      make(map[string]*dnsQueryCounter),
+		subscriber: subscriber,
+		observer:   observable.NewObserver[adapter.DNSQueryRecord](subscriber, 64),
+	}
+}
+
+func (l *DNSQueryLog) Add(record adapter.DNSQueryRecord) {
+	l.access.Lock()
+	if len(l.records) < dnsQueryLogSize {
+		l.records = append(l.records, record)
+	} else {
+		l.records[l.next] = record
+	}
+	l.next = (l.next + 1) % dnsQueryLogSize
+	l.total.add(&record)
+	if record.Transport != "" {
+		counter := l.transports[record.Transport]
+		if counter == nil {
+			counter = new(dnsQueryCounter)
+			l.transports[record.Transport] = counter
+		}
+		counter.add(&record)
+	}
+	if record.Rule != "" {
+		counter := l.rules[record.Rule]
+		if counter == nil {
+			counter = new(dnsQueryCounter)
+			l.rules[record.Rule] = counter
+		}
+		counter.add(&record)
+	}
+	l.access.Unlock()
+	l.observer.Emit(record)
+}
+
+// Records returns buffered records from oldest to newest.
+func (l *DNSQueryLog) Records() []adapter.DNSQueryRecord {
+	l.access.Lock()
+	defer l.access.Unlock()
+	records := make([]adapter.DNSQueryRecord, 0, len(l.records))
+	if len(l.records) == dnsQueryLogSize {
+		records = append(records, l.records[l.next:]...)
+		records = append(records, l.records[:l.next]...)
+	} else {
+		records = append(records, l.records...)
+	}
+	return records
+}
+
+func (l *DNSQueryLog) Statistics() adapter.DNSQueryStatistics {
+	l.access.Lock()
+	defer l.access.Unlock()
+	statistics := adapter.DNSQueryStatistics{
+		Total:      l.total.export(),
+		Transports: make(map[string]adapter.DNSQueryCounter, len(l.transports)),
+		Rules:      make(map[string]adapter.DNSQueryCounter, len(l.rules)),
+	}
+	for name, counter := range l.transports {
+		statistics.Transports[name] = counter.export()
+	}
+	for name, counter := range l.rules {
+		statistics.Rules[name] = counter.export()
+	}
+	return statistics
+}
+
+func (l *DNSQueryLog) Reset() {
+	l.access.Lock()
+	defer l.access.Unlock()
+	l.records = l.records[:0]
+	l.next = 0
+	l.total = dnsQueryCounter{}
+	l.transports = make(map[string]*dnsQueryCounter)
+	l.rules = make(map[string]*dnsQueryCounter)
+}
+
+func (l *DNSQueryLog) Subscribe() (subscription observable.Subscription[adapter.DNSQueryRecord], done <-chan struct{}, err error) {
+	return l.observer.Subscribe()
+}
+
+func (l *DNSQueryLog) UnSubscribe(subscription observable.Subscription[adapter.DNSQueryRecord]) {
+	l.observer.UnSubscribe(subscription)
+}
+
+func (l *DNSQueryLog) Close() error {
+	return l.observer.Close()
+}
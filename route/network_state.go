@@ -0,0 +1,29 @@
+package route
+
+import (
+	"net/netip"
+
+	"github.com/sagernet/sing-box/adapter"
+)
+
+func (r *Router) NetworkState() adapter.NetworkState {
+	return r.networkState.Load()
+}
+
+func (r *Router) updateNetworkState() {
+	if r.interfaceMonitor == nil {
+		return
+	}
+	interfaceName := r.interfaceMonitor.DefaultInterfaceName(netip.IPv4Unspecified())
+	state, err := readNetworkState(interfaceName)
+	if err != nil {
+		r.logger.Debug("read network state: ", err)
+	}
+	if r.platformInterface != nil {
+		state.WIFIState = r.platformInterface.ReadWIFIState()
+	}
+	r.networkState.Store(state)
+	if state.InterfaceType != "" {
+		r.logger.Debug("updated network state: ", state.InterfaceType, " ", state.InterfaceName)
+	}
+}
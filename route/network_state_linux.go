@@ -0,0 +1,61 @@
+package route
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sagernet/netlink"
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/sing/common/rw"
+)
+
+func readNetworkState(interfaceName string) (adapter.NetworkState, error) {
+	state := adapter.NetworkState{InterfaceName: interfaceName}
+	if interfaceName == "" {
+		return state, nil
+	}
+	link, err := netlink.LinkByName(interfaceName)
+	if err != nil {
+		return state, E.Cause(err, "find interface ", interfaceName)
+	}
+	state.InterfaceType = readInterfaceType(link)
+	routes, err := netlink.RouteListFiltered(netlink.FAMILY_ALL, &netlink.Route{LinkIndex: link.Attrs().Index}, netlink.RT_FILTER_OIF)
+	if err != nil {
+		return state, E.Cause(err, "list routes")
+	}
+	for _, route := range routes {
+		if route.Dst != nil || route.Gw == nil {
+			continue
+		}
+		gateway := M.AddrFromIP(route.Gw)
+		if !common.Contains(state.Gateways, gateway) {
+			state.Gateways = append(state.Gateways, gateway)
+		}
+	}
+	return state, nil
+}
+
+func readInterfaceType(link netlink.Link) string {
+	sysPath := filepath.Join("/sys/class/net", link.Attrs().Name)
+	if rw.FileExists(filepath.Join(sysPath, "wireless")) || rw.FileExists(filepath.Join(sysPath, "phy80211")) {
+		return C.InterfaceTypeWIFI
+	}
+	uevent, _ := os.ReadFile(filepath.Join(sysPath, "uevent"))
+	if strings.Contains(string(uevent), "DEVTYPE=wwan") {
+		return C.InterfaceTypeCellular
+	}
+	for _, prefix := range []string{"rmnet", "ccmni", "wwan", "pdp"} {
+		if strings.HasPrefix(link.Attrs().Name, prefix) {
+			return C.InterfaceTypeCellular
+		}
+	}
+	if link.Type() == "device" && link.Attrs().EncapType == "ether" {
+		return C.InterfaceTypeEthernet
+	}
+	return C.InterfaceTypeOther
+}
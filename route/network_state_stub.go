@@ -0,0 +1,9 @@
+//go:build !linux
+
+package route
+
+import "github.com/sagernet/sing-box/adapter"
+
+func readNetworkState(interfaceName string) (adapter.NetworkState, error) {
+	return adapter.NetworkState{InterfaceName: interfaceName}, nil
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/user"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
@@ -15,6 +16,7 @@ import (
 	"github.com/sagernet/sing-box/common/dialer/conntrack"
 	"github.com/sagernet/sing-box/common/geoip"
 	"github.com/sagernet/sing-box/common/geosite"
+	"github.com/sagernet/sing-box/common/limiter"
 	"github.com/sagernet/sing-box/common/mux"
 	"github.com/sagernet/sing-box/common/process"
 	"github.com/sagernet/sing-box/common/sniff"
@@ -24,11 +26,13 @@ import (
 	"github.com/sagernet/sing-box/ntp"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-box/outbound"
+	"github.com/sagernet/sing-box/transport/dnstransport"
 	"github.com/sagernet/sing-box/transport/fakeip"
 	dns "github.com/sagernet/sing-dns"
 	tun "github.com/sagernet/sing-tun"
 	vmess "github.com/sagernet/sing-vmess"
 	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/atomic"
 	"github.com/sagernet/sing/common/buf"
 	"github.com/sagernet/sing/common/bufio"
 	"github.com/sagernet/sing/common/bufio/deadline"
@@ -70,6 +74,12 @@ type Router struct {
 	transportMap                       map[string]dns.Transport
 	transportDomainStrategy            map[dns.Transport]dns.DomainStrategy
 	dnsReverseMapping                  *DNSReverseMapping
+	dnsHosts                           *DNSHosts
+	dnsCache                           *DNSCache
+	dnsQueryLog                        *DNSQueryLog
+	dnsCacheAvailable                  bool
+	dnsClientSubnetRules               bool
+	dnsIndependentCache                bool
 	fakeIPStore                        adapter.FakeIPStore
 	fakeIPDualStack                    bool
 	interfaceFinder                    myInterfaceFinder
@@ -79,11 +89,16 @@ type Router struct {
 	networkMonitor                     tun.NetworkUpdateMonitor
 	interfaceMonitor                   tun.DefaultInterfaceMonitor
 	packageManager                     tun.PackageManager
+	networkState                       atomic.TypedValue[adapter.NetworkState]
 	processSearcher                    process.Searcher
 	timeService                        adapter.TimeService
 	clashServer                        adapter.ClashServer
 	v2rayServer                        adapter.V2RayServer
 	platformInterface                  platform.Interface
+	limiterAccess                      sync.Mutex
+	limiters                           map[*option.InboundLimitOptions]*limiter.Limiter
+	muxServiceAccess                   sync.Mutex
+	muxServices                        map[*option.InboundMultiplexOptions]*mux.Service
 }
 
 func NewRouter(
@@ -102,7 +117,7 @@ func NewRouter(
 		outboundByTag:         make(map[string]adapter.Outbound),
 		rules:                 make([]adapter.Rule, 0, len(options.Rules)),
 		dnsRules:              make([]adapter.DNSRule, 0, len(dnsOptions.Rules)),
-		needGeoIPDatabase:     hasRule(options.Rules, isGeoIPRule) || hasDNSRule(dnsOptions.Rules, isGeoIPDNSRule),
+		needGeoIPDatabase:     hasRule(options.Rules, isGeoIPRule) || hasDNSRule(dnsOptions.Rules, isGeoIPDNSRule) || common.Any(dnsOptions.Servers, isGeoIPDNSServer),
 		needGeositeDatabase:   hasRule(options.Rules, isGeositeRule) || hasDNSRule(dnsOptions.Rules, isGeositeDNSRule),
 		geoIPOptions:          common.PtrValueOrDefault(options.GeoIP),
 		geositeOptions:        common.PtrValueOrDefault(options.Geosite),
@@ -154,6 +169,11 @@ func NewRouter(
 		transportTags[i] = tag
 		transportTagMap[tag] = true
 	}
+	needClientSubnet := common.Any(dnsOptions.Rules, func(rule option.DNSRule) bool {
+		return rule.DefaultOptions.ClientSubnet != "" || rule.LogicalOptions.ClientSubnet != ""
+	})
+	router.dnsClientSubnetRules = needClientSubnet
+	router.dnsIndependentCache = dnsOptions.DNSClientOptions.IndependentCache
 	ctx = adapter.ContextWithRouter(ctx, router)
 	for {
 		lastLen := len(dummyTransportMap)
@@ -170,6 +190,19 @@ func NewRouter(
 			}
 			switch server.Address {
 			case "local":
+			case "group":
+				var dependencyMissing bool
+				for _, upstream := range server.Upstreams {
+					if !transportTagMap[upstream.Server] {
+						return nil, E.New("parse dns server[", tag, "]: upstream not found: ", upstream.Server)
+					}
+					if _, exists := dummyTransportMap[upstream.Server]; !exists {
+						dependencyMissing = true
+					}
+				}
+				if dependencyMissing {
+					continue
+				}
 			default:
 				serverURL, _ := url.Parse(server.Address)
 				var serverAddress string
@@ -193,10 +226,32 @@ func NewRouter(
 					return nil, E.New("parse dns server[", tag, "]: missing address_resolver")
 				}
 			}
-			transport, err := dns.CreateTransport(tag, ctx, logFactory.NewLogger(F.ToString("dns/transport[", tag, "]")), detour, server.Address)
+			var (
+				transport dns.Transport
+				err       error
+			)
+			transportLogger := logFactory.NewLogger(F.ToString("dns/transport[", tag, "]"))
+			if server.Address == "group" {
+				transport, err = NewDNSGroupTransport(router, router.dnsClient, transportLogger, tag, server.Upstreams, dummyTransportMap)
+			} else if dnstransport.IsTuned(server) {
+				transport, err = dnstransport.NewTransport(ctx, transportLogger, tag, detour, server)
+			} else {
+				transport, err = dns.CreateTransport(tag, ctx, transportLogger, detour, server.Address)
+			}
 			if err != nil {
 				return nil, E.Cause(err, "parse dns server[", tag, "]")
 			}
+			if server.Address != "group" && transport.Raw() && (server.ClientSubnet != "" || needClientSubnet) {
+				var clientSubnet *netip.Prefix
+				if server.ClientSubnet != "" {
+					prefix, err := parseClientSubnet(server.ClientSubnet)
+					if err != nil {
+						return nil, E.Cause(err, "parse dns server[", tag, "]")
+					}
+					clientSubnet = &prefix
+				}
+				transport = newClientSubnetTransport(transport, clientSubnet)
+			}
 			transports[i] = transport
 			dummyTransportMap[tag] = transport
 			if server.Tag != "" {
@@ -246,6 +301,22 @@ func NewRouter(
 		router.dnsReverseMapping = NewDNSReverseMapping()
 	}
 
+	router.dnsCacheAvailable = !dnsOptions.DNSClientOptions.DisableCache
+	if dnsOptions.ServeStale || dnsOptions.Prefetch {
+		if !router.dnsCacheAvailable {
+			return nil, E.New("serve_stale and prefetch are not compatible with disable_cache")
+		}
+		router.dnsCache = NewDNSCache(router.dnsLogger, router.dnsIndependentCache, dnsOptions.ServeStale, time.Duration(dnsOptions.StaleMaxAge), dnsOptions.Prefetch)
+	}
+
+	if dnsOptions.Hosts != nil {
+		dnsHosts, err := NewDNSHosts(*dnsOptions.Hosts)
+		if err != nil {
+			return nil, E.Cause(err, "parse dns hosts")
+		}
+		router.dnsHosts = dnsHosts
+	}
+
 	if fakeIPOptions := dnsOptions.FakeIP; fakeIPOptions != nil && dnsOptions.FakeIP.Enabled {
 		var inet4Range netip.Prefix
 		var inet6Range netip.Prefix
@@ -255,12 +326,16 @@ func NewRouter(
 		if fakeIPOptions.Inet6Range != nil {
 			inet6Range = fakeIPOptions.Inet6Range.Build()
 		}
-		router.fakeIPStore = fakeip.NewStore(router, router.logger, inet4Range, inet6Range)
+		exclude, err := fakeip.NewExclude(*fakeIPOptions)
+		if err != nil {
+			return nil, E.Cause(err, "parse fakeip")
+		}
+		router.fakeIPStore = fakeip.NewStore(router, router.logger, inet4Range, inet6Range, exclude)
 		router.fakeIPDualStack = inet4Range.IsValid() && inet6Range.IsValid()
 	}
 
 	usePlatformDefaultInterfaceMonitor := platformInterface != nil && platformInterface.UsePlatformDefaultInterfaceMonitor()
-	needInterfaceMonitor := options.AutoDetectInterface || common.Any(inbounds, func(inbound option.Inbound) bool {
+	needInterfaceMonitor := options.AutoDetectInterface || hasRule(options.Rules, isNetworkRule) || hasDNSRule(dnsOptions.Rules, isNetworkDNSRule) || common.Any(inbounds, func(inbound option.Inbound) bool {
 		return inbound.HTTPOptions.SetSystemProxy || inbound.MixedOptions.SetSystemProxy || inbound.TunOptions.AutoRoute
 	})
 
@@ -318,6 +393,12 @@ func NewRouter(
 			}
 		}
 	}
+	for i, inbound := range inbounds {
+		_, err := router.loadMuxService(inbound.InboundOptions().Multiplex)
+		if err != nil {
+			return nil, E.Cause(err, "parse inbound[", i, "] multiplex")
+		}
+	}
 	if ntpOptions.Enabled {
 		router.timeService = ntp.NewService(ctx, router, logFactory.NewLogger("ntp"), ntpOptions)
 	}
@@ -445,6 +526,7 @@ func (r *Router) Start() error {
 			return err
 		}
 	}
+	r.updateNetworkState()
 	if r.packageManager != nil {
 		err := r.packageManager.Start()
 		if err != nil {
@@ -483,6 +565,14 @@ func (r *Router) Start() error {
 			return E.Cause(err, "initialize DNS rule[", i, "]")
 		}
 	}
+	if r.clashServer != nil && r.clashServer.StoreDNS() && r.dnsCacheAvailable {
+		if cacheFile := r.clashServer.CacheFile(); cacheFile != nil {
+			if r.dnsCache == nil {
+				r.dnsCache = NewDNSCache(r.dnsLogger, r.dnsIndependentCache, false, 0, false)
+			}
+			r.dnsCache.SetStorage(cacheFile)
+		}
+	}
 	if r.fakeIPStore != nil {
 		err := r.fakeIPStore.Start()
 		if err != nil {
@@ -524,6 +614,25 @@ func (r *Router) Close() error {
 			return E.Cause(err, "close dns transport[", i, "]")
 		})
 	}
+	if r.dnsCache != nil {
+		r.logger.Trace("closing dns cache")
+		err = E.Append(err, r.dnsCache.Close(), func(err error) error {
+			return E.Cause(err, "close dns cache")
+		})
+	}
+	if r.dnsQueryLog != nil {
+		r.logger.Trace("closing dns query log")
+		err = E.Append(err, r.dnsQueryLog.Close(), func(err error) error {
+			return E.Cause(err, "close dns query log")
+		})
+	}
+	r.limiterAccess.Lock()
+	for _, inboundLimiter := range r.limiters {
+		err = E.Append(err, inboundLimiter.Close(), func(err error) error {
+			return E.Cause(err, "close limiter")
+		})
+	}
+	r.limiterAccess.Unlock()
 	if r.geositeReader != nil {
 		r.logger.Trace("closing geoip reader")
 		err = E.Append(err, common.Close(r.geoIPReader), func(err error) error {
@@ -563,6 +672,11 @@ func (r *Router) Close() error {
 	return err
 }
 
+func (r *Router) Inbound(tag string) (adapter.Inbound, bool) {
+	inbound, loaded := r.inboundByTag[tag]
+	return inbound, loaded
+}
+
 func (r *Router) Outbound(tag string) (adapter.Outbound, bool) {
 	outbound, loaded := r.outboundByTag[tag]
 	return outbound, loaded
@@ -611,10 +725,31 @@ func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata ad
 		return nil
 	}
 	metadata.Network = N.NetworkTCP
+
+	// multiplexed and UoT connections are limited as a whole, their streams are not counted again
+	if limiter.SessionFromContext(ctx) == nil {
+		limitSession, err := r.limitSession(metadata)
+		if err != nil {
+			return err
+		}
+		if limitSession != nil {
+			defer limitSession.Release()
+			ctx = limiter.ContextWithSession(ctx, limitSession)
+			conn = limitSession.NewConn(ctx, conn)
+		}
+	}
+
 	switch metadata.Destination.Fqdn {
 	case mux.Destination.Fqdn:
 		r.logger.InfoContext(ctx, "inbound multiplex connection")
 		handler := adapter.NewUpstreamHandler(metadata, r.RouteConnection, r.RoutePacketConnection, r)
+		muxService, err := r.muxService(metadata)
+		if err != nil {
+			return E.Cause(err, "create multiplex service")
+		}
+		if muxService != nil {
+			return muxService.NewConnection(ctx, handler, r.logger, conn, adapter.UpstreamMetadata(metadata))
+		}
 		return mux.HandleConnection(ctx, handler, r.logger, conn, adapter.UpstreamMetadata(metadata))
 	case vmess.MuxDestination.Fqdn:
 		r.logger.InfoContext(ctx, "inbound legacy multiplex connection")
@@ -747,6 +882,18 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 	}
 	metadata.Network = N.NetworkUDP
 
+	if limiter.SessionFromContext(ctx) == nil {
+		limitSession, err := r.limitSession(metadata)
+		if err != nil {
+			return err
+		}
+		if limitSession != nil {
+			defer limitSession.Release()
+			ctx = limiter.ContextWithSession(ctx, limitSession)
+			conn = limitSession.NewPacketConn(ctx, conn)
+		}
+	}
+
 	var originAddress M.Socksaddr
 	if r.fakeIPStore != nil && r.fakeIPStore.Contains(metadata.Destination.Addr) {
 		domain, loaded := r.fakeIPStore.Lookup(metadata.Destination.Addr)
@@ -966,6 +1113,9 @@ func (r *Router) ClashServer() adapter.ClashServer {
 
 func (r *Router) SetClashServer(server adapter.ClashServer) {
 	r.clashServer = server
+	if server != nil && r.dnsQueryLog == nil {
+		r.dnsQueryLog = NewDNSQueryLog()
+	}
 }
 
 func (r *Router) V2RayServer() adapter.V2RayServer {
@@ -1002,6 +1152,7 @@ func (r *Router) notifyNetworkUpdate(int) error {
 		r.logger.Info("updated default interface ", r.interfaceMonitor.DefaultInterfaceName(netip.IPv4Unspecified()), ", index ", r.interfaceMonitor.DefaultInterfaceIndex(netip.IPv4Unspecified()))
 	}
 
+	r.updateNetworkState()
 	conntrack.Close()
 
 	for _, outbound := range r.outbounds {
@@ -1017,6 +1168,7 @@ func (r *Router) notifyNetworkUpdate(int) error {
 }
 
 func (r *Router) ResetNetwork() error {
+	r.updateNetworkState()
 	conntrack.Close()
 
 	for _, outbound := range r.outbounds {
@@ -2,6 +2,7 @@ package route
 
 import (
 	"context"
+	"errors"
 	"net/netip"
 	"strings"
 	"time"
@@ -9,11 +10,13 @@ import (
 	"github.com/sagernet/sing-box/adapter"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/transport/fakeip"
 	"github.com/sagernet/sing-dns"
 	"github.com/sagernet/sing/common/cache"
 	E "github.com/sagernet/sing/common/exceptions"
 	F "github.com/sagernet/sing/common/format"
 	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/sing/common/task"
 
 	mDNS "github.com/miekg/dns"
 )
@@ -37,14 +40,28 @@ func (m *DNSReverseMapping) Query(address netip.Addr) (string, bool) {
 	return domain, loaded
 }
 
-func (r *Router) matchDNS(ctx context.Context) (context.Context, dns.Transport, dns.DomainStrategy) {
+func (r *Router) matchDNS(ctx context.Context, ruleIndex int) (context.Context, dns.Transport, dns.DomainStrategy, adapter.DNSRule, int) {
 	metadata := adapter.ContextFrom(ctx)
 	if metadata == nil {
 		panic("no context")
 	}
-	for i, rule := range r.dnsRules {
-		if rule.Match(metadata) {
+	if ruleIndex < len(r.dnsRules) {
+		dnsRules := r.dnsRules
+		if ruleIndex != -1 {
+			dnsRules = dnsRules[ruleIndex+1:]
+		}
+		for currentRuleIndex, rule := range dnsRules {
+			if ruleIndex != -1 {
+				currentRuleIndex += ruleIndex + 1
+			}
+			if !rule.Match(metadata) {
+				continue
+			}
 			detour := rule.Outbound()
+			if detour == "" {
+				r.dnsLogger.DebugContext(ctx, "match[", currentRuleIndex, "] ", rule.String(), " => predefined")
+				return ctx, nil, dns.DomainStrategyAsIS, rule, currentRuleIndex
+			}
 			transport, loaded := r.transportMap[detour]
 			if !loaded {
 				r.dnsLogger.ErrorContext(ctx, "transport not found: ", detour)
@@ -53,24 +70,29 @@ func (r *Router) matchDNS(ctx context.Context) (context.Context, dns.Transport,
 			if _, isFakeIP := transport.(adapter.FakeIPTransport); isFakeIP && metadata.FakeIP {
 				continue
 			}
-			r.dnsLogger.DebugContext(ctx, "match[", i, "] ", rule.String(), " => ", detour)
+			r.dnsLogger.DebugContext(ctx, "match[", currentRuleIndex, "] ", rule.String(), " => ", detour)
 			if rule.DisableCache() {
 				ctx = dns.ContextWithDisableCache(ctx, true)
 			}
 			if rewriteTTL := rule.RewriteTTL(); rewriteTTL != nil {
 				ctx = dns.ContextWithRewriteTTL(ctx, *rewriteTTL)
 			}
+			if clientSubnet := rule.ClientSubnet(); clientSubnet != nil {
+				ctx = contextWithClientSubnet(ctx, *clientSubnet)
+				// caches are keyed by question only, responses for another subnet must not be shared
+				ctx = dns.ContextWithDisableCache(ctx, true)
+			}
 			if domainStrategy, dsLoaded := r.transportDomainStrategy[transport]; dsLoaded {
-				return ctx, transport, domainStrategy
+				return ctx, transport, domainStrategy, rule, currentRuleIndex
 			} else {
-				return ctx, transport, dns.DomainStrategyAsIS
+				return ctx, transport, dns.DomainStrategyAsIS, rule, currentRuleIndex
 			}
 		}
 	}
 	if domainStrategy, dsLoaded := r.transportDomainStrategy[r.defaultTransport]; dsLoaded {
-		return ctx, r.defaultTransport, domainStrategy
+		return ctx, r.defaultTransport, domainStrategy, nil, -1
 	} else {
-		return ctx, r.defaultTransport, dns.DomainStrategyAsIS
+		return ctx, r.defaultTransport, dns.DomainStrategyAsIS, nil, -1
 	}
 }
 
@@ -80,30 +102,50 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, er
 	}
 	var (
 		response *mDNS.Msg
-		cached   bool
+		loaded   bool
 		err      error
+		record   = adapter.DNSQueryRecord{Time: time.Now(), RuleIndex: -1}
 	)
-	response, cached = r.dnsClient.ExchangeCache(ctx, message)
-	if !cached {
-		ctx, metadata := adapter.AppendContext(ctx)
-		if len(message.Question) > 0 {
-			metadata.QueryType = message.Question[0].Qtype
-			switch metadata.QueryType {
-			case mDNS.TypeA:
-				metadata.IPVersion = 4
-			case mDNS.TypeAAAA:
-				metadata.IPVersion = 6
-			}
-			metadata.Domain = fqdnToDomain(message.Question[0].Name)
+	if len(message.Question) > 0 {
+		record.Domain = fqdnToDomain(message.Question[0].Name)
+		record.QueryType = mDNS.Type(message.Question[0].Qtype).String()
+	}
+	if r.dnsHosts != nil {
+		response, loaded = r.dnsHosts.Exchange(message)
+		if loaded {
+			r.dnsLogger.DebugContext(ctx, "hosts matched for ", formatQuestion(message.Question[0].String()))
+			record.Transport = "hosts"
 		}
-		ctx, transport, strategy := r.matchDNS(ctx)
-		ctx, cancel := context.WithTimeout(ctx, C.DNSTimeout)
-		defer cancel()
-		response, err = r.dnsClient.Exchange(ctx, transport, message, strategy)
-		if err != nil && len(message.Question) > 0 {
-			r.dnsLogger.ErrorContext(ctx, E.Cause(err, "exchange failed for ", formatQuestion(message.Question[0].String())))
+	}
+	if !loaded {
+		response, record.Transport, loaded = r.exchangePTR(message)
+		if loaded {
+			r.dnsLogger.DebugContext(ctx, "reverse mapping matched for ", formatQuestion(message.Question[0].String()))
 		}
 	}
+	// with client subnet rules or independent cache, caches are checked after matching in exchange
+	if !loaded && r.dnsCache != nil && !r.dnsClientSubnetRules && !r.dnsIndependentCache {
+		var refresh bool
+		response, refresh, loaded = r.dnsCache.Load(message, "")
+		if refresh {
+			go r.refreshDNSCache(ctx, message.Copy(), "")
+		}
+		record.Cached = loaded
+	}
+	if !loaded && !r.dnsClientSubnetRules {
+		response, loaded = r.dnsClient.ExchangeCache(ctx, message)
+		record.Cached = loaded
+	}
+	if !loaded {
+		response, err = r.exchange(ctx, message, &record)
+	}
+	if response != nil {
+		record.RCode = mDNS.RcodeToString[response.Rcode]
+		for _, answer := range response.Answer {
+			record.Answers = append(record.Answers, formatQuestion(answer.String()))
+		}
+	}
+	r.saveDNSQuery(ctx, &record, err)
 	if len(message.Question) > 0 && response != nil {
 		LogDNSAnswers(r.dnsLogger, ctx, message.Question[0].Name, response.Answer)
 	}
@@ -120,11 +162,218 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, er
 	return response, err
 }
 
+func (r *Router) exchange(ctx context.Context, message *mDNS.Msg, record *adapter.DNSQueryRecord) (*mDNS.Msg, error) {
+	ctx, metadata := adapter.AppendContext(ctx)
+	var (
+		response      *mDNS.Msg
+		err           error
+		cacheable     = true
+		ruleIndex     = -1
+		transportName string
+	)
+	if len(message.Question) > 0 {
+		metadata.QueryType = message.Question[0].Qtype
+		switch metadata.QueryType {
+		case mDNS.TypeA:
+			metadata.IPVersion = 4
+		case mDNS.TypeAAAA:
+			metadata.IPVersion = 6
+		}
+		metadata.Domain = fqdnToDomain(message.Question[0].Name)
+	}
+	for {
+		dnsCtx, transport, strategy, rule, currentRuleIndex := r.matchDNS(ctx, ruleIndex)
+		ruleIndex = currentRuleIndex
+		if record != nil {
+			record.RuleIndex, record.Rule = dnsQueryRule(rule, ruleIndex)
+		}
+		if rule != nil {
+			if predefined := rule.PredefinedResponse(message); predefined != nil {
+				if record != nil {
+					record.Transport = "predefined"
+				}
+				return predefined, nil
+			}
+			if rule.DisableCache() || rule.ClientSubnet() != nil {
+				cacheable = false
+			}
+		}
+		if (r.dnsClientSubnetRules || r.dnsIndependentCache) && r.dnsCache != nil && !dns.DisableCacheFromContext(dnsCtx) {
+			if cachedResponse, refresh, loaded := r.dnsCache.Load(message, transport.Name()); loaded {
+				if refresh {
+					go r.refreshDNSCache(ctx, message.Copy(), transport.Name())
+				}
+				if record != nil {
+					record.Cached = true
+				}
+				return cachedResponse, nil
+			}
+		}
+		addressLimit := rule != nil && rule.WithAddressLimit() && isAddressQuery(message)
+		if addressLimit {
+			// responses may be rejected, so don't let them into the shared cache
+			dnsCtx = dns.ContextWithDisableCache(dnsCtx, true)
+			cacheable = false
+		}
+		if _, isFakeIP := transport.(adapter.FakeIPTransport); isFakeIP {
+			// fake addresses are only valid for the current fakeip store
+			cacheable = false
+		}
+		transportName = transport.Name()
+		if record != nil {
+			record.Transport = transportName
+		}
+		dnsCtx, cancel := context.WithTimeout(dnsCtx, C.DNSTimeout)
+		response, err = r.dnsClient.Exchange(dnsCtx, transport, message, strategy)
+		cancel()
+		if errors.Is(err, fakeip.ErrExcluded) && ruleIndex != -1 {
+			r.dnsLogger.DebugContext(ctx, "excluded from fakeip: ", formatQuestion(message.Question[0].String()))
+			continue
+		}
+		if err != nil && len(message.Question) > 0 {
+			r.dnsLogger.ErrorContext(ctx, E.Cause(err, "exchange failed for ", formatQuestion(message.Question[0].String())))
+		}
+		if addressLimit && err == nil && !r.matchAddressLimit(metadata, rule, MessageToAddresses(response)) {
+			r.dnsLogger.DebugContext(ctx, "response rejected for ", formatQuestion(message.Question[0].String()), " by rule[", ruleIndex, "]")
+			continue
+		}
+		break
+	}
+	if err == nil && cacheable && r.dnsCache != nil {
+		r.dnsCache.Store(message, transportName, response)
+	}
+	return response, err
+}
+
+func (r *Router) refreshDNSCache(ctx context.Context, message *mDNS.Msg, transportName string) {
+	refreshCtx := log.ContextWithNewID(r.ctx)
+	if metadata := adapter.ContextFrom(ctx); metadata != nil {
+		metadataCopy := *metadata
+		refreshCtx = adapter.WithContext(refreshCtx, &metadataCopy)
+	}
+	refreshCtx = dns.ContextWithDisableCache(refreshCtx, true)
+	r.dnsLogger.DebugContext(refreshCtx, "refresh cache for ", formatQuestion(message.Question[0].String()))
+	_, err := r.exchange(refreshCtx, message, nil)
+	if err != nil {
+		r.dnsCache.RefreshFailed(message, transportName)
+	}
+}
+
 func (r *Router) Lookup(ctx context.Context, domain string, strategy dns.DomainStrategy) ([]netip.Addr, error) {
 	r.dnsLogger.DebugContext(ctx, "lookup domain ", domain)
+	record := adapter.DNSQueryRecord{
+		Time:      time.Now(),
+		Domain:    domain,
+		QueryType: r.lookupQueryType(strategy),
+		RuleIndex: -1,
+	}
+	if r.dnsHosts != nil {
+		if addresses, loaded := r.dnsHosts.Lookup(domain); loaded {
+			if strategy == dns.DomainStrategyAsIS {
+				strategy = r.defaultDomainStrategy
+			}
+			addresses = filterAddressesByStrategy(addresses, strategy)
+			record.Transport = "hosts"
+			if len(addresses) == 0 {
+				r.dnsLogger.InfoContext(ctx, "hosts matched for ", domain, ": no address for the strategy")
+				r.saveDNSQuery(ctx, &record, dns.RCodeNameError)
+				return nil, dns.RCodeNameError
+			}
+			r.dnsLogger.InfoContext(ctx, "hosts matched for ", domain, ": ", strings.Join(F.MapToString(addresses), " "))
+			record.RCode = mDNS.RcodeToString[mDNS.RcodeSuccess]
+			record.Answers = F.MapToString(addresses)
+			r.saveDNSQuery(ctx, &record, nil)
+			return addresses, nil
+		}
+	}
 	ctx, metadata := adapter.AppendContext(ctx)
 	metadata.Domain = domain
-	ctx, transport, transportStrategy := r.matchDNS(ctx)
+	var (
+		addrs     []netip.Addr
+		err       error
+		ruleIndex = -1
+	)
+	for {
+		dnsCtx, transport, transportStrategy, rule, currentRuleIndex := r.matchDNS(ctx, ruleIndex)
+		ruleIndex = currentRuleIndex
+		record.RuleIndex, record.Rule = dnsQueryRule(rule, ruleIndex)
+		if rule != nil && rule.Outbound() == "" {
+			record.Transport = "predefined"
+			addrs, err = r.lookupPredefined(rule, domain, strategy)
+			break
+		}
+		record.Transport = transport.Name()
+		addressLimit := rule != nil && rule.WithAddressLimit()
+		if addressLimit {
+			dnsCtx = dns.ContextWithDisableCache(dnsCtx, true)
+		}
+		addrs, err = r.lookup(dnsCtx, metadata, transport, domain, strategy, transportStrategy)
+		if errors.Is(err, fakeip.ErrExcluded) && ruleIndex != -1 {
+			r.dnsLogger.DebugContext(ctx, "excluded from fakeip: ", domain)
+			continue
+		}
+		if addressLimit && err == nil && !r.matchAddressLimit(metadata, rule, addrs) {
+			r.dnsLogger.DebugContext(ctx, "response rejected for ", domain, " by rule[", ruleIndex, "]")
+			continue
+		}
+		break
+	}
+	if len(addrs) > 0 {
+		r.dnsLogger.InfoContext(ctx, "lookup succeed for ", domain, ": ", strings.Join(F.MapToString(addrs), " "))
+	} else {
+		r.dnsLogger.ErrorContext(ctx, E.Cause(err, "lookup failed for ", domain))
+		if err == nil {
+			err = dns.RCodeNameError
+		}
+	}
+	if err == nil {
+		record.RCode = mDNS.RcodeToString[mDNS.RcodeSuccess]
+	}
+	record.Answers = F.MapToString(addrs)
+	r.saveDNSQuery(ctx, &record, err)
+	return addrs, err
+}
+
+func (r *Router) lookupQueryType(strategy dns.DomainStrategy) string {
+	if strategy == dns.DomainStrategyAsIS {
+		strategy = r.defaultDomainStrategy
+	}
+	switch strategy {
+	case dns.DomainStrategyUseIPv4:
+		return "A"
+	case dns.DomainStrategyUseIPv6:
+		return "AAAA"
+	default:
+		return "A/AAAA"
+	}
+}
+
+func (r *Router) saveDNSQuery(ctx context.Context, record *adapter.DNSQueryRecord, err error) {
+	if r.dnsQueryLog == nil {
+		return
+	}
+	record.Latency = time.Since(record.Time)
+	if metadata := adapter.ContextFrom(ctx); metadata != nil {
+		record.Inbound = metadata.Inbound
+		record.Client = metadata.Source
+	}
+	if err != nil {
+		record.Error = err.Error()
+		if rCodeError, isRCodeError := err.(dns.RCodeError); isRCodeError {
+			record.RCode = mDNS.RcodeToString[int(rCodeError)]
+		}
+	}
+	r.dnsQueryLog.Add(*record)
+}
+
+func dnsQueryRule(rule adapter.DNSRule, ruleIndex int) (int, string) {
+	if rule == nil {
+		return -1, "final"
+	}
+	return ruleIndex, F.ToString("[", ruleIndex, "] ", rule)
+}
+
+func (r *Router) lookup(ctx context.Context, metadata *adapter.InboundContext, transport dns.Transport, domain string, strategy dns.DomainStrategy, transportStrategy dns.DomainStrategy) ([]netip.Addr, error) {
 	if strategy == dns.DomainStrategyAsIS {
 		strategy = transportStrategy
 	}
@@ -146,28 +395,165 @@ func (r *Router) Lookup(ctx context.Context, domain string, strategy dns.DomainS
 	}
 	ctx, cancel := context.WithTimeout(ctx, C.DNSTimeout)
 	defer cancel()
-	addrs, err := r.dnsClient.Lookup(ctx, transport, domain, strategy)
-	if len(addrs) > 0 {
-		r.dnsLogger.InfoContext(ctx, "lookup succeed for ", domain, ": ", strings.Join(F.MapToString(addrs), " "))
-	} else {
-		r.dnsLogger.ErrorContext(ctx, E.Cause(err, "lookup failed for ", domain))
-		if err == nil {
-			err = dns.RCodeNameError
+	if r.dnsCache != nil && transport.Raw() && !dns.DisableCacheFromContext(ctx) {
+		return r.lookupCache(ctx, transport, domain, strategy)
+	}
+	return r.dnsClient.Lookup(ctx, transport, domain, strategy)
+}
+
+// lookupCache resolves the domain through the persistent cache, so that lookups of dialers get stale
+// answers and prefetch as exchanges do.
+func (r *Router) lookupCache(ctx context.Context, transport dns.Transport, domain string, strategy dns.DomainStrategy) ([]netip.Addr, error) {
+	if strategy == dns.DomainStrategyUseIPv4 {
+		return r.lookupCacheType(ctx, transport, domain, mDNS.TypeA, strategy)
+	} else if strategy == dns.DomainStrategyUseIPv6 {
+		return r.lookupCacheType(ctx, transport, domain, mDNS.TypeAAAA, strategy)
+	}
+	var (
+		response4 []netip.Addr
+		response6 []netip.Addr
+		group     task.Group
+	)
+	group.Append("exchange4", func(ctx context.Context) error {
+		response, err := r.lookupCacheType(ctx, transport, domain, mDNS.TypeA, strategy)
+		if err != nil {
+			return err
 		}
+		response4 = response
+		return nil
+	})
+	group.Append("exchange6", func(ctx context.Context) error {
+		response, err := r.lookupCacheType(ctx, transport, domain, mDNS.TypeAAAA, strategy)
+		if err != nil {
+			return err
+		}
+		response6 = response
+		return nil
+	})
+	err := group.Run(ctx)
+	if len(response4) == 0 && len(response6) == 0 {
+		return nil, err
 	}
-	return addrs, err
+	if strategy == dns.DomainStrategyPreferIPv6 {
+		return append(response6, response4...), nil
+	}
+	return append(response4, response6...), nil
+}
+
+func (r *Router) lookupCacheType(ctx context.Context, transport dns.Transport, domain string, queryType uint16, strategy dns.DomainStrategy) ([]netip.Addr, error) {
+	message := new(mDNS.Msg)
+	message.SetQuestion(mDNS.Fqdn(domain), queryType)
+	response, refresh, loaded := r.dnsCache.Load(message, transport.Name())
+	if refresh {
+		go r.refreshDNSCache(ctx, message.Copy(), transport.Name())
+	}
+	if !loaded {
+		var err error
+		response, err = r.dnsClient.Exchange(ctx, transport, message, strategy)
+		if err != nil {
+			return nil, err
+		}
+		r.dnsCache.Store(message, transport.Name(), response)
+	}
+	if response.Rcode != mDNS.RcodeSuccess {
+		return nil, dns.RCodeError(response.Rcode)
+	}
+	return MessageToAddresses(response), nil
+}
+
+func (r *Router) lookupPredefined(rule adapter.DNSRule, domain string, strategy dns.DomainStrategy) ([]netip.Addr, error) {
+	if strategy == dns.DomainStrategyAsIS {
+		strategy = r.defaultDomainStrategy
+	}
+	var queryTypes []uint16
+	if strategy != dns.DomainStrategyUseIPv6 {
+		queryTypes = append(queryTypes, mDNS.TypeA)
+	}
+	if strategy != dns.DomainStrategyUseIPv4 {
+		queryTypes = append(queryTypes, mDNS.TypeAAAA)
+	}
+	var addresses []netip.Addr
+	for _, queryType := range queryTypes {
+		message := new(mDNS.Msg)
+		message.SetQuestion(mDNS.Fqdn(domain), queryType)
+		response := rule.PredefinedResponse(message)
+		if response.Rcode != mDNS.RcodeSuccess {
+			return nil, dns.RCodeError(response.Rcode)
+		}
+		addresses = append(addresses, MessageToAddresses(response)...)
+	}
+	return filterAddressesByStrategy(addresses, strategy), nil
+}
+
+func (r *Router) matchAddressLimit(metadata *adapter.InboundContext, rule adapter.DNSRule, addresses []netip.Addr) bool {
+	destination := metadata.Destination
+	destinationAddresses := metadata.DestinationAddresses
+	geoIPCode := metadata.GeoIPCode
+	// match against the response only, not the address the query was sent to
+	metadata.Destination = M.Socksaddr{Port: destination.Port}
+	metadata.DestinationAddresses = addresses
+	metadata.GeoIPCode = ""
+	defer func() {
+		metadata.Destination = destination
+		metadata.DestinationAddresses = destinationAddresses
+		metadata.GeoIPCode = geoIPCode
+	}()
+	return rule.MatchAddressLimit(metadata)
 }
 
 func (r *Router) LookupDefault(ctx context.Context, domain string) ([]netip.Addr, error) {
 	return r.Lookup(ctx, domain, dns.DomainStrategyAsIS)
 }
 
+func (r *Router) DNSTransports() []dns.Transport {
+	return r.transports
+}
+
+func (r *Router) DNSQueryLog() adapter.DNSQueryLog {
+	if r.dnsQueryLog == nil {
+		return nil
+	}
+	return r.dnsQueryLog
+}
+
+func (r *Router) ClearDNSCache() error {
+	if r.dnsCache == nil {
+		return nil
+	}
+	return r.dnsCache.Clear()
+}
+
 func LogDNSAnswers(logger log.ContextLogger, ctx context.Context, domain string, answers []mDNS.RR) {
 	for _, answer := range answers {
 		logger.InfoContext(ctx, "exchanged ", domain, " ", mDNS.Type(answer.Header().Rrtype).String(), " ", formatQuestion(answer.String()))
 	}
 }
 
+func MessageToAddresses(response *mDNS.Msg) []netip.Addr {
+	if response == nil || response.Rcode != mDNS.RcodeSuccess {
+		return nil
+	}
+	addresses := make([]netip.Addr, 0, len(response.Answer))
+	for _, rawAnswer := range response.Answer {
+		switch answer := rawAnswer.(type) {
+		case *mDNS.A:
+			addresses = append(addresses, M.AddrFromIP(answer.A))
+		case *mDNS.AAAA:
+			addresses = append(addresses, M.AddrFromIP(answer.AAAA))
+		}
+	}
+	return addresses
+}
+
+func isAddressQuery(message *mDNS.Msg) bool {
+	for _, question := range message.Question {
+		if question.Qtype == mDNS.TypeA || question.Qtype == mDNS.TypeAAAA {
+			return true
+		}
+	}
+	return false
+}
+
 func fqdnToDomain(fqdn string) string {
 	if mDNS.IsFqdn(fqdn) {
 		return fqdn[:len(fqdn)-1]
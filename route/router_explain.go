@@ -0,0 +1,100 @@
+package route
+
+import (
+	"context"
+
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+func (r *Router) ExplainRoute(ctx context.Context, metadata adapter.InboundContext, resolve bool) (*adapter.RouteExplanation, error) {
+	if metadata.Network == "" {
+		metadata.Network = N.NetworkTCP
+	}
+	switch metadata.Network {
+	case N.NetworkTCP, N.NetworkUDP:
+	default:
+		return nil, E.Cause(N.ErrUnknownNetwork, metadata.Network)
+	}
+	if !metadata.Destination.IsValid() {
+		return nil, E.New("missing destination")
+	}
+	if metadata.Inbound != "" {
+		inbound, loaded := r.inboundByTag[metadata.Inbound]
+		if !loaded {
+			return nil, E.New("inbound not found: ", metadata.Inbound)
+		}
+		metadata.InboundType = inbound.Type()
+	}
+	if metadata.Destination.IsFqdn() {
+		metadata.Domain = metadata.Destination.Fqdn
+	} else if metadata.Destination.IsIPv4() {
+		metadata.IPVersion = 4
+	} else if metadata.Destination.IsIPv6() {
+		metadata.IPVersion = 6
+	}
+	if r.fakeIPStore != nil && r.fakeIPStore.Contains(metadata.Destination.Addr) {
+		domain, loaded := r.fakeIPStore.Lookup(metadata.Destination.Addr)
+		if !loaded {
+			return nil, E.New("missing fakeip context")
+		}
+		metadata.Destination = M.Socksaddr{
+			Fqdn: domain,
+			Port: metadata.Destination.Port,
+		}
+		metadata.Domain = domain
+		metadata.FakeIP = true
+	}
+	if r.dnsReverseMapping != nil && metadata.Domain == "" {
+		domain, loaded := r.dnsReverseMapping.Query(metadata.Destination.Addr)
+		if loaded {
+			metadata.Domain = domain
+		}
+	}
+	explanation := &adapter.RouteExplanation{
+		MatchedRule: -1,
+	}
+	if resolve && metadata.Destination.IsFqdn() {
+		addresses, err := r.LookupDefault(adapter.WithContext(ctx, &metadata), metadata.Destination.Fqdn)
+		if err != nil {
+			explanation.ResolveError = err
+		} else {
+			metadata.DestinationAddresses = addresses
+		}
+	}
+	var detour adapter.Outbound
+	for i, rule := range r.rules {
+		ruleExplanation := adapter.RuleExplanation{
+			Index:    i,
+			Type:     rule.Type(),
+			Rule:     rule.String(),
+			Outbound: rule.Outbound(),
+		}
+		if rule.Match(&metadata) {
+			ruleExplanation.Matched = true
+			explanation.Rules = append(explanation.Rules, ruleExplanation)
+			if outbound, loaded := r.Outbound(rule.Outbound()); loaded {
+				explanation.MatchedRule = i
+				detour = outbound
+				break
+			}
+			explanation.Rules[len(explanation.Rules)-1].Reason = "outbound not found: " + rule.Outbound()
+			continue
+		}
+		ruleExplanation.Reason = explainRule(rule, &metadata)
+		explanation.Rules = append(explanation.Rules, ruleExplanation)
+	}
+	if detour == nil {
+		detour = r.DefaultOutbound(metadata.Network)
+	}
+	explanation.Metadata = metadata
+	explanation.Outbound = detour.Tag()
+	realOutbound, err := adapter.RealOutbound(r, detour)
+	if err != nil {
+		return nil, err
+	}
+	explanation.RealOutbound = realOutbound.Tag()
+	return explanation, nil
+}
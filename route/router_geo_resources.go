@@ -271,7 +271,13 @@ func isGeoIPRule(rule option.DefaultRule) bool {
 }
 
 func isGeoIPDNSRule(rule option.DefaultDNSRule) bool {
-	return len(rule.SourceGeoIP) > 0 && common.Any(rule.SourceGeoIP, notPrivateNode)
+	return len(rule.SourceGeoIP) > 0 && common.Any(rule.SourceGeoIP, notPrivateNode) || len(rule.GeoIP) > 0 && common.Any(rule.GeoIP, notPrivateNode)
+}
+
+func isGeoIPDNSServer(server option.DNSServerOptions) bool {
+	return common.Any(server.Upstreams, func(it option.DNSUpstream) bool {
+		return common.Any(it.GeoIP, notPrivateNode)
+	})
 }
 
 func isGeositeRule(rule option.DefaultRule) bool {
@@ -290,6 +296,14 @@ func isProcessDNSRule(rule option.DefaultDNSRule) bool {
 	return len(rule.ProcessName) > 0 || len(rule.ProcessPath) > 0 || len(rule.PackageName) > 0 || len(rule.User) > 0 || len(rule.UserID) > 0
 }
 
+func isNetworkRule(rule option.DefaultRule) bool {
+	return len(rule.NetworkType) > 0 || len(rule.NetworkInterface) > 0 || len(rule.DefaultGateway) > 0 || len(rule.WIFISSID) > 0 || len(rule.WIFIBSSID) > 0
+}
+
+func isNetworkDNSRule(rule option.DefaultDNSRule) bool {
+	return len(rule.NetworkType) > 0 || len(rule.NetworkInterface) > 0 || len(rule.DefaultGateway) > 0 || len(rule.WIFISSID) > 0 || len(rule.WIFIBSSID) > 0
+}
+
 func notPrivateNode(code string) bool {
 	return code != "private"
 }
@@ -0,0 +1,39 @@
+package route
+
+import (
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/limiter"
+	"github.com/sagernet/sing-box/option"
+)
+
+// limitSession checks the limits of the inbound for a new connection, a nil session is returned if the inbound has no limits.
+func (r *Router) limitSession(metadata adapter.InboundContext) (*limiter.Session, error) {
+	limitOptions := metadata.InboundOptions.Limit
+	if limitOptions == nil {
+		return nil, nil
+	}
+	r.limiterAccess.Lock()
+	inboundLimiter, loaded := r.limiters[limitOptions]
+	if !loaded {
+		inbound, inboundType := metadata.Inbound, metadata.InboundType
+		inboundLimiter = limiter.New(*limitOptions, func(err *limiter.LimitError) {
+			r.logger.Warn("inbound/", inboundType, "[", inbound, "]: ", err)
+			if r.v2rayServer != nil {
+				if statsService := r.v2rayServer.StatsService(); statsService != nil {
+					statsService.LimitReached(inbound, err.User, err.Limit)
+				}
+			}
+		})
+		if inbound != "" && r.clashServer != nil && r.clashServer.StoreLimit() {
+			if cacheFile := r.clashServer.CacheFile(); cacheFile != nil {
+				inboundLimiter.SetStorage(cacheFile, inbound, r.logger)
+			}
+		}
+		if r.limiters == nil {
+			r.limiters = make(map[*option.InboundLimitOptions]*limiter.Limiter)
+		}
+		r.limiters[limitOptions] = inboundLimiter
+	}
+	r.limiterAccess.Unlock()
+	return inboundLimiter.Acquire(metadata.User, metadata.Source.Addr)
+}
@@ -0,0 +1,34 @@
+package route
+
+import (
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/mux"
+	"github.com/sagernet/sing-box/option"
+)
+
+// muxService returns the multiplex service of the inbound, a nil service is returned if the inbound has no multiplex options.
+// Services of configured inbounds are created by NewRouter, so invalid options fail the configuration instead of the first connection.
+func (r *Router) muxService(metadata adapter.InboundContext) (*mux.Service, error) {
+	return r.loadMuxService(metadata.InboundOptions.Multiplex)
+}
+
+func (r *Router) loadMuxService(muxOptions *option.InboundMultiplexOptions) (*mux.Service, error) {
+	if muxOptions == nil {
+		return nil, nil
+	}
+	r.muxServiceAccess.Lock()
+	defer r.muxServiceAccess.Unlock()
+	service, loaded := r.muxServices[muxOptions]
+	if loaded {
+		return service, nil
+	}
+	service, err := mux.NewService(*muxOptions)
+	if err != nil {
+		return nil, err
+	}
+	if r.muxServices == nil {
+		r.muxServices = make(map[*option.InboundMultiplexOptions]*mux.Service)
+	}
+	r.muxServices[muxOptions] = service
+	return service, nil
+}
@@ -14,6 +14,7 @@ type abstractDefaultRule struct {
 	sourceAddressItems      []RuleItem
 	sourcePortItems         []RuleItem
 	destinationAddressItems []RuleItem
+	destinationIPCIDRItems  []RuleItem
 	destinationPortItems    []RuleItem
 	allItems                []RuleItem
 	invert                  bool
@@ -106,6 +107,19 @@ func (r *abstractDefaultRule) Match(metadata *adapter.InboundContext) bool {
 		}
 	}
 
+	if len(r.destinationIPCIDRItems) > 0 && !metadata.IgnoreDestinationIPCIDRMatch {
+		var destinationIPCIDRMatch bool
+		for _, item := range r.destinationIPCIDRItems {
+			if item.Match(metadata) {
+				destinationIPCIDRMatch = true
+				break
+			}
+		}
+		if !destinationIPCIDRMatch {
+			return r.invert
+		}
+	}
+
 	if len(r.destinationPortItems) > 0 {
 		var destinationPortMatch bool
 		for _, item := range r.destinationPortItems {
@@ -1,11 +1,16 @@
 package route
 
 import (
+	"net/netip"
+
 	"github.com/sagernet/sing-box/adapter"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
+
+	mDNS "github.com/miekg/dns"
 )
 
 func NewDNSRule(router adapter.Router, logger log.ContextLogger, options option.DNSRule) (adapter.DNSRule, error) {
@@ -14,16 +19,20 @@ func NewDNSRule(router adapter.Router, logger log.ContextLogger, options option.
 		if !options.DefaultOptions.IsValid() {
 			return nil, E.New("missing conditions")
 		}
-		if options.DefaultOptions.Server == "" {
+		if options.DefaultOptions.Server == "" && options.DefaultOptions.Predefined == nil {
 			return nil, E.New("missing server field")
+		} else if options.DefaultOptions.Server != "" && options.DefaultOptions.Predefined != nil {
+			return nil, E.New("server and predefined are mutually exclusive")
 		}
 		return NewDefaultDNSRule(router, logger, options.DefaultOptions)
 	case C.RuleTypeLogical:
 		if !options.LogicalOptions.IsValid() {
 			return nil, E.New("missing conditions")
 		}
-		if options.LogicalOptions.Server == "" {
+		if options.LogicalOptions.Server == "" && options.LogicalOptions.Predefined == nil {
 			return nil, E.New("missing server field")
+		} else if options.LogicalOptions.Server != "" && options.LogicalOptions.Predefined != nil {
+			return nil, E.New("server and predefined are mutually exclusive")
 		}
 		return NewLogicalDNSRule(router, logger, options.LogicalOptions)
 	default:
@@ -37,6 +46,8 @@ type DefaultDNSRule struct {
 	abstractDefaultRule
 	disableCache bool
 	rewriteTTL   *uint32
+	clientSubnet *netip.Prefix
+	predefined   *predefinedResponse
 }
 
 func NewDefaultDNSRule(router adapter.Router, logger log.ContextLogger, options option.DefaultDNSRule) (*DefaultDNSRule, error) {
@@ -48,6 +59,20 @@ func NewDefaultDNSRule(router adapter.Router, logger log.ContextLogger, options
 		disableCache: options.DisableCache,
 		rewriteTTL:   options.RewriteTTL,
 	}
+	if options.Predefined != nil {
+		predefined, err := newPredefinedResponse(*options.Predefined)
+		if err != nil {
+			return nil, E.Cause(err, "predefined")
+		}
+		rule.predefined = predefined
+	}
+	if options.ClientSubnet != "" {
+		clientSubnet, err := parseClientSubnet(options.ClientSubnet)
+		if err != nil {
+			return nil, err
+		}
+		rule.clientSubnet = &clientSubnet
+	}
 	if len(options.Inbound) > 0 {
 		item := NewInboundRule(options.Inbound)
 		rule.items = append(rule.items, item)
@@ -111,6 +136,11 @@ func NewDefaultDNSRule(router adapter.Router, logger log.ContextLogger, options
 		rule.sourceAddressItems = append(rule.sourceAddressItems, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if len(options.GeoIP) > 0 {
+		item := NewGeoIPItem(router, logger, false, options.GeoIP)
+		rule.destinationIPCIDRItems = append(rule.destinationIPCIDRItems, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	if len(options.SourceIPCIDR) > 0 {
 		item, err := NewIPCIDRItem(true, options.SourceIPCIDR)
 		if err != nil {
@@ -119,6 +149,29 @@ func NewDefaultDNSRule(router adapter.Router, logger log.ContextLogger, options
 		rule.sourceAddressItems = append(rule.sourceAddressItems, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if len(options.IPCIDR) > 0 {
+		item, err := NewIPCIDRItem(false, options.IPCIDR)
+		if err != nil {
+			return nil, E.Cause(err, "ip_cidr")
+		}
+		rule.destinationIPCIDRItems = append(rule.destinationIPCIDRItems, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if options.SourceIPIsPrivate {
+		item := NewIPIsPrivateItem(true)
+		rule.sourceAddressItems = append(rule.sourceAddressItems, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if options.IPIsPrivate {
+		item := NewIPIsPrivateItem(false)
+		rule.destinationIPCIDRItems = append(rule.destinationIPCIDRItems, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if options.FakeIP {
+		item := NewDNSFakeIPItem(router)
+		rule.destinationIPCIDRItems = append(rule.destinationIPCIDRItems, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	if len(options.SourcePort) > 0 {
 		item := NewPortItem(true, options.SourcePort)
 		rule.sourcePortItems = append(rule.sourcePortItems, item)
@@ -175,6 +228,39 @@ func NewDefaultDNSRule(router adapter.Router, logger log.ContextLogger, options
 		rule.items = append(rule.items, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if len(options.NetworkType) > 0 {
+		item := NewNetworkTypeItem(router, options.NetworkType)
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if len(options.NetworkInterface) > 0 {
+		item := NewNetworkInterfaceItem(router, options.NetworkInterface)
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if len(options.DefaultGateway) > 0 {
+		item, err := NewDefaultGatewayItem(router, options.DefaultGateway)
+		if err != nil {
+			return nil, E.Cause(err, "default_gateway")
+		}
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if len(options.WIFISSID) > 0 {
+		item := NewWIFISSIDItem(router, options.WIFISSID)
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if len(options.WIFIBSSID) > 0 {
+		item := NewWIFIBSSIDItem(router, options.WIFIBSSID)
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if options.DHCPSearchDomain {
+		item := NewDHCPSearchDomainItem(router)
+		rule.destinationAddressItems = append(rule.destinationAddressItems, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	if options.ClashMode != "" {
 		item := NewClashModeItem(router, options.ClashMode)
 		rule.items = append(rule.items, item)
@@ -191,12 +277,41 @@ func (r *DefaultDNSRule) RewriteTTL() *uint32 {
 	return r.rewriteTTL
 }
 
+func (r *DefaultDNSRule) ClientSubnet() *netip.Prefix {
+	return r.clientSubnet
+}
+
+func (r *DefaultDNSRule) PredefinedResponse(request *mDNS.Msg) *mDNS.Msg {
+	if r.predefined == nil {
+		return nil
+	}
+	return r.predefined.Response(request)
+}
+
+func (r *DefaultDNSRule) WithAddressLimit() bool {
+	return len(r.destinationIPCIDRItems) > 0
+}
+
+func (r *DefaultDNSRule) Match(metadata *adapter.InboundContext) bool {
+	metadata.IgnoreDestinationIPCIDRMatch = true
+	defer func() {
+		metadata.IgnoreDestinationIPCIDRMatch = false
+	}()
+	return r.abstractDefaultRule.Match(metadata)
+}
+
+func (r *DefaultDNSRule) MatchAddressLimit(metadata *adapter.InboundContext) bool {
+	return r.abstractDefaultRule.Match(metadata)
+}
+
 var _ adapter.DNSRule = (*LogicalDNSRule)(nil)
 
 type LogicalDNSRule struct {
 	abstractLogicalRule
 	disableCache bool
 	rewriteTTL   *uint32
+	clientSubnet *netip.Prefix
+	predefined   *predefinedResponse
 }
 
 func NewLogicalDNSRule(router adapter.Router, logger log.ContextLogger, options option.LogicalDNSRule) (*LogicalDNSRule, error) {
@@ -209,6 +324,20 @@ func NewLogicalDNSRule(router adapter.Router, logger log.ContextLogger, options
 		disableCache: options.DisableCache,
 		rewriteTTL:   options.RewriteTTL,
 	}
+	if options.Predefined != nil {
+		predefined, err := newPredefinedResponse(*options.Predefined)
+		if err != nil {
+			return nil, E.Cause(err, "predefined")
+		}
+		r.predefined = predefined
+	}
+	if options.ClientSubnet != "" {
+		clientSubnet, err := parseClientSubnet(options.ClientSubnet)
+		if err != nil {
+			return nil, err
+		}
+		r.clientSubnet = &clientSubnet
+	}
 	switch options.Mode {
 	case C.LogicalTypeAnd:
 		r.mode = C.LogicalTypeAnd
@@ -234,3 +363,32 @@ func (r *LogicalDNSRule) DisableCache() bool {
 func (r *LogicalDNSRule) RewriteTTL() *uint32 {
 	return r.rewriteTTL
 }
+
+func (r *LogicalDNSRule) ClientSubnet() *netip.Prefix {
+	return r.clientSubnet
+}
+
+func (r *LogicalDNSRule) PredefinedResponse(request *mDNS.Msg) *mDNS.Msg {
+	if r.predefined == nil {
+		return nil
+	}
+	return r.predefined.Response(request)
+}
+
+func (r *LogicalDNSRule) WithAddressLimit() bool {
+	return common.Any(r.rules, func(rule adapter.Rule) bool {
+		return rule.(adapter.DNSRule).WithAddressLimit()
+	})
+}
+
+func (r *LogicalDNSRule) MatchAddressLimit(metadata *adapter.InboundContext) bool {
+	if r.mode == C.LogicalTypeAnd {
+		return common.All(r.rules, func(it adapter.Rule) bool {
+			return it.(adapter.DNSRule).MatchAddressLimit(metadata)
+		}) != r.invert
+	} else {
+		return common.Any(r.rules, func(it adapter.Rule) bool {
+			return it.(adapter.DNSRule).MatchAddressLimit(metadata)
+		}) != r.invert
+	}
+}
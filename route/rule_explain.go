@@ -0,0 +1,67 @@
+package route
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	F "github.com/sagernet/sing/common/format"
+)
+
+func explainRule(rule adapter.Rule, metadata *adapter.InboundContext) string {
+	switch typedRule := rule.(type) {
+	case *DefaultRule:
+		return typedRule.abstractDefaultRule.explain(metadata)
+	case *LogicalRule:
+		return typedRule.abstractLogicalRule.explain(metadata)
+	default:
+		return "not matched"
+	}
+}
+
+func (r *abstractDefaultRule) explain(metadata *adapter.InboundContext) string {
+	if r.invert {
+		return "all conditions matched, but the rule is inverted"
+	}
+	for _, item := range r.items {
+		if !item.Match(metadata) {
+			return item.String() + " not matched"
+		}
+	}
+	for _, itemGroup := range [][]RuleItem{r.sourceAddressItems, r.sourcePortItems, r.destinationAddressItems, r.destinationIPCIDRItems, r.destinationPortItems} {
+		if len(itemGroup) == 0 {
+			continue
+		}
+		var groupMatch bool
+		for _, item := range itemGroup {
+			if item.Match(metadata) {
+				groupMatch = true
+				break
+			}
+		}
+		if !groupMatch {
+			return strings.Join(F.MapToString(itemGroup), " || ") + " not matched"
+		}
+	}
+	return "not matched"
+}
+
+func (r *abstractLogicalRule) explain(metadata *adapter.InboundContext) string {
+	if r.invert {
+		return "sub rules matched, but the rule is inverted"
+	}
+	if r.mode == C.LogicalTypeAnd {
+		for i, rule := range r.rules {
+			if !rule.Match(metadata) {
+				return F.ToString("sub rule[", i, "]: ", explainRule(rule, metadata))
+			}
+		}
+		return "not matched"
+	} else {
+		reasons := make([]string, 0, len(r.rules))
+		for i, rule := range r.rules {
+			reasons = append(reasons, F.ToString("sub rule[", i, "]: ", explainRule(rule, metadata)))
+		}
+		return "no sub rule matched (" + strings.Join(reasons, "; ") + ")"
+	}
+}
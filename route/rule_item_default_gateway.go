@@ -0,0 +1,66 @@
+package route
+
+import (
+	"net/netip"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"go4.org/netipx"
+)
+
+var _ RuleItem = (*DefaultGatewayItem)(nil)
+
+type DefaultGatewayItem struct {
+	router      adapter.Router
+	ipSet       *netipx.IPSet
+	description string
+}
+
+func NewDefaultGatewayItem(router adapter.Router, prefixStrings []string) (*DefaultGatewayItem, error) {
+	var builder netipx.IPSetBuilder
+	for i, prefixString := range prefixStrings {
+		prefix, err := netip.ParsePrefix(prefixString)
+		if err == nil {
+			builder.AddPrefix(prefix)
+			continue
+		}
+		addr, addrErr := netip.ParseAddr(prefixString)
+		if addrErr == nil {
+			builder.Add(addr)
+			continue
+		}
+		return nil, E.Cause(err, "parse default_gateway [", i, "]")
+	}
+	description := "default_gateway="
+	if dLen := len(prefixStrings); dLen == 1 {
+		description += prefixStrings[0]
+	} else if dLen > 3 {
+		description += "[" + strings.Join(prefixStrings[:3], " ") + "...]"
+	} else {
+		description += "[" + strings.Join(prefixStrings, " ") + "]"
+	}
+	ipSet, err := builder.IPSet()
+	if err != nil {
+		return nil, err
+	}
+	return &DefaultGatewayItem{
+		router:      router,
+		ipSet:       ipSet,
+		description: description,
+	}, nil
+}
+
+func (r *DefaultGatewayItem) Match(metadata *adapter.InboundContext) bool {
+	for _, gateway := range r.router.NetworkState().Gateways {
+		if r.ipSet.Contains(gateway) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *DefaultGatewayItem) String() string {
+	return r.description
+}
@@ -0,0 +1,41 @@
+package route
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common"
+)
+
+var _ RuleItem = (*DHCPSearchDomainItem)(nil)
+
+type DHCPSearchDomainItem struct {
+	router adapter.Router
+}
+
+func NewDHCPSearchDomainItem(router adapter.Router) *DHCPSearchDomainItem {
+	return &DHCPSearchDomainItem{router}
+}
+
+func (r *DHCPSearchDomainItem) Match(metadata *adapter.InboundContext) bool {
+	domain := strings.ToLower(metadata.Domain)
+	if domain == "" {
+		return false
+	}
+	for _, transport := range r.router.DNSTransports() {
+		dhcpTransport, isDHCP := common.Cast[adapter.DHCPTransport](transport)
+		if !isDHCP {
+			continue
+		}
+		for _, searchDomain := range dhcpTransport.DHCPInformation().SearchDomains {
+			if domain == searchDomain || strings.HasSuffix(domain, "."+searchDomain) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *DHCPSearchDomainItem) String() string {
+	return "dhcp_search_domain=true"
+}
@@ -0,0 +1,40 @@
+package route
+
+import (
+	"github.com/sagernet/sing-box/adapter"
+)
+
+var _ RuleItem = (*FakeIPItem)(nil)
+
+type FakeIPItem struct {
+	router adapter.Router
+}
+
+func NewFakeIPItem() *FakeIPItem {
+	return &FakeIPItem{}
+}
+
+// NewDNSFakeIPItem matches responses with addresses in the FakeIP range.
+func NewDNSFakeIPItem(router adapter.Router) *FakeIPItem {
+	return &FakeIPItem{router}
+}
+
+func (r *FakeIPItem) Match(metadata *adapter.InboundContext) bool {
+	if r.router == nil {
+		return metadata.FakeIP
+	}
+	fakeIPStore := r.router.FakeIPStore()
+	if fakeIPStore == nil {
+		return false
+	}
+	for _, destinationAddress := range metadata.DestinationAddresses {
+		if fakeIPStore.Contains(destinationAddress) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *FakeIPItem) String() string {
+	return "fakeip=true"
+}
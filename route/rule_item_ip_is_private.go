@@ -0,0 +1,80 @@
+package route
+
+import (
+	"net/netip"
+
+	"github.com/sagernet/sing-box/adapter"
+
+	"go4.org/netipx"
+)
+
+var _ RuleItem = (*IPIsPrivateItem)(nil)
+
+var privateIPSet *netipx.IPSet
+
+func init() {
+	var builder netipx.IPSetBuilder
+	for _, prefix := range []string{
+		"0.0.0.0/8",
+		"10.0.0.0/8",
+		"100.64.0.0/10",
+		"127.0.0.0/8",
+		"169.254.0.0/16",
+		"172.16.0.0/12",
+		"192.0.0.0/24",
+		"192.0.2.0/24",
+		"192.88.99.0/24",
+		"192.168.0.0/16",
+		"198.18.0.0/15",
+		"198.51.100.0/24",
+		"203.0.113.0/24",
+		"224.0.0.0/4",
+		"240.0.0.0/4",
+		"::/128",
+		"::1/128",
+		"64:ff9b:1::/48",
+		"100::/64",
+		"2001:db8::/32",
+		"fc00::/7",
+		"fe80::/10",
+		"ff00::/8",
+	} {
+		builder.AddPrefix(netip.MustParsePrefix(prefix))
+	}
+	privateIPSet, _ = builder.IPSet()
+}
+
+func isPrivateAddr(addr netip.Addr) bool {
+	return privateIPSet.Contains(addr.Unmap())
+}
+
+type IPIsPrivateItem struct {
+	isSource bool
+}
+
+func NewIPIsPrivateItem(isSource bool) *IPIsPrivateItem {
+	return &IPIsPrivateItem{isSource}
+}
+
+func (r *IPIsPrivateItem) Match(metadata *adapter.InboundContext) bool {
+	if r.isSource {
+		return isPrivateAddr(metadata.Source.Addr)
+	}
+	if metadata.Destination.IsIP() {
+		return isPrivateAddr(metadata.Destination.Addr)
+	}
+	for _, destinationAddress := range metadata.DestinationAddresses {
+		if isPrivateAddr(destinationAddress) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *IPIsPrivateItem) String() string {
+	if r.isSource {
+		return "source_ip_is_private=true"
+	} else {
+		return "ip_is_private=true"
+	}
+}
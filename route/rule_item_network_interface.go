@@ -0,0 +1,43 @@
+package route
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	F "github.com/sagernet/sing/common/format"
+)
+
+var _ RuleItem = (*NetworkInterfaceItem)(nil)
+
+type NetworkInterfaceItem struct {
+	router           adapter.Router
+	interfaceNames   []string
+	interfaceNameMap map[string]bool
+}
+
+func NewNetworkInterfaceItem(router adapter.Router, interfaceNames []string) *NetworkInterfaceItem {
+	interfaceNameMap := make(map[string]bool)
+	for _, interfaceName := range interfaceNames {
+		interfaceNameMap[interfaceName] = true
+	}
+	return &NetworkInterfaceItem{
+		router:           router,
+		interfaceNames:   interfaceNames,
+		interfaceNameMap: interfaceNameMap,
+	}
+}
+
+func (r *NetworkInterfaceItem) Match(metadata *adapter.InboundContext) bool {
+	interfaceName := r.router.NetworkState().InterfaceName
+	if interfaceName == "" {
+		return false
+	}
+	return r.interfaceNameMap[interfaceName]
+}
+
+func (r *NetworkInterfaceItem) String() string {
+	if len(r.interfaceNames) == 1 {
+		return F.ToString("network_interface=", r.interfaceNames[0])
+	}
+	return F.ToString("network_interface=[", strings.Join(r.interfaceNames, " "), "]")
+}
@@ -0,0 +1,43 @@
+package route
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	F "github.com/sagernet/sing/common/format"
+)
+
+var _ RuleItem = (*NetworkTypeItem)(nil)
+
+type NetworkTypeItem struct {
+	router         adapter.Router
+	networkTypes   []string
+	networkTypeMap map[string]bool
+}
+
+func NewNetworkTypeItem(router adapter.Router, networkTypes []string) *NetworkTypeItem {
+	networkTypeMap := make(map[string]bool)
+	for _, networkType := range networkTypes {
+		networkTypeMap[strings.ToLower(networkType)] = true
+	}
+	return &NetworkTypeItem{
+		router:         router,
+		networkTypes:   networkTypes,
+		networkTypeMap: networkTypeMap,
+	}
+}
+
+func (r *NetworkTypeItem) Match(metadata *adapter.InboundContext) bool {
+	networkType := r.router.NetworkState().InterfaceType
+	if networkType == "" {
+		return false
+	}
+	return r.networkTypeMap[networkType]
+}
+
+func (r *NetworkTypeItem) String() string {
+	if len(r.networkTypes) == 1 {
+		return F.ToString("network_type=", r.networkTypes[0])
+	}
+	return F.ToString("network_type=[", strings.Join(r.networkTypes, " "), "]")
+}
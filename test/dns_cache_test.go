@@ -0,0 +1,408 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/experimental/clashapi/cachefile"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+	F "github.com/sagernet/sing/common/format"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/protocol/socks"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSCacheServeStaleLookup(t *testing.T) {
+	var (
+		queries int32
+		failed  atomic.Bool
+	)
+	packetConn, err := net.ListenPacket("udp", F.ToString("127.0.0.1:", serverPort))
+	require.NoError(t, err)
+	server := &mDNS.Server{
+		PacketConn: packetConn,
+		Handler: mDNS.HandlerFunc(func(writer mDNS.ResponseWriter, message *mDNS.Msg) {
+			atomic.AddInt32(&queries, 1)
+			response := new(mDNS.Msg)
+			response.SetReply(message)
+			if failed.Load() {
+				response.Rcode = mDNS.RcodeServerFailure
+			} else if message.Question[0].Qtype == mDNS.TypeA {
+				response.Answer = append(response.Answer, &mDNS.A{
+					Hdr: mDNS.RR_Header{Name: message.Question[0].Name, Rrtype: mDNS.TypeA, Class: mDNS.ClassINET, Ttl: 1},
+					A:   net.IPv4(127, 0, 0, 1),
+				})
+			}
+			writer.WriteMsg(response)
+		}),
+	}
+	go server.ActivateAndServe()
+	t.Cleanup(func() {
+		server.Shutdown()
+	})
+	startInstance(t, option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeMixed,
+				MixedOptions: option.HTTPMixedInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: clientPort,
+					},
+				},
+			},
+		},
+		Outbounds: []option.Outbound{
+			{
+				Type: C.TypeDirect,
+				DirectOptions: option.DirectOutboundOptions{
+					DialerOptions: option.DialerOptions{
+						DomainStrategy: option.DomainStrategy(dns.DomainStrategyUseIPv4),
+					},
+				},
+			},
+		},
+		DNS: &option.DNSOptions{
+			Servers: []option.DNSServerOptions{
+				{
+					Address: F.ToString("udp://127.0.0.1:", serverPort),
+				},
+			},
+			ServeStale: true,
+		},
+	})
+	listener, err := listen("tcp", F.ToString("127.0.0.1:", testPort))
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("ok"))
+			conn.Close()
+		}
+	}()
+	dialer := socks.NewClient(N.SystemDialer, M.ParseSocksaddrHostPort("127.0.0.1", clientPort), socks.Version5, "", "")
+	dial := func() error {
+		conn, err := dialer.DialContext(context.Background(), "tcp", M.ParseSocksaddrHostPort("stale.example.com", testPort))
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		_, err = io.ReadFull(conn, make([]byte, 2))
+		return err
+	}
+	require.NoError(t, dial())
+	require.Equal(t, int32(1), atomic.LoadInt32(&queries))
+	time.Sleep(2 * time.Second)
+	failed.Store(true)
+	require.NoError(t, dial())
+}
+
+func TestDNSCacheClientSubnetRules(t *testing.T) {
+	packetConn, err := net.ListenPacket("udp", F.ToString("127.0.0.1:", serverPort))
+	require.NoError(t, err)
+	server := &mDNS.Server{
+		PacketConn: packetConn,
+		Handler: mDNS.HandlerFunc(func(writer mDNS.ResponseWriter, message *mDNS.Msg) {
+			response := new(mDNS.Msg)
+			response.SetReply(message)
+			address := net.IPv4zero
+			if opt := message.IsEdns0(); opt != nil {
+				for _, option := range opt.Option {
+					if subnet, isSubnet := option.(*mDNS.EDNS0_SUBNET); isSubnet {
+						address = subnet.Address
+					}
+				}
+			}
+			response.Answer = append(response.Answer, &mDNS.A{
+				Hdr: mDNS.RR_Header{Name: message.Question[0].Name, Rrtype: mDNS.TypeA, Class: mDNS.ClassINET, Ttl: 60},
+				A:   address,
+			})
+			writer.WriteMsg(response)
+		}),
+	}
+	go server.ActivateAndServe()
+	t.Cleanup(func() {
+		server.Shutdown()
+	})
+	startInstance(t, option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeDNS,
+				Tag:  "dns-a",
+				DNSOptions: option.DNSInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: clientPort,
+					},
+					Network: option.NetworkList(N.NetworkUDP),
+				},
+			},
+			{
+				Type: C.TypeDNS,
+				Tag:  "dns-b",
+				DNSOptions: option.DNSInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: testPort,
+					},
+					Network: option.NetworkList(N.NetworkUDP),
+				},
+			},
+		},
+		DNS: &option.DNSOptions{
+			Servers: []option.DNSServerOptions{
+				{
+					Tag:     "upstream",
+					Address: F.ToString("udp://127.0.0.1:", serverPort),
+				},
+			},
+			Rules: []option.DNSRule{
+				{
+					DefaultOptions: option.DefaultDNSRule{
+						Inbound:      []string{"dns-a"},
+						Server:       "upstream",
+						ClientSubnet: "1.0.1.0/24",
+					},
+				},
+				{
+					DefaultOptions: option.DefaultDNSRule{
+						Inbound:      []string{"dns-b"},
+						Server:       "upstream",
+						ClientSubnet: "1.0.2.0/24",
+					},
+				},
+			},
+			Prefetch: true,
+		},
+	})
+	for i := 0; i < 2; i++ {
+		for port, address := range map[uint16]string{clientPort: "1.0.1.0", testPort: "1.0.2.0"} {
+			message := new(mDNS.Msg)
+			message.SetQuestion("subnet.example.com.", mDNS.TypeA)
+			response, err := mDNS.Exchange(message, F.ToString("127.0.0.1:", port))
+			require.NoError(t, err)
+			require.Len(t, response.Answer, 1)
+			require.Equal(t, address, response.Answer[0].(*mDNS.A).A.String())
+		}
+	}
+}
+
+func TestDNSCacheFakeIP(t *testing.T) {
+	inet4Range := option.ListenPrefix(netip.MustParsePrefix("198.18.0.0/15"))
+	options := option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeDNS,
+				DNSOptions: option.DNSInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: clientPort,
+					},
+					Network: option.NetworkList(N.NetworkUDP),
+				},
+			},
+		},
+		DNS: &option.DNSOptions{
+			Servers: []option.DNSServerOptions{
+				{
+					Address: "fakeip",
+				},
+			},
+			FakeIP: &option.DNSFakeIPOptions{
+				Enabled:    true,
+				Inet4Range: &inet4Range,
+			},
+			ServeStale: true,
+		},
+		Experimental: &option.ExperimentalOptions{
+			ClashAPI: &option.ClashAPIOptions{
+				ExternalController: F.ToString("127.0.0.1:", otherPort),
+				StoreDNS:           true,
+				CacheFile:          filepath.Join(t.TempDir(), "cache.db"),
+			},
+		},
+	}
+	exchange := func(domain string) string {
+		message := new(mDNS.Msg)
+		message.SetQuestion(domain, mDNS.TypeA)
+		response, err := mDNS.Exchange(message, F.ToString("127.0.0.1:", clientPort))
+		require.NoError(t, err)
+		require.Len(t, response.Answer, 1)
+		return response.Answer[0].(*mDNS.A).A.String()
+	}
+	instance := startInstance(t, options)
+	address := exchange("stored.example.com.")
+	require.NoError(t, instance.Close())
+	// fake addresses are allocated again after restart, so a stored answer would point to another domain
+	startInstance(t, options)
+	require.Equal(t, address, exchange("other.example.com."))
+	require.NotEqual(t, address, exchange("stored.example.com."))
+}
+
+func TestDNSCacheIndependent(t *testing.T) {
+	for port, address := range map[uint16]net.IP{serverPort: net.IPv4(1, 1, 1, 1), otherPort: net.IPv4(2, 2, 2, 2)} {
+		packetConn, err := net.ListenPacket("udp", F.ToString("127.0.0.1:", port))
+		require.NoError(t, err)
+		address := address
+		server := &mDNS.Server{
+			PacketConn: packetConn,
+			Handler: mDNS.HandlerFunc(func(writer mDNS.ResponseWriter, message *mDNS.Msg) {
+				response := new(mDNS.Msg)
+				response.SetReply(message)
+				response.Answer = append(response.Answer, &mDNS.A{
+					Hdr: mDNS.RR_Header{Name: message.Question[0].Name, Rrtype: mDNS.TypeA, Class: mDNS.ClassINET, Ttl: 60},
+					A:   address,
+				})
+				writer.WriteMsg(response)
+			}),
+		}
+		go server.ActivateAndServe()
+		t.Cleanup(func() {
+			server.Shutdown()
+		})
+	}
+	startInstance(t, option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeDNS,
+				Tag:  "dns-a",
+				DNSOptions: option.DNSInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: clientPort,
+					},
+					Network: option.NetworkList(N.NetworkUDP),
+				},
+			},
+			{
+				Type: C.TypeDNS,
+				Tag:  "dns-b",
+				DNSOptions: option.DNSInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: testPort,
+					},
+					Network: option.NetworkList(N.NetworkUDP),
+				},
+			},
+		},
+		DNS: &option.DNSOptions{
+			Servers: []option.DNSServerOptions{
+				{
+					Tag:     "upstream-a",
+					Address: F.ToString("udp://127.0.0.1:", serverPort),
+				},
+				{
+					Tag:     "upstream-b",
+					Address: F.ToString("udp://127.0.0.1:", otherPort),
+				},
+			},
+			Rules: []option.DNSRule{
+				{
+					DefaultOptions: option.DefaultDNSRule{
+						Inbound: []string{"dns-b"},
+						Server:  "upstream-b",
+					},
+				},
+			},
+			Final: "upstream-a",
+			DNSClientOptions: option.DNSClientOptions{
+				IndependentCache: true,
+			},
+			ServeStale: true,
+		},
+	})
+	for i := 0; i < 2; i++ {
+		for port, address := range map[uint16]string{clientPort: "1.1.1.1", testPort: "2.2.2.2"} {
+			message := new(mDNS.Msg)
+			message.SetQuestion("independent.example.com.", mDNS.TypeA)
+			response, err := mDNS.Exchange(message, F.ToString("127.0.0.1:", port))
+			require.NoError(t, err)
+			require.Len(t, response.Answer, 1)
+			require.Equal(t, address, response.Answer[0].(*mDNS.A).A.String())
+		}
+	}
+}
+
+func TestDNSCacheStoreEvicted(t *testing.T) {
+	packetConn, err := net.ListenPacket("udp", F.ToString("127.0.0.1:", serverPort))
+	require.NoError(t, err)
+	server := &mDNS.Server{
+		PacketConn: packetConn,
+		Handler: mDNS.HandlerFunc(func(writer mDNS.ResponseWriter, message *mDNS.Msg) {
+			response := new(mDNS.Msg)
+			response.SetReply(message)
+			response.Answer = append(response.Answer, &mDNS.A{
+				Hdr: mDNS.RR_Header{Name: message.Question[0].Name, Rrtype: mDNS.TypeA, Class: mDNS.ClassINET, Ttl: 60},
+				A:   net.IPv4(1, 1, 1, 1),
+			})
+			writer.WriteMsg(response)
+		}),
+	}
+	go server.ActivateAndServe()
+	t.Cleanup(func() {
+		server.Shutdown()
+	})
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	instance := startInstance(t, option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeDNS,
+				DNSOptions: option.DNSInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: clientPort,
+					},
+					Network: option.NetworkList(N.NetworkUDP),
+				},
+			},
+		},
+		DNS: &option.DNSOptions{
+			Servers: []option.DNSServerOptions{
+				{
+					Address: F.ToString("udp://127.0.0.1:", serverPort),
+				},
+			},
+		},
+		Experimental: &option.ExperimentalOptions{
+			ClashAPI: &option.ClashAPIOptions{
+				ExternalController: F.ToString("127.0.0.1:", otherPort),
+				StoreDNS:           true,
+				CacheFile:          cachePath,
+			},
+		},
+	})
+	client := new(mDNS.Client)
+	conn, err := client.Dial(F.ToString("127.0.0.1:", clientPort))
+	require.NoError(t, err)
+	defer conn.Close()
+	const queries = 4200
+	for i := 0; i < queries; i++ {
+		message := new(mDNS.Msg)
+		message.SetQuestion(F.ToString("domain", i, ".example.com."), mDNS.TypeA)
+		_, _, err = client.ExchangeWithConn(message, conn)
+		require.NoError(t, err)
+	}
+	require.NoError(t, instance.Close())
+	cacheFile, err := cachefile.Open(cachePath, "")
+	require.NoError(t, err)
+	defer cacheFile.Close()
+	entries := cacheFile.DNSCacheEntries()
+	require.LessOrEqual(t, len(entries), 4096)
+	require.Contains(t, entries, F.ToString("domain", queries-1, ".example.com./", mDNS.TypeA, "/", mDNS.ClassINET))
+}
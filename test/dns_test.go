@@ -0,0 +1,439 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sagernet/quic-go"
+	"github.com/sagernet/quic-go/http3"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/task"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func newDNSTestResponse(message *mDNS.Msg) *mDNS.Msg {
+	response := new(mDNS.Msg)
+	response.SetReply(message)
+	response.Answer = append(response.Answer, &mDNS.A{
+		Hdr: mDNS.RR_Header{Name: message.Question[0].Name, Rrtype: mDNS.TypeA, Class: mDNS.ClassINET, Ttl: 60},
+		A:   net.IPv4(1, 0, 0, 1),
+	})
+	return response
+}
+
+func dnsOverHTTPSHandler(w http.ResponseWriter, r *http.Request) {
+	rawMessage, err := io.ReadAll(r.Body)
+	if err != nil {
+		return
+	}
+	var message mDNS.Msg
+	err = message.Unpack(rawMessage)
+	if err != nil || len(message.Question) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	rawMessage, err = newDNSTestResponse(&message).Pack()
+	if err != nil {
+		return
+	}
+	w.Header().Set("content-type", "application/dns-message")
+	w.Write(rawMessage)
+}
+
+func startDNSOverHTTPSServer(t *testing.T, certificate tls.Certificate) *int32 {
+	var connections int32
+	listener, err := net.Listen("tcp", F.ToString("127.0.0.1:", serverPort))
+	require.NoError(t, err)
+	server := &http.Server{
+		Handler: http.HandlerFunc(dnsOverHTTPSHandler),
+		ConnState: func(conn net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				atomic.AddInt32(&connections, 1)
+			}
+		},
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{certificate},
+		},
+	}
+	go server.ServeTLS(listener, "", "")
+	t.Cleanup(func() {
+		server.Close()
+	})
+	return &connections
+}
+
+func TestDNSOverHTTPSTuned(t *testing.T) {
+	_, certPem, keyPem := createSelfSignedCertificate(t, "example.org")
+	certificate, err := tls.LoadX509KeyPair(certPem, keyPem)
+	require.NoError(t, err)
+	certificateHash := sha256.Sum256(certificate.Certificate[0])
+	connections := startDNSOverHTTPSServer(t, certificate)
+	startInstance(t, option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeDNS,
+				DNSOptions: option.DNSInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: clientPort,
+					},
+					Network: option.NetworkList(N.NetworkUDP),
+				},
+			},
+		},
+		Outbounds: []option.Outbound{
+			{
+				Type: C.TypeDirect,
+				Tag:  "direct-out",
+			},
+		},
+		DNS: &option.DNSOptions{
+			Servers: []option.DNSServerOptions{
+				{
+					Tag:                  "doh",
+					Address:              F.ToString("https://127.0.0.1:", serverPort, "/dns-query"),
+					Detour:               "direct-out",
+					IdleTimeout:          option.Duration(time.Minute),
+					MaxConcurrentQueries: 1,
+					TLS: &option.DNSServerTLSOptions{
+						ServerName:        "example.org",
+						CertificateSHA256: []string{hex.EncodeToString(certificateHash[:])},
+					},
+				},
+			},
+			DNSClientOptions: option.DNSClientOptions{
+				DisableCache: true,
+			},
+		},
+	})
+	for i := 0; i < 3; i++ {
+		message := new(mDNS.Msg)
+		message.SetQuestion("example.com.", mDNS.TypeA)
+		response, err := mDNS.Exchange(message, F.ToString("127.0.0.1:", clientPort))
+		require.NoError(t, err)
+		require.Len(t, response.Answer, 1)
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(connections))
+}
+
+func TestDNSInbound(t *testing.T) {
+	largeAddresses := make([]string, 0, 64)
+	for i := 1; i <= 64; i++ {
+		largeAddresses = append(largeAddresses, F.ToString("10.0.0.", i))
+	}
+	startInstance(t, option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeDNS,
+				DNSOptions: option.DNSInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: clientPort,
+					},
+				},
+			},
+			{
+				Type: C.TypeDNS,
+				DNSOptions: option.DNSInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: serverPort,
+					},
+					HTTP: true,
+				},
+			},
+		},
+		DNS: &option.DNSOptions{
+			Hosts: &option.DNSHostsOptions{
+				Predefined: map[string]option.Listable[string]{
+					"example.com":       {"1.0.0.1"},
+					"large.example.com": largeAddresses,
+				},
+			},
+		},
+	})
+	exchangeHTTP := func(t *testing.T, method string, message *mDNS.Msg) *mDNS.Msg {
+		rawMessage, err := message.Pack()
+		require.NoError(t, err)
+		var request *http.Request
+		requestURL := F.ToString("http://127.0.0.1:", serverPort, "/dns-query")
+		if method == http.MethodGet {
+			request, err = http.NewRequest(method, requestURL+"?dns="+base64.RawURLEncoding.EncodeToString(rawMessage), nil)
+		} else {
+			request, err = http.NewRequest(method, requestURL, bytes.NewReader(rawMessage))
+			request.Header.Set("Content-Type", "application/dns-message")
+		}
+		require.NoError(t, err)
+		httpResponse, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		defer httpResponse.Body.Close()
+		require.Equal(t, http.StatusOK, httpResponse.StatusCode)
+		rawMessage, err = io.ReadAll(httpResponse.Body)
+		require.NoError(t, err)
+		var response mDNS.Msg
+		require.NoError(t, response.Unpack(rawMessage))
+		return &response
+	}
+	for _, network := range []string{"udp", "tcp", "doh-get", "doh-post"} {
+		t.Run(network, func(t *testing.T) {
+			for _, testCase := range []struct {
+				domain  string
+				answers int
+			}{
+				{"example.com.", 1},
+				{"large.example.com.", 64},
+			} {
+				message := new(mDNS.Msg)
+				message.SetQuestion(testCase.domain, mDNS.TypeA)
+				var response *mDNS.Msg
+				switch network {
+				case "udp", "tcp":
+					client := &mDNS.Client{Net: network}
+					var err error
+					response, _, err = client.Exchange(message, F.ToString("127.0.0.1:", clientPort))
+					require.NoError(t, err)
+				case "doh-get":
+					response = exchangeHTTP(t, http.MethodGet, message)
+				case "doh-post":
+					response = exchangeHTTP(t, http.MethodPost, message)
+				}
+				require.Equal(t, mDNS.RcodeSuccess, response.Rcode)
+				if network == "udp" && testCase.answers > 1 {
+					require.True(t, response.Truncated)
+					response.Compress = true
+					require.LessOrEqual(t, response.Len(), mDNS.MinMsgSize)
+				} else {
+					require.False(t, response.Truncated)
+					require.Len(t, response.Answer, testCase.answers)
+				}
+			}
+		})
+	}
+	t.Run("udp-edns0", func(t *testing.T) {
+		message := new(mDNS.Msg)
+		message.SetQuestion("large.example.com.", mDNS.TypeA)
+		message.SetEdns0(4096, false)
+		client := &mDNS.Client{UDPSize: 4096}
+		response, _, err := client.Exchange(message, F.ToString("127.0.0.1:", clientPort))
+		require.NoError(t, err)
+		require.False(t, response.Truncated)
+		require.Len(t, response.Answer, 64)
+	})
+}
+
+func TestDNSInboundTCPPipelining(t *testing.T) {
+	packetConn, err := net.ListenPacket("udp", F.ToString("127.0.0.1:", serverPort))
+	require.NoError(t, err)
+	server := &mDNS.Server{
+		PacketConn: packetConn,
+		Handler: mDNS.HandlerFunc(func(writer mDNS.ResponseWriter, message *mDNS.Msg) {
+			time.Sleep(200 * time.Millisecond)
+			writer.WriteMsg(newDNSTestResponse(message))
+		}),
+	}
+	go server.ActivateAndServe()
+	t.Cleanup(func() {
+		server.Shutdown()
+	})
+	startInstance(t, option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeDNS,
+				DNSOptions: option.DNSInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: clientPort,
+					},
+					Network: option.NetworkList(N.NetworkTCP),
+				},
+			},
+		},
+		DNS: &option.DNSOptions{
+			Servers: []option.DNSServerOptions{
+				{
+					Address: F.ToString("udp://127.0.0.1:", serverPort),
+				},
+			},
+			DNSClientOptions: option.DNSClientOptions{
+				DisableCache: true,
+			},
+		},
+	})
+	conn, err := net.Dial("tcp", F.ToString("127.0.0.1:", clientPort))
+	require.NoError(t, err)
+	defer conn.Close()
+	dnsConn := &mDNS.Conn{Conn: conn}
+	domains := []string{"a.example.com.", "b.example.com.", "c.example.com."}
+	for _, domain := range domains {
+		message := new(mDNS.Msg)
+		message.SetQuestion(domain, mDNS.TypeA)
+		require.NoError(t, dnsConn.WriteMsg(message))
+	}
+	require.NoError(t, conn.(*net.TCPConn).CloseWrite())
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	answered := make(map[string]bool)
+	for range domains {
+		response, err := dnsConn.ReadMsg()
+		require.NoError(t, err)
+		require.Equal(t, mDNS.RcodeSuccess, response.Rcode)
+		require.Len(t, response.Answer, 1)
+		answered[response.Question[0].Name] = true
+	}
+	require.Len(t, answered, len(domains))
+}
+
+func startDNSOverQUICServer(t *testing.T, certificate tls.Certificate) *int32 {
+	var connections int32
+	listener, err := quic.ListenAddrEarly(F.ToString("127.0.0.1:", serverPort), &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		NextProtos:   []string{"doq"},
+	}, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+	go func() {
+		for {
+			connection, err := listener.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&connections, 1)
+			go func() {
+				for {
+					stream, err := connection.AcceptStream(context.Background())
+					if err != nil {
+						return
+					}
+					go serveDNSOverQUICStream(stream)
+				}
+			}()
+		}
+	}()
+	return &connections
+}
+
+func serveDNSOverQUICStream(stream quic.Stream) {
+	defer stream.Close()
+	var length uint16
+	err := binary.Read(stream, binary.BigEndian, &length)
+	if err != nil {
+		return
+	}
+	rawMessage := make([]byte, length)
+	_, err = io.ReadFull(stream, rawMessage)
+	if err != nil {
+		return
+	}
+	var message mDNS.Msg
+	err = message.Unpack(rawMessage)
+	if err != nil || len(message.Question) == 0 {
+		return
+	}
+	rawMessage, err = newDNSTestResponse(&message).Pack()
+	if err != nil {
+		return
+	}
+	binary.Write(stream, binary.BigEndian, uint16(len(rawMessage)))
+	stream.Write(rawMessage)
+}
+
+func startDNSOverHTTP3Server(t *testing.T, certificate tls.Certificate) {
+	packetConn, err := net.ListenPacket("udp", F.ToString("127.0.0.1:", serverPort))
+	require.NoError(t, err)
+	server := &http3.Server{
+		Handler: http.HandlerFunc(dnsOverHTTPSHandler),
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{certificate},
+		},
+	}
+	go server.Serve(packetConn)
+	t.Cleanup(func() {
+		server.Close()
+		packetConn.Close()
+	})
+}
+
+func testDNSOverQUIC(t *testing.T, address string, certificate tls.Certificate) {
+	certificateHash := sha256.Sum256(certificate.Certificate[0])
+	startInstance(t, option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeDNS,
+				DNSOptions: option.DNSInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: clientPort,
+					},
+					Network: option.NetworkList(N.NetworkUDP),
+				},
+			},
+		},
+		DNS: &option.DNSOptions{
+			Servers: []option.DNSServerOptions{
+				{
+					Tag:     "upstream",
+					Address: address,
+					TLS: &option.DNSServerTLSOptions{
+						ServerName:        "example.org",
+						CertificateSHA256: []string{hex.EncodeToString(certificateHash[:])},
+					},
+				},
+			},
+			DNSClientOptions: option.DNSClientOptions{
+				DisableCache: true,
+			},
+		},
+	})
+	var group task.Group
+	for i := 0; i < 8; i++ {
+		group.Append0(func(ctx context.Context) error {
+			message := new(mDNS.Msg)
+			message.SetQuestion("example.com.", mDNS.TypeA)
+			response, err := mDNS.Exchange(message, F.ToString("127.0.0.1:", clientPort))
+			if err != nil {
+				return err
+			}
+			if len(response.Answer) != 1 {
+				return E.New("unexpected answers: ", len(response.Answer))
+			}
+			return nil
+		})
+	}
+	require.NoError(t, group.Run(context.Background()))
+}
+
+func TestDNSOverQUIC(t *testing.T) {
+	_, certPem, keyPem := createSelfSignedCertificate(t, "example.org")
+	certificate, err := tls.LoadX509KeyPair(certPem, keyPem)
+	require.NoError(t, err)
+	connections := startDNSOverQUICServer(t, certificate)
+	testDNSOverQUIC(t, F.ToString("quic://127.0.0.1:", serverPort), certificate)
+	require.Equal(t, int32(1), atomic.LoadInt32(connections))
+}
+
+func TestDNSOverHTTP3(t *testing.T) {
+	_, certPem, keyPem := createSelfSignedCertificate(t, "example.org")
+	certificate, err := tls.LoadX509KeyPair(certPem, keyPem)
+	require.NoError(t, err)
+	startDNSOverHTTP3Server(t, certificate)
+	testDNSOverQUIC(t, F.ToString("h3://127.0.0.1:", serverPort, "/dns-query"), certificate)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	F "github.com/sagernet/sing/common/format"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeIPExclude(t *testing.T) {
+	packetConn, err := net.ListenPacket("udp", F.ToString("127.0.0.1:", serverPort))
+	require.NoError(t, err)
+	server := &mDNS.Server{
+		PacketConn: packetConn,
+		Handler: mDNS.HandlerFunc(func(writer mDNS.ResponseWriter, message *mDNS.Msg) {
+			response := new(mDNS.Msg)
+			response.SetReply(message)
+			response.Answer = append(response.Answer, &mDNS.A{
+				Hdr: mDNS.RR_Header{Name: message.Question[0].Name, Rrtype: mDNS.TypeA, Class: mDNS.ClassINET, Ttl: 60},
+				A:   net.IPv4(1, 1, 1, 1),
+			})
+			writer.WriteMsg(response)
+		}),
+	}
+	go server.ActivateAndServe()
+	t.Cleanup(func() {
+		server.Shutdown()
+	})
+	inet4Range := option.ListenPrefix(netip.MustParsePrefix("198.18.0.0/15"))
+	startInstance(t, option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeDNS,
+				DNSOptions: option.DNSInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: clientPort,
+					},
+					Network: option.NetworkList(N.NetworkUDP),
+				},
+			},
+		},
+		DNS: &option.DNSOptions{
+			Servers: []option.DNSServerOptions{
+				{
+					Tag:     "upstream",
+					Address: F.ToString("udp://127.0.0.1:", serverPort),
+				},
+				{
+					Tag:     "fakeip",
+					Address: "fakeip",
+				},
+			},
+			Rules: []option.DNSRule{
+				{
+					DefaultOptions: option.DefaultDNSRule{
+						DomainSuffix: []string{"example.com"},
+						Server:       "fakeip",
+					},
+				},
+			},
+			Final: "upstream",
+			FakeIP: &option.DNSFakeIPOptions{
+				Enabled:              true,
+				Inet4Range:           &inet4Range,
+				ExcludeDomainKeyword: []string{"NTP"},
+			},
+		},
+	})
+	for domain, excluded := range map[string]bool{"www.example.com.": false, "time.ntp.example.com.": true} {
+		message := new(mDNS.Msg)
+		message.SetQuestion(domain, mDNS.TypeA)
+		response, err := mDNS.Exchange(message, F.ToString("127.0.0.1:", clientPort))
+		require.NoError(t, err)
+		require.Len(t, response.Answer, 1)
+		address := response.Answer[0].(*mDNS.A).A
+		if excluded {
+			require.Equal(t, "1.1.1.1", address.String())
+		} else {
+			require.True(t, netip.Prefix(inet4Range).Contains(M.AddrFromIP(address)), address.String())
+		}
+	}
+}
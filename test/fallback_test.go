@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	F "github.com/sagernet/sing/common/format"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func startFallbackServer(t *testing.T, name string) *option.ServerOptions {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.Read(make([]byte, 1024))
+				conn.Write([]byte(name))
+			}()
+		}
+	}()
+	return &option.ServerOptions{
+		Server:     "127.0.0.1",
+		ServerPort: uint16(listener.Addr().(*net.TCPAddr).Port),
+	}
+}
+
+func requestFallback(t *testing.T, path string, tlsConfig *tls.Config) string {
+	conn, err := net.Dial("tcp", F.ToString("127.0.0.1:", serverPort))
+	require.NoError(t, err)
+	defer conn.Close()
+	if tlsConfig != nil {
+		conn = tls.Client(conn, tlsConfig)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Write([]byte("GET " + path + " HTTP/1.1\r\nHost: example.org\r\n\r\n"))
+	require.NoError(t, err)
+	response, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	return string(response)
+}
+
+func startFallbackInstance(t *testing.T, inboundType string, inboundTLS *option.InboundTLSOptions, fallbackOptions option.InboundFallbackOptions) {
+	user, err := uuid.NewV4()
+	require.NoError(t, err)
+	listenOptions := option.ListenOptions{
+		Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+		ListenPort: serverPort,
+	}
+	inbound := option.Inbound{
+		Type: inboundType,
+	}
+	switch inboundType {
+	case C.TypeVLESS:
+		inbound.VLESSOptions = option.VLESSInboundOptions{
+			ListenOptions:          listenOptions,
+			Users:                  []option.VLESSUser{{Name: "sekai", UUID: user.String()}},
+			TLS:                    inboundTLS,
+			InboundFallbackOptions: fallbackOptions,
+		}
+	case C.TypeVMess:
+		inbound.VMessOptions = option.VMessInboundOptions{
+			ListenOptions:          listenOptions,
+			Users:                  []option.VMessUser{{Name: "sekai", UUID: user.String()}},
+			TLS:                    inboundTLS,
+			InboundFallbackOptions: fallbackOptions,
+		}
+	}
+	startInstance(t, option.Options{
+		Inbounds: []option.Inbound{inbound},
+		Outbounds: []option.Outbound{
+			{
+				Type: C.TypeDirect,
+			},
+		},
+	})
+}
+
+func TestFallback(t *testing.T) {
+	for _, inboundType := range []string{C.TypeVLESS, C.TypeVMess} {
+		t.Run(inboundType, func(t *testing.T) {
+			t.Run("plain", func(t *testing.T) {
+				startFallbackInstance(t, inboundType, nil, option.InboundFallbackOptions{
+					Fallback: startFallbackServer(t, "default"),
+					FallbackForPath: map[string]*option.ServerOptions{
+						"/fallback": startFallbackServer(t, "path"),
+					},
+				})
+				require.Equal(t, "default", requestFallback(t, "/", nil))
+				require.Equal(t, "path", requestFallback(t, "/fallback/index.html", nil))
+				// a request line never completed must not hold the connection
+				conn, err := net.Dial("tcp", F.ToString("127.0.0.1:", serverPort))
+				require.NoError(t, err)
+				defer conn.Close()
+				conn.SetDeadline(time.Now().Add(5 * time.Second))
+				_, err = conn.Write([]byte("GET /fallback/index.html HTTP/1.1 " + strings.Repeat("a", 64)))
+				require.NoError(t, err)
+				response, err := io.ReadAll(conn)
+				require.NoError(t, err)
+				require.Equal(t, "default", string(response))
+			})
+			t.Run("tls", func(t *testing.T) {
+				_, certPem, keyPem := createSelfSignedCertificate(t, "example.org")
+				startFallbackInstance(t, inboundType, &option.InboundTLSOptions{
+					Enabled:         true,
+					ServerName:      "example.org",
+					ALPN:            []string{"h2", "http/1.1"},
+					CertificatePath: certPem,
+					KeyPath:         keyPem,
+				}, option.InboundFallbackOptions{
+					Fallback: startFallbackServer(t, "default"),
+					FallbackForServerName: map[string]*option.ServerOptions{
+						"fallback.example.org": startFallbackServer(t, "sni"),
+					},
+					FallbackForALPN: map[string]*option.ServerOptions{
+						"h2":       startFallbackServer(t, "alpn"),
+						"http/1.1": startFallbackServer(t, "default"),
+					},
+				})
+				require.Equal(t, "default", requestFallback(t, "/", &tls.Config{
+					ServerName:         "example.org",
+					InsecureSkipVerify: true,
+				}))
+				require.Equal(t, "sni", requestFallback(t, "/", &tls.Config{
+					ServerName:         "fallback.example.org",
+					NextProtos:         []string{"h2"},
+					InsecureSkipVerify: true,
+				}))
+				require.Equal(t, "alpn", requestFallback(t, "/", &tls.Config{
+					ServerName:         "example.org",
+					NextProtos:         []string{"h2"},
+					InsecureSkipVerify: true,
+				}))
+			})
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/hysteria2"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHysteria2Self(t *testing.T) {
+	t.Run("self", func(t *testing.T) {
+		testHysteria2Self(t, 100, 100, false)
+	})
+	t.Run("self-bbr", func(t *testing.T) {
+		testHysteria2Self(t, 0, 0, false)
+	})
+	t.Run("self-salamander", func(t *testing.T) {
+		testHysteria2Self(t, 100, 100, true)
+	})
+}
+
+func TestHysteria2DuplicatePassword(t *testing.T) {
+	_, certPem, keyPem := createSelfSignedCertificate(t, "example.org")
+	_, err := box.New(box.Options{
+		Context: context.Background(),
+		Options: option.Options{
+			Inbounds: []option.Inbound{
+				{
+					Type: C.TypeHysteria2,
+					Hysteria2Options: option.Hysteria2InboundOptions{
+						ListenOptions: option.ListenOptions{
+							Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+							ListenPort: serverPort,
+						},
+						Users: []option.Hysteria2User{
+							{Name: "alice", Password: "password"},
+							{Name: "bob", Password: "password"},
+						},
+						TLS: &option.InboundTLSOptions{
+							Enabled:         true,
+							ServerName:      "example.org",
+							CertificatePath: certPem,
+							KeyPath:         keyPem,
+						},
+					},
+				},
+			},
+		},
+	})
+	require.ErrorIs(t, err, hysteria2.ErrUserExists)
+}
+
+func testHysteria2Self(t *testing.T, upMbps int, downMbps int, salamander bool) {
+	_, certPem, keyPem := createSelfSignedCertificate(t, "example.org")
+	var obfs *option.Hysteria2Obfs
+	if salamander {
+		obfs = &option.Hysteria2Obfs{
+			Type:     "salamander",
+			Password: "cry_me_a_r1ver",
+		}
+	}
+	startInstance(t, option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeMixed,
+				Tag:  "mixed-in",
+				MixedOptions: option.HTTPMixedInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: clientPort,
+					},
+				},
+			},
+			{
+				Type: C.TypeHysteria2,
+				Hysteria2Options: option.Hysteria2InboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: serverPort,
+					},
+					UpMbps:   upMbps,
+					DownMbps: downMbps,
+					Obfs:     obfs,
+					Users: []option.Hysteria2User{{
+						Password: "password",
+					}},
+					TLS: &option.InboundTLSOptions{
+						Enabled:         true,
+						ServerName:      "example.org",
+						CertificatePath: certPem,
+						KeyPath:         keyPem,
+					},
+				},
+			},
+		},
+		Outbounds: []option.Outbound{
+			{
+				Type: C.TypeDirect,
+			},
+			{
+				Type: C.TypeHysteria2,
+				Tag:  "hy2-out",
+				Hysteria2Options: option.Hysteria2OutboundOptions{
+					ServerOptions: option.ServerOptions{
+						Server:     "127.0.0.1",
+						ServerPort: serverPort,
+					},
+					UpMbps:   upMbps,
+					DownMbps: downMbps,
+					Obfs:     obfs,
+					Password: "password",
+					TLS: &option.OutboundTLSOptions{
+						Enabled:         true,
+						ServerName:      "example.org",
+						CertificatePath: certPem,
+					},
+				},
+			},
+		},
+		Route: &option.RouteOptions{
+			Rules: []option.Rule{
+				{
+					DefaultOptions: option.DefaultRule{
+						Inbound:  []string{"mixed-in"},
+						Outbound: "hy2-out",
+					},
+				},
+			},
+		},
+	})
+	testSuit(t, clientPort, testPort)
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	F "github.com/sagernet/sing/common/format"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func requestInboundUsers(t *testing.T, method string, path string, body []byte) (int, []option.ShadowsocksUser) {
+	request, err := http.NewRequest(method, F.ToString("http://127.0.0.1:", otherPort, "/inbounds/", path), bytes.NewReader(body))
+	require.NoError(t, err)
+	request.Close = true
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	var content struct {
+		Users []option.ShadowsocksUser `json:"users"`
+	}
+	if response.StatusCode == http.StatusOK {
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&content))
+	}
+	return response.StatusCode, content.Users
+}
+
+func requireUserRejected(t *testing.T) {
+	listener, err := net.Listen("tcp", F.ToString("127.0.0.1:", testPort))
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(conn, conn)
+	}()
+	requireMuxRejected(t, dialSocksTCP(clientPort, testPort))
+}
+
+func TestInboundUsersAPI(t *testing.T) {
+	method := "2022-blake3-aes-128-gcm"
+	password := mkBase64(t, 16)
+	userPassword := mkBase64(t, 16)
+	startInstance(t, option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeMixed,
+				Tag:  "mixed-in",
+				MixedOptions: option.HTTPMixedInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: clientPort,
+					},
+				},
+			},
+			{
+				Type: C.TypeShadowsocks,
+				Tag:  "ss-in",
+				ShadowsocksOptions: option.ShadowsocksInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: serverPort,
+					},
+					Method:   method,
+					Password: password,
+					Users: []option.ShadowsocksUser{
+						{
+							Name:     "sekai",
+							Password: mkBase64(t, 16),
+						},
+					},
+				},
+			},
+		},
+		Outbounds: []option.Outbound{
+			{
+				Type: C.TypeDirect,
+			},
+			{
+				Type: C.TypeShadowsocks,
+				Tag:  "ss-out",
+				ShadowsocksOptions: option.ShadowsocksOutboundOptions{
+					ServerOptions: option.ServerOptions{
+						Server:     "127.0.0.1",
+						ServerPort: serverPort,
+					},
+					Method:   method,
+					Password: password + ":" + userPassword,
+				},
+			},
+		},
+		Route: &option.RouteOptions{
+			Rules: []option.Rule{
+				{
+					DefaultOptions: option.DefaultRule{
+						Inbound:  []string{"mixed-in"},
+						Outbound: "ss-out",
+					},
+				},
+			},
+		},
+		Experimental: &option.ExperimentalOptions{
+			ClashAPI: &option.ClashAPIOptions{
+				ExternalController: F.ToString("127.0.0.1:", otherPort),
+			},
+		},
+	})
+	statusCode, users := requestInboundUsers(t, http.MethodGet, "ss-in/users", nil)
+	require.Equal(t, http.StatusOK, statusCode)
+	require.Len(t, users, 1)
+	require.Equal(t, "sekai", users[0].Name)
+	statusCode, _ = requestInboundUsers(t, http.MethodGet, "not-found/users", nil)
+	require.Equal(t, http.StatusNotFound, statusCode)
+	statusCode, _ = requestInboundUsers(t, http.MethodGet, "mixed-in/users", nil)
+	require.Equal(t, http.StatusBadRequest, statusCode)
+	requireUserRejected(t)
+
+	newUsers, err := json.Marshal([]option.ShadowsocksUser{{Name: "new", Password: userPassword}})
+	require.NoError(t, err)
+	statusCode, _ = requestInboundUsers(t, http.MethodPost, "ss-in/users", newUsers)
+	require.Equal(t, http.StatusNoContent, statusCode)
+	statusCode, _ = requestInboundUsers(t, http.MethodPost, "ss-in/users", []byte(`[{"password":"invalid"}]`))
+	require.Equal(t, http.StatusBadRequest, statusCode)
+	statusCode, users = requestInboundUsers(t, http.MethodGet, "ss-in/users", nil)
+	require.Equal(t, http.StatusOK, statusCode)
+	require.Len(t, users, 2)
+	testTCP(t, clientPort, testPort)
+
+	statusCode, _ = requestInboundUsers(t, http.MethodDelete, "ss-in/users/new", nil)
+	require.Equal(t, http.StatusNoContent, statusCode)
+	statusCode, _ = requestInboundUsers(t, http.MethodDelete, "ss-in/users/new", nil)
+	require.Equal(t, http.StatusNotFound, statusCode)
+	requireUserRejected(t)
+}
+
+func TestInboundUsersUpdateWhileConnecting(t *testing.T) {
+	userID, err := uuid.DefaultGenerator.NewV4()
+	require.NoError(t, err)
+	startInstance(t, option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeMixed,
+				Tag:  "mixed-in",
+				MixedOptions: option.HTTPMixedInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: clientPort,
+					},
+				},
+			},
+			{
+				Type: C.TypeVMess,
+				Tag:  "vmess-in",
+				VMessOptions: option.VMessInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: serverPort,
+					},
+					Users: []option.VMessUser{
+						{
+							Name:    "sekai",
+							UUID:    userID.String(),
+							AlterId: 1,
+						},
+					},
+				},
+			},
+		},
+		Outbounds: []option.Outbound{
+			{
+				Type: C.TypeDirect,
+			},
+			{
+				Type: C.TypeVMess,
+				Tag:  "vmess-out",
+				VMessOptions: option.VMessOutboundOptions{
+					ServerOptions: option.ServerOptions{
+						Server:     "127.0.0.1",
+						ServerPort: serverPort,
+					},
+					UUID:     userID.String(),
+					Security: "aes-128-gcm",
+					AlterId:  1,
+				},
+			},
+		},
+		Route: &option.RouteOptions{
+			Rules: []option.Rule{
+				{
+					DefaultOptions: option.DefaultRule{
+						Inbound:  []string{"mixed-in"},
+						Outbound: "vmess-out",
+					},
+				},
+			},
+		},
+		Experimental: &option.ExperimentalOptions{
+			ClashAPI: &option.ClashAPIOptions{
+				ExternalController: F.ToString("127.0.0.1:", otherPort),
+			},
+		},
+	})
+	updateDone := make(chan struct{})
+	connectDone := make(chan error, 1)
+	go func() {
+		for {
+			select {
+			case <-updateDone:
+				connectDone <- nil
+				return
+			default:
+			}
+			err := testPingPongWithConn(t, testPort, dialSocksTCP(clientPort, testPort))
+			if err != nil {
+				connectDone <- err
+				return
+			}
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		otherID, err := uuid.DefaultGenerator.NewV4()
+		require.NoError(t, err)
+		newUsers, err := json.Marshal([]option.VMessUser{{Name: F.ToString("user", i), UUID: otherID.String(), AlterId: 1}})
+		require.NoError(t, err)
+		statusCode, _ := requestInboundUsers(t, http.MethodPost, "vmess-in/users", newUsers)
+		require.Equal(t, http.StatusNoContent, statusCode)
+	}
+	close(updateDone)
+	require.NoError(t, <-connectDone)
+}
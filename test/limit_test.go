@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"net/netip"
+	"path/filepath"
+	"testing"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/auth"
+	F "github.com/sagernet/sing/common/format"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/protocol/socks"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitStoreQuota(t *testing.T) {
+	listener, err := listen("tcp", F.ToString("127.0.0.1:", testPort))
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	options := option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeSocks,
+				Tag:  "socks-in",
+				SocksOptions: option.SocksInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: clientPort,
+						InboundOptions: option.InboundOptions{
+							Limit: &option.InboundLimitOptions{
+								Users: []option.UserLimitOptions{
+									{
+										Name:               []string{"sekai"},
+										QuotaMB:            1,
+										QuotaResetInterval: option.Duration(time.Hour),
+									},
+								},
+							},
+						},
+					},
+					Users: []auth.User{{Username: "sekai", Password: "password"}},
+				},
+			},
+		},
+		Experimental: &option.ExperimentalOptions{
+			ClashAPI: &option.ClashAPIOptions{
+				ExternalController: F.ToString("127.0.0.1:", otherPort),
+				StoreLimit:         true,
+				CacheFile:          filepath.Join(t.TempDir(), "cache.db"),
+			},
+		},
+	}
+	dialer := socks.NewClient(N.SystemDialer, M.ParseSocksaddrHostPort("127.0.0.1", clientPort), socks.Version5, "sekai", "password")
+	echo := func(size int) error {
+		conn, err := dialer.DialContext(context.Background(), "tcp", M.ParseSocksaddrHostPort("127.0.0.1", testPort))
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		payload := make([]byte, size)
+		rand.Read(payload)
+		go conn.Write(payload)
+		response := make([]byte, size)
+		_, err = io.ReadFull(conn, response)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(payload, response) {
+			return io.ErrUnexpectedEOF
+		}
+		return nil
+	}
+	instance := startInstance(t, options)
+	// uplink and downlink are both accounted
+	require.NoError(t, echo(400*1024))
+	require.NoError(t, instance.Close())
+	startInstance(t, options)
+	require.Error(t, echo(200*1024))
+	require.Error(t, echo(4))
+}
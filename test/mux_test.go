@@ -1,14 +1,25 @@
 package main
 
 import (
+	"context"
+	"io"
+	"net"
 	"net/netip"
 	"testing"
+	"time"
 
+	"github.com/sagernet/sing-box"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-shadowsocks/shadowaead_2022"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/protocol/socks"
 
 	"github.com/gofrs/uuid/v5"
+	"github.com/stretchr/testify/require"
 )
 
 var muxProtocols = []string{
@@ -52,6 +63,17 @@ func TestShadowsockSMuxPadding(t *testing.T) {
 }
 
 func testShadowsocksMux(t *testing.T, options option.MultiplexOptions) {
+	startShadowsocksMux(t, options, nil)
+	testSuit(t, clientPort, testPort)
+}
+
+func startShadowsocksMux(t *testing.T, options option.MultiplexOptions, inboundOptions *option.InboundMultiplexOptions) {
+	startShadowsocksMuxWithInbound(t, options, option.InboundOptions{
+		Multiplex: inboundOptions,
+	})
+}
+
+func startShadowsocksMuxWithInbound(t *testing.T, options option.MultiplexOptions, inboundOptions option.InboundOptions) {
 	method := shadowaead_2022.List[0]
 	password := mkBase64(t, 16)
 	startInstance(t, option.Options{
@@ -70,8 +92,9 @@ func testShadowsocksMux(t *testing.T, options option.MultiplexOptions) {
 				Type: C.TypeShadowsocks,
 				ShadowsocksOptions: option.ShadowsocksInboundOptions{
 					ListenOptions: option.ListenOptions{
-						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
-						ListenPort: serverPort,
+						Listen:         option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort:     serverPort,
+						InboundOptions: inboundOptions,
 					},
 					Method:   method,
 					Password: password,
@@ -107,7 +130,6 @@ func testShadowsocksMux(t *testing.T, options option.MultiplexOptions) {
 			},
 		},
 	})
-	testSuit(t, clientPort, testPort)
 }
 
 func testVMessMux(t *testing.T, options option.MultiplexOptions) {
@@ -170,3 +192,215 @@ func testVMessMux(t *testing.T, options option.MultiplexOptions) {
 	})
 	testSuit(t, clientPort, testPort)
 }
+
+func TestShadowsocksMuxServerOptions(t *testing.T) {
+	startShadowsocksMux(t, option.MultiplexOptions{
+		Enabled:  true,
+		Protocol: "smux",
+		Padding:  true,
+	}, &option.InboundMultiplexOptions{
+		Protocol:    []string{"smux", "yamux"},
+		MaxStreams:  16,
+		Padding:     true,
+		IdleTimeout: option.Duration(time.Second),
+	})
+	testSuit(t, clientPort, testPort)
+	time.Sleep(2 * time.Second)
+	require.NoError(t, testPingPongWithConn(t, testPort, dialSocksTCP(clientPort, testPort)))
+}
+
+func TestShadowsocksMuxServerReject(t *testing.T) {
+	testCases := []struct {
+		name           string
+		options        option.MultiplexOptions
+		inboundOptions option.InboundMultiplexOptions
+	}{
+		{
+			name: "protocol",
+			options: option.MultiplexOptions{
+				Enabled:  true,
+				Protocol: "yamux",
+			},
+			inboundOptions: option.InboundMultiplexOptions{
+				Protocol: []string{"smux"},
+			},
+		},
+		{
+			name: "padding",
+			options: option.MultiplexOptions{
+				Enabled:  true,
+				Protocol: "smux",
+			},
+			inboundOptions: option.InboundMultiplexOptions{
+				Padding: true,
+			},
+		},
+		{
+			name: "brutal",
+			options: option.MultiplexOptions{
+				Enabled:  true,
+				Protocol: "smux",
+				Brutal: &option.BrutalOptions{
+					Enabled:  true,
+					UpMbps:   100,
+					DownMbps: 100,
+				},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			startShadowsocksMux(t, testCase.options, &testCase.inboundOptions)
+			requireMuxRejected(t, dialSocksTCP(clientPort, testPort))
+		})
+	}
+}
+
+func TestShadowsocksMuxServerInvalidOptions(t *testing.T) {
+	testCases := []struct {
+		name           string
+		inboundOptions option.InboundMultiplexOptions
+	}{
+		{
+			name: "protocol",
+			inboundOptions: option.InboundMultiplexOptions{
+				Protocol: []string{"quic"},
+			},
+		},
+		{
+			name: "brutal",
+			inboundOptions: option.InboundMultiplexOptions{
+				Brutal: &option.BrutalOptions{
+					Enabled: true,
+				},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := box.New(box.Options{
+				Context: context.Background(),
+				Options: option.Options{
+					Inbounds: []option.Inbound{
+						{
+							Type: C.TypeShadowsocks,
+							ShadowsocksOptions: option.ShadowsocksInboundOptions{
+								ListenOptions: option.ListenOptions{
+									Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+									ListenPort: serverPort,
+									InboundOptions: option.InboundOptions{
+										Multiplex: &testCase.inboundOptions,
+									},
+								},
+								Method:   shadowaead_2022.List[0],
+								Password: mkBase64(t, 16),
+							},
+						},
+					},
+				},
+			})
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestShadowsocksMuxServerMaxStreams(t *testing.T) {
+	startShadowsocksMux(t, option.MultiplexOptions{
+		Enabled:        true,
+		Protocol:       "smux",
+		MaxConnections: 1,
+	}, &option.InboundMultiplexOptions{
+		MaxStreams: 1,
+	})
+	listener, err := listen("tcp", ":"+F.ToString(testPort))
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	dialTCP := dialSocksTCP(clientPort, testPort)
+	conn, err := dialTCP()
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	response := make([]byte, 4)
+	_, err = io.ReadFull(conn, response)
+	require.NoError(t, err)
+	requireMuxRejected(t, dialTCP)
+	conn.Close()
+	listener.Close()
+	time.Sleep(time.Second)
+	require.NoError(t, testPingPongWithConn(t, testPort, dialTCP))
+}
+
+func TestShadowsocksMuxLimit(t *testing.T) {
+	startShadowsocksMuxWithInbound(t, option.MultiplexOptions{
+		Enabled:        true,
+		Protocol:       "smux",
+		MaxConnections: 1,
+	}, option.InboundOptions{
+		Limit: &option.InboundLimitOptions{
+			MaxConnections: 1,
+		},
+	})
+	listener, err := listen("tcp", ":"+F.ToString(testPort))
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	dialTCP := dialSocksTCP(clientPort, testPort)
+	// streams of the multiplexed connection share its slot
+	for i := 0; i < 2; i++ {
+		conn, err := dialTCP()
+		require.NoError(t, err)
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		_, err = conn.Write([]byte("ping"))
+		require.NoError(t, err)
+		_, err = io.ReadFull(conn, make([]byte, 4))
+		require.NoError(t, err)
+	}
+}
+
+func requireMuxRejected(t *testing.T, dialTCP func() (net.Conn, error)) {
+	conn, err := dialTCP()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Write([]byte("ping"))
+	if err != nil {
+		return
+	}
+	_, err = io.ReadFull(conn, make([]byte, 4))
+	require.Error(t, err)
+	require.False(t, E.IsTimeout(err), "stream not rejected")
+}
+
+func dialSocksTCP(clientPort uint16, testPort uint16) func() (net.Conn, error) {
+	dialer := socks.NewClient(N.SystemDialer, M.ParseSocksaddrHostPort("127.0.0.1", clientPort), socks.Version5, "", "")
+	return func() (net.Conn, error) {
+		return dialer.DialContext(context.Background(), "tcp", M.ParseSocksaddrHostPort("127.0.0.1", testPort))
+	}
+}
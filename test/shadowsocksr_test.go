@@ -46,3 +46,84 @@ func TestShadowsocksR(t *testing.T) {
 	})
 	testSuit(t, clientPort, testPort)
 }
+
+func TestShadowsocksRSelf(t *testing.T) {
+	for _, obfs := range []string{"plain", "http_simple", "tls1.2_ticket_auth"} {
+		for _, protocol := range []string{"origin", "auth_aes128_md5", "auth_aes128_sha1", "auth_chain_a"} {
+			t.Run(obfs+"-"+protocol, func(t *testing.T) {
+				testShadowsocksRSelf(t, obfs, protocol, nil, "")
+			})
+		}
+	}
+	users := []option.ShadowsocksRUser{
+		{Name: "user1", ID: 1, Password: "password1"},
+		{Name: "user2", ID: 2, Password: "password2"},
+	}
+	for _, protocol := range []string{"auth_aes128_md5", "auth_aes128_sha1", "auth_chain_a"} {
+		t.Run("multi-user-"+protocol, func(t *testing.T) {
+			testShadowsocksRSelf(t, "tls1.2_ticket_auth", protocol, users, "2:password2")
+		})
+	}
+}
+
+func testShadowsocksRSelf(t *testing.T, obfs string, protocol string, users []option.ShadowsocksRUser, protocolParam string) {
+	startInstance(t, option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeMixed,
+				Tag:  "mixed-in",
+				MixedOptions: option.HTTPMixedInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: clientPort,
+					},
+				},
+			},
+			{
+				Type: C.TypeShadowsocksR,
+				ShadowsocksROptions: option.ShadowsocksRInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: serverPort,
+					},
+					Method:   "aes-256-cfb",
+					Password: "password0",
+					Obfs:     obfs,
+					Protocol: protocol,
+					Users:    users,
+				},
+			},
+		},
+		Outbounds: []option.Outbound{
+			{
+				Type: C.TypeDirect,
+			},
+			{
+				Type: C.TypeShadowsocksR,
+				Tag:  "ssr-out",
+				ShadowsocksROptions: option.ShadowsocksROutboundOptions{
+					ServerOptions: option.ServerOptions{
+						Server:     "127.0.0.1",
+						ServerPort: serverPort,
+					},
+					Method:        "aes-256-cfb",
+					Password:      "password0",
+					Obfs:          obfs,
+					Protocol:      protocol,
+					ProtocolParam: protocolParam,
+				},
+			},
+		},
+		Route: &option.RouteOptions{
+			Rules: []option.Rule{
+				{
+					DefaultOptions: option.DefaultRule{
+						Inbound:  []string{"mixed-in"},
+						Outbound: "ssr-out",
+					},
+				},
+			},
+		},
+	})
+	testTCP(t, clientPort, testPort)
+}
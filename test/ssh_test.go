@@ -0,0 +1,148 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSHSelf(t *testing.T) {
+	hostKey, hostPublicKey := mkSSHKey(t)
+	userKey, userPublicKey := mkSSHKey(t)
+	users := []option.SSHUser{
+		{Name: "password-user", Password: "password"},
+		{Name: "key-user", AuthorizedKeys: []string{userPublicKey}},
+	}
+	t.Run("password", func(t *testing.T) {
+		testSSHSelf(t, hostKey, hostPublicKey, users, option.SSHOutboundOptions{
+			User:     "password-user",
+			Password: "password",
+		})
+	})
+	t.Run("public-key", func(t *testing.T) {
+		testSSHSelf(t, hostKey, hostPublicKey, users, option.SSHOutboundOptions{
+			User:       "key-user",
+			PrivateKey: userKey,
+		})
+	})
+}
+
+func TestSSHInboundHandshakeLimits(t *testing.T) {
+	hostKey, _ := mkSSHKey(t)
+	startInstance(t, option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeSSH,
+				SSHOptions: option.SSHInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: serverPort,
+					},
+					Users:   []option.SSHUser{{Name: "sekai", Password: "password"}},
+					HostKey: []string{hostKey},
+				},
+			},
+		},
+	})
+	t.Run("timeout", func(t *testing.T) {
+		conn, err := net.Dial("tcp", netip.AddrPortFrom(netip.IPv4Unspecified(), serverPort).String())
+		require.NoError(t, err)
+		defer conn.Close()
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(3*C.TCPTimeout)))
+		start := time.Now()
+		_, err = io.Copy(io.Discard, conn)
+		require.NoError(t, err)
+		require.Less(t, time.Since(start), 2*C.TCPTimeout)
+	})
+	t.Run("auth-tries", func(t *testing.T) {
+		var tries int
+		_, err := ssh.Dial("tcp", netip.AddrPortFrom(netip.IPv4Unspecified(), serverPort).String(), &ssh.ClientConfig{
+			User: "sekai",
+			Auth: []ssh.AuthMethod{
+				ssh.RetryableAuthMethod(ssh.PasswordCallback(func() (string, error) {
+					tries++
+					return "wrong", nil
+				}), 10),
+			},
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		})
+		require.Error(t, err)
+		require.LessOrEqual(t, tries, 3)
+	})
+}
+
+func mkSSHKey(t *testing.T) (string, string) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	privateKeyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyBytes})), string(ssh.MarshalAuthorizedKey(sshPublicKey))
+}
+
+func testSSHSelf(t *testing.T, hostKey string, hostPublicKey string, users []option.SSHUser, outboundOptions option.SSHOutboundOptions) {
+	outboundOptions.ServerOptions = option.ServerOptions{
+		Server:     "127.0.0.1",
+		ServerPort: serverPort,
+	}
+	outboundOptions.HostKey = []string{hostPublicKey}
+	startInstance(t, option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeMixed,
+				Tag:  "mixed-in",
+				MixedOptions: option.HTTPMixedInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: clientPort,
+					},
+				},
+			},
+			{
+				Type: C.TypeSSH,
+				SSHOptions: option.SSHInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: serverPort,
+					},
+					Users:   users,
+					HostKey: []string{hostKey},
+				},
+			},
+		},
+		Outbounds: []option.Outbound{
+			{
+				Type: C.TypeDirect,
+			},
+			{
+				Type:       C.TypeSSH,
+				Tag:        "ssh-out",
+				SSHOptions: outboundOptions,
+			},
+		},
+		Route: &option.RouteOptions{
+			Rules: []option.Rule{
+				{
+					DefaultOptions: option.DefaultRule{
+						Inbound:  []string{"mixed-in"},
+						Outbound: "ssh-out",
+					},
+				},
+			},
+		},
+	})
+	testTCP(t, clientPort, testPort)
+}
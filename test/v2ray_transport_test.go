@@ -1,11 +1,14 @@
 package main
 
 import (
+	"net/http"
 	"net/netip"
+	"strings"
 	"testing"
 
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
+	F "github.com/sagernet/sing/common/format"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/stretchr/testify/require"
@@ -26,6 +29,43 @@ func TestV2RayHTTPPlainSelf(t *testing.T) {
 	})
 }
 
+func TestV2RayHTTPUpgradeSelf(t *testing.T) {
+	testV2RayTransportSelf(t, &option.V2RayTransportOptions{
+		Type: C.V2RayTransportTypeHTTPUpgrade,
+		HTTPUpgradeOptions: option.V2RayHTTPUpgradeOptions{
+			Path: "/upgrade",
+		},
+	})
+}
+
+func TestV2RayHTTPUpgradePlainSelf(t *testing.T) {
+	testV2RayTransportNOTLSSelf(t, &option.V2RayTransportOptions{
+		Type: C.V2RayTransportTypeHTTPUpgrade,
+	})
+}
+
+func TestV2RaySplitHTTPSelf(t *testing.T) {
+	testV2RayTransportSelf(t, &option.V2RayTransportOptions{
+		Type: C.V2RayTransportTypeSplitHTTP,
+		SplitHTTPOptions: option.V2RaySplitHTTPOptions{
+			Path: "/split",
+		},
+	})
+}
+
+func TestV2RaySplitHTTPPlainSelf(t *testing.T) {
+	testV2RayTransportNOTLSSelf(t, &option.V2RayTransportOptions{
+		Type: C.V2RayTransportTypeSplitHTTP,
+	})
+	// uploads are only accepted for sessions with an active download
+	sessionID, err := uuid.DefaultGenerator.NewV4()
+	require.NoError(t, err)
+	response, err := http.Post(F.ToString("http://127.0.0.1:", serverPort, "/", sessionID, "/0"), "application/octet-stream", strings.NewReader("ping"))
+	require.NoError(t, err)
+	response.Body.Close()
+	require.Equal(t, http.StatusNotFound, response.StatusCode)
+}
+
 func testV2RayTransportSelf(t *testing.T, transport *option.V2RayTransportOptions) {
 	testV2RayTransportSelfWith(t, transport, transport)
 }
@@ -1,12 +1,17 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"net/netip"
 	"testing"
 	"time"
 
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/curve25519"
 )
 
 func _TestWireGuard(t *testing.T) {
@@ -49,3 +54,134 @@ func _TestWireGuard(t *testing.T) {
 	})
 	testSuitWg(t, clientPort, testPort)
 }
+
+func newWireGuardKeyPair(t *testing.T) (privateKey string, publicKey string) {
+	var key [32]byte
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+	key[0] &= 248
+	key[31] = (key[31] & 127) | 64
+	public, err := curve25519.X25519(key[:], curve25519.Basepoint)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(key[:]), base64.StdEncoding.EncodeToString(public)
+}
+
+func TestWireGuardSelf(t *testing.T) {
+	serverPrivateKey, serverPublicKey := newWireGuardKeyPair(t)
+	userPrivateKey, userPublicKey := newWireGuardKeyPair(t)
+	otherPrivateKey, otherPublicKey := newWireGuardKeyPair(t)
+	startInstance(t, option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeMixed,
+				Tag:  "mixed-in",
+				MixedOptions: option.HTTPMixedInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: clientPort,
+					},
+				},
+			},
+			{
+				Type: C.TypeMixed,
+				Tag:  "mixed-in-other",
+				MixedOptions: option.HTTPMixedInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: otherPort,
+					},
+				},
+			},
+			{
+				Type: C.TypeWireGuard,
+				Tag:  "wg-in",
+				WireGuardOptions: option.WireGuardInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: serverPort,
+					},
+					PrivateKey: serverPrivateKey,
+					Peers: []option.WireGuardInboundPeer{
+						{
+							Name:       "sekai",
+							PublicKey:  userPublicKey,
+							AllowedIPs: []string{"10.0.0.2/32"},
+						},
+						{
+							Name:       "other",
+							PublicKey:  otherPublicKey,
+							AllowedIPs: []string{"10.0.0.3/32"},
+						},
+					},
+				},
+			},
+		},
+		Outbounds: []option.Outbound{
+			{
+				Type: C.TypeDirect,
+				Tag:  "direct",
+			},
+			{
+				Type: C.TypeBlock,
+				Tag:  "block",
+			},
+			{
+				Type: C.TypeWireGuard,
+				Tag:  "wg-out",
+				WireGuardOptions: option.WireGuardOutboundOptions{
+					ServerOptions: option.ServerOptions{
+						Server:     "127.0.0.1",
+						ServerPort: serverPort,
+					},
+					LocalAddress:  []option.ListenPrefix{option.ListenPrefix(netip.MustParsePrefix("10.0.0.2/32"))},
+					PrivateKey:    userPrivateKey,
+					PeerPublicKey: serverPublicKey,
+					Reserved:      []uint8{1, 2, 3},
+				},
+			},
+			{
+				Type: C.TypeWireGuard,
+				Tag:  "wg-out-other",
+				WireGuardOptions: option.WireGuardOutboundOptions{
+					ServerOptions: option.ServerOptions{
+						Server:     "127.0.0.1",
+						ServerPort: serverPort,
+					},
+					LocalAddress:  []option.ListenPrefix{option.ListenPrefix(netip.MustParsePrefix("10.0.0.3/32"))},
+					PrivateKey:    otherPrivateKey,
+					PeerPublicKey: serverPublicKey,
+				},
+			},
+		},
+		Route: &option.RouteOptions{
+			Rules: []option.Rule{
+				{
+					DefaultOptions: option.DefaultRule{
+						Inbound:  []string{"mixed-in"},
+						Outbound: "wg-out",
+					},
+				},
+				{
+					DefaultOptions: option.DefaultRule{
+						Inbound:  []string{"mixed-in-other"},
+						Outbound: "wg-out-other",
+					},
+				},
+				{
+					DefaultOptions: option.DefaultRule{
+						AuthUser: []string{"sekai"},
+						Outbound: "direct",
+					},
+				},
+				{
+					DefaultOptions: option.DefaultRule{
+						Inbound:  []string{"wg-in"},
+						Outbound: "block",
+					},
+				},
+			},
+		},
+	})
+	testTCP(t, clientPort, testPort)
+	require.Error(t, testPingPongWithConn(t, testPort, dialSocksTCP(otherPort, testPort)))
+}
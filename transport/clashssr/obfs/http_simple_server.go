@@ -0,0 +1,152 @@
+package obfs
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Dreamacro/clash/common/pool"
+)
+
+// 10240: max length of the http request header
+const httpSimpleMaxHeaderLength = 10240
+
+func init() {
+	registerServer("http_simple", newHTTPSimpleServer, 0)
+	registerServer("http_post", newHTTPSimpleServer, 0)
+}
+
+func newHTTPSimpleServer(b *Base) ServerObfs {
+	return &httpObfs{Base: b}
+}
+
+type httpServerConn struct {
+	net.Conn
+	*httpObfs
+	hasSentHeader bool
+	hasRecvHeader bool
+	buf           []byte
+}
+
+func (h *httpObfs) ServerConn(c net.Conn) net.Conn {
+	return &httpServerConn{Conn: c, httpObfs: h}
+}
+
+func (c *httpServerConn) Read(b []byte) (int, error) {
+	if c.buf != nil {
+		n := copy(b, c.buf)
+		if n == len(c.buf) {
+			c.buf = nil
+		} else {
+			c.buf = c.buf[n:]
+		}
+		return n, nil
+	}
+
+	if c.hasRecvHeader {
+		return c.Conn.Read(b)
+	}
+
+	header := pool.GetBuffer()
+	defer pool.PutBuffer(header)
+	buf := pool.Get(pool.RelayBufferSize)
+	defer pool.Put(buf)
+	pos := -1
+	for pos == -1 {
+		n, err := c.Conn.Read(buf)
+		if err != nil {
+			return 0, err
+		}
+		header.Write(buf[:n])
+		pos = bytes.Index(header.Bytes(), []byte("\r\n\r\n"))
+		if pos == -1 && header.Len() > httpSimpleMaxHeaderLength {
+			return 0, errHTTPSimpleHeaderTooLong
+		}
+	}
+	headData, err := c.parseHeader(header.Bytes()[:pos])
+	if err != nil {
+		return 0, err
+	}
+	c.hasRecvHeader = true
+	c.buf = append(headData, header.Bytes()[pos+4:]...)
+	if len(c.buf) == 0 {
+		c.buf = nil
+		return c.Conn.Read(b)
+	}
+	return c.Read(b)
+}
+
+func (c *httpServerConn) parseHeader(header []byte) ([]byte, error) {
+	lines := strings.Split(string(header), "\r\n")
+	requestLine := strings.Split(lines[0], " ")
+	if len(requestLine) != 3 || (requestLine[0] != "GET" && requestLine[0] != "POST") || !strings.HasPrefix(requestLine[2], "HTTP/") {
+		return nil, errHTTPSimpleBadRequest
+	}
+	if len(c.Param) > 0 {
+		var host string
+		for _, line := range lines[1:] {
+			key, value, found := strings.Cut(line, ":")
+			if found && strings.EqualFold(strings.TrimSpace(key), "Host") {
+				host = strings.TrimSpace(value)
+				break
+			}
+		}
+		if hostname, _, err := net.SplitHostPort(host); err == nil {
+			host = hostname
+		}
+		hosts := c.Param
+		if pos := strings.Index(hosts, "#"); pos != -1 {
+			hosts = hosts[:pos]
+		}
+		matched := false
+		for _, allowed := range strings.Split(hosts, ",") {
+			if strings.EqualFold(allowed, host) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil, errHTTPSimpleBadRequest
+		}
+	}
+	return unpackURLEncodedHeadData(requestLine[1])
+}
+
+func unpackURLEncodedHeadData(path string) ([]byte, error) {
+	items := strings.Split(path, "%")
+	data := make([]byte, 0, len(items))
+	for _, item := range items[1:] {
+		if len(item) < 2 {
+			return nil, errHTTPSimpleBadRequest
+		}
+		b, err := hex.DecodeString(item[:2])
+		if err != nil {
+			return nil, errHTTPSimpleBadRequest
+		}
+		data = append(data, b...)
+		if len(item) > 2 {
+			break
+		}
+	}
+	return data, nil
+}
+
+func (c *httpServerConn) Write(b []byte) (int, error) {
+	if c.hasSentHeader {
+		return c.Conn.Write(b)
+	}
+	buf := pool.GetBuffer()
+	defer pool.PutBuffer(buf)
+	buf.WriteString("HTTP/1.1 200 OK\r\nConnection: keep-alive\r\nContent-Encoding: gzip\r\nContent-Type: text/html\r\nDate: ")
+	buf.WriteString(time.Now().UTC().Format("Mon, 02 Jan 2006 15:04:05 GMT"))
+	buf.WriteString("\r\nServer: nginx\r\nVary: Accept-Encoding\r\n\r\n")
+	buf.Write(b)
+	_, err := c.Conn.Write(buf.Bytes())
+	if err != nil {
+		return 0, err
+	}
+	c.hasSentHeader = true
+	return len(b), nil
+}
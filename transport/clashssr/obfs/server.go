@@ -0,0 +1,50 @@
+package obfs
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+var (
+	errHTTPSimpleBadRequest          = errors.New("http_simple bad request")
+	errHTTPSimpleHeaderTooLong       = errors.New("http_simple header too long")
+	errTLS12TicketAuthBadClientHello = errors.New("tls1.2_ticket_auth bad client hello")
+	errTLS12TicketAuthBadFinished    = errors.New("tls1.2_ticket_auth bad finished")
+	errTLS12TicketAuthTimeError      = errors.New("tls1.2_ticket_auth client time out of range")
+)
+
+type ServerObfs interface {
+	ServerConn(net.Conn) net.Conn
+}
+
+type serverObfsCreator func(b *Base) ServerObfs
+
+var serverObfsList = make(map[string]struct {
+	overhead int
+	new      serverObfsCreator
+})
+
+func registerServer(name string, c serverObfsCreator, o int) {
+	serverObfsList[name] = struct {
+		overhead int
+		new      serverObfsCreator
+	}{overhead: o, new: c}
+}
+
+func PickServerObfs(name string, b *Base) (ServerObfs, int, error) {
+	if choice, ok := serverObfsList[name]; ok {
+		return choice.new(b), choice.overhead, nil
+	}
+	return nil, 0, fmt.Errorf("Obfs %s not supported in server", name)
+}
+
+func init() {
+	registerServer("plain", newPlainServer, 0)
+}
+
+func newPlainServer(b *Base) ServerObfs {
+	return &plain{}
+}
+
+func (p *plain) ServerConn(c net.Conn) net.Conn { return c }
@@ -7,6 +7,7 @@ import (
 	"math/rand"
 	"net"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Dreamacro/clash/common/pool"
@@ -36,6 +37,7 @@ type tls12TicketConn struct {
 	decoded         bytes.Buffer
 	underDecoded    bytes.Buffer
 	sendBuf         bytes.Buffer
+	access          sync.Mutex
 }
 
 func (t *tls12Ticket) StreamConn(c net.Conn) net.Conn {
@@ -54,7 +56,10 @@ func (c *tls12TicketConn) Read(b []byte) (int, error) {
 		return 0, err
 	}
 
-	if c.handshakeStatus == 8 {
+	c.access.Lock()
+	handshakeStatus := c.handshakeStatus
+	c.access.Unlock()
+	if handshakeStatus == 8 {
 		c.underDecoded.Write(buf[:n])
 		for c.underDecoded.Len() > 5 {
 			if !bytes.Equal(c.underDecoded.Bytes()[:3], []byte{0x17, 3, 3}) {
@@ -86,7 +91,9 @@ func (c *tls12TicketConn) Read(b []byte) (int, error) {
 
 func (c *tls12TicketConn) Write(b []byte) (int, error) {
 	length := len(b)
+	c.access.Lock()
 	if c.handshakeStatus == 8 {
+		c.access.Unlock()
 		buf := pool.GetBuffer()
 		defer pool.PutBuffer(buf)
 		for len(b) > 2048 {
@@ -106,6 +113,7 @@ func (c *tls12TicketConn) Write(b []byte) (int, error) {
 		}
 		return length, nil
 	}
+	defer c.access.Unlock()
 
 	if len(b) > 0 {
 		packData(&c.sendBuf, b)
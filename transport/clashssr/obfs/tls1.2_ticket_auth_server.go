@@ -0,0 +1,219 @@
+package obfs
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/Dreamacro/clash/common/pool"
+	"github.com/Dreamacro/clash/transport/ssr/tools"
+)
+
+// 86400: default max time difference between the client and the server in seconds
+const tls12TicketAuthMaxTimeDiff = 86400
+
+func init() {
+	registerServer("tls1.2_ticket_auth", newTLS12TicketServer, 5)
+	registerServer("tls1.2_ticket_fastauth", newTLS12TicketServer, 5)
+}
+
+type tls12TicketServer struct {
+	*Base
+	maxTimeDiff int64
+}
+
+func newTLS12TicketServer(b *Base) ServerObfs {
+	t := &tls12TicketServer{Base: b, maxTimeDiff: tls12TicketAuthMaxTimeDiff}
+	if maxTimeDiff, err := strconv.ParseInt(b.Param, 10, 64); err == nil {
+		t.maxTimeDiff = maxTimeDiff
+	}
+	return t
+}
+
+type tls12TicketServerConn struct {
+	net.Conn
+	*tls12TicketServer
+	*authData
+	handshakeDone bool
+	remaining     int
+}
+
+func (t *tls12TicketServer) ServerConn(c net.Conn) net.Conn {
+	return &tls12TicketServerConn{Conn: c, tls12TicketServer: t, authData: &authData{}}
+}
+
+func (c *tls12TicketServerConn) Read(b []byte) (int, error) {
+	if !c.handshakeDone {
+		err := c.handshake()
+		if err != nil {
+			return 0, err
+		}
+		c.handshakeDone = true
+	}
+	if c.remaining == 0 {
+		header := pool.Get(5)
+		defer pool.Put(header)
+		_, err := io.ReadFull(c.Conn, header)
+		if err != nil {
+			return 0, err
+		}
+		if !bytes.Equal(header[:3], []byte{0x17, 3, 3}) {
+			return 0, errTLS12TicketAuthIncorrectMagicNumber
+		}
+		c.remaining = int(binary.BigEndian.Uint16(header[3:5]))
+		if c.remaining == 0 {
+			return 0, nil
+		}
+	}
+	if len(b) > c.remaining {
+		b = b[:c.remaining]
+	}
+	n, err := c.Conn.Read(b)
+	c.remaining -= n
+	return n, err
+}
+
+func (c *tls12TicketServerConn) handshake() error {
+	header := pool.Get(5)
+	defer pool.Put(header)
+	_, err := io.ReadFull(c.Conn, header)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(header[:3], []byte{0x16, 3, 1}) {
+		return errTLS12TicketAuthIncorrectMagicNumber
+	}
+	hello := make([]byte, binary.BigEndian.Uint16(header[3:5]))
+	_, err = io.ReadFull(c.Conn, hello)
+	if err != nil {
+		return err
+	}
+	/*
+		4:	handshake type client hello(1) and uint24 BigEndian length(3)
+		2:	tls version
+		32:	auth data, uint32 BigEndian timestamp(4), random bytes(18) and hmac of them(10)
+		33:	session id length(1) and client id as session id(32)
+	*/
+	if len(hello) < 4+2+32+33 || !bytes.Equal(hello[:2], []byte{1, 0}) ||
+		int(binary.BigEndian.Uint16(hello[2:4])) != len(hello)-4 ||
+		!bytes.Equal(hello[4:6], []byte{3, 3}) || hello[38] < 32 {
+		return errTLS12TicketAuthBadClientHello
+	}
+	copy(c.clientID[:], hello[39:71])
+	authData := hello[6:38]
+	if !hmac.Equal(authData[22:], c.hmacSHA1(authData[:22])) {
+		return errTLS12TicketAuthHMACError
+	}
+	if c.maxTimeDiff > 0 {
+		timeDiff := time.Now().Unix() - int64(binary.BigEndian.Uint32(authData[:4]))
+		if timeDiff < -c.maxTimeDiff || timeDiff > c.maxTimeDiff {
+			return errTLS12TicketAuthTimeError
+		}
+	}
+
+	_, err = c.Conn.Write(c.packServerHello())
+	if err != nil {
+		return err
+	}
+
+	/*
+		6:	change cipher spec
+		5:	finished header
+		32:	random bytes(22) and hmac of change cipher spec and finished(10)
+	*/
+	finished := pool.Get(6 + 5 + 32)
+	defer pool.Put(finished)
+	_, err = io.ReadFull(c.Conn, finished[:11])
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(finished[:6], []byte{0x14, 3, 3, 0, 1, 1}) || !bytes.Equal(finished[6:9], []byte{0x16, 3, 3}) ||
+		binary.BigEndian.Uint16(finished[9:11]) != 32 {
+		return errTLS12TicketAuthBadFinished
+	}
+	_, err = io.ReadFull(c.Conn, finished[11:])
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(finished[33:], c.hmacSHA1(finished[:33])) {
+		return errTLS12TicketAuthHMACError
+	}
+	return nil
+}
+
+func (c *tls12TicketServerConn) packServerHello() []byte {
+	data := pool.GetBuffer()
+	defer pool.PutBuffer(data)
+
+	data.Write([]byte{3, 3})
+	binary.Write(data, binary.BigEndian, uint32(time.Now().Unix()))
+	tools.AppendRandBytes(data, 18)
+	data.Write(c.hmacSHA1(data.Bytes()[2:])[:10])
+	data.WriteByte(0x20)
+	data.Write(c.clientID[:])
+	data.Write([]byte{0xc0, 0x2f, 0x00, 0x00, 0x05, 0xff, 0x01, 0x00, 0x01, 0x00})
+
+	buf := &bytes.Buffer{}
+	buf.Write([]byte{0x16, 3, 3})
+	binary.Write(buf, binary.BigEndian, uint16(data.Len()+4))
+	buf.Write([]byte{2, 0})
+	binary.Write(buf, binary.BigEndian, uint16(data.Len()))
+	buf.ReadFrom(data)
+
+	if rand.Intn(8) < 1 {
+		ticketLength := rand.Intn(164)*2 + 64
+		buf.Write([]byte{0x16, 3, 3})
+		binary.Write(buf, binary.BigEndian, uint16(ticketLength+4))
+		buf.Write([]byte{4, 0})
+		binary.Write(buf, binary.BigEndian, uint16(ticketLength))
+		tools.AppendRandBytes(buf, ticketLength)
+	}
+
+	buf.Write([]byte{0x14, 3, 3, 0, 1, 1})
+	finishedLength := 32
+	if rand.Intn(2) == 0 {
+		finishedLength = 40
+	}
+	buf.Write([]byte{0x16, 3, 3})
+	binary.Write(buf, binary.BigEndian, uint16(finishedLength))
+	tools.AppendRandBytes(buf, finishedLength-10)
+	buf.Write(c.hmacSHA1(buf.Bytes()))
+	return buf.Bytes()
+}
+
+func (c *tls12TicketServerConn) hmacSHA1(data []byte) []byte {
+	key := pool.Get(len(c.Key) + 32)
+	defer pool.Put(key)
+	copy(key, c.Key)
+	copy(key[len(c.Key):], c.clientID[:])
+
+	sha1Data := tools.HmacSHA1(key, data)
+	return sha1Data[:10]
+}
+
+func (c *tls12TicketServerConn) Write(b []byte) (int, error) {
+	length := len(b)
+	buf := pool.GetBuffer()
+	defer pool.PutBuffer(buf)
+	for len(b) > 2048 {
+		size := rand.Intn(4096) + 100
+		if len(b) < size {
+			size = len(b)
+		}
+		packData(buf, b[:size])
+		b = b[size:]
+	}
+	if len(b) > 0 {
+		packData(buf, b)
+	}
+	_, err := c.Conn.Write(buf.Bytes())
+	if err != nil {
+		return 0, err
+	}
+	return length, nil
+}
@@ -0,0 +1,140 @@
+package protocol
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/binary"
+	"net"
+	"time"
+
+	"github.com/Dreamacro/clash/transport/ssr/tools"
+)
+
+func init() {
+	registerServer("auth_aes128_md5", newAuthAES128MD5Server, 9)
+	registerServer("auth_aes128_sha1", newAuthAES128SHA1Server, 9)
+}
+
+type authAES128Server struct {
+	*ServerBase
+	*authAES128Function
+}
+
+func newAuthAES128MD5Server(b *ServerBase) ServerProtocol {
+	return &authAES128Server{
+		ServerBase:         b,
+		authAES128Function: &authAES128Function{salt: "auth_aes128_md5", hmac: tools.HmacMD5, hashDigest: tools.MD5Sum},
+	}
+}
+
+func newAuthAES128SHA1Server(b *ServerBase) ServerProtocol {
+	return &authAES128Server{
+		ServerBase:         b,
+		authAES128Function: &authAES128Function{salt: "auth_aes128_sha1", hmac: tools.HmacSHA1, hashDigest: tools.SHA1Sum},
+	}
+}
+
+func (a *authAES128Server) ServerConn(c net.Conn, iv []byte) *ServerConn {
+	return &ServerConn{Conn: c, serverProtocol: &authAES128ServerConn{authAES128Server: a, iv: iv}}
+}
+
+type authAES128ServerConn struct {
+	*authAES128Server
+	stream  *authAES128
+	iv      []byte
+	userID  uint32
+	hasUser bool
+}
+
+func (a *authAES128ServerConn) UserID() (uint32, bool) {
+	return a.userID, a.hasUser
+}
+
+func (a *authAES128ServerConn) Decode(dst, src *bytes.Buffer) error {
+	if a.stream == nil {
+		authenticated, err := a.decodeAuthData(dst, src)
+		if err != nil || !authenticated {
+			return err
+		}
+	}
+	return a.stream.Decode(dst, src)
+}
+
+func (a *authAES128ServerConn) Encode(buf *bytes.Buffer, b []byte) error {
+	if a.stream == nil {
+		return errAuthNotAuthenticated
+	}
+	return a.stream.Encode(buf, b)
+}
+
+func (a *authAES128ServerConn) decodeAuthData(dst, src *bytes.Buffer) (bool, error) {
+	/*
+		7:	checkHead(1) and hmac of checkHead(6)
+		4:	userID
+		16:	encrypted data of authdata(12), uint16 LittleEndian packedDataLength(2) and uint16 LittleEndian randDataLength(2)
+		4:	hmac of userID and encrypted data
+		4:	hmac of packedAuthData except the last 4 bytes
+	*/
+	if src.Len() < 7+4+16+4 {
+		return false, nil
+	}
+	data := src.Bytes()
+	macKey := make([]byte, len(a.iv)+len(a.Key))
+	copy(macKey, a.iv)
+	copy(macKey[len(a.iv):], a.Key)
+	if !hmac.Equal(a.hmac(macKey, data[:1])[:6], data[1:7]) || !hmac.Equal(a.hmac(macKey, data[7:27])[:4], data[27:31]) {
+		src.Reset()
+		return false, errAuthAES128HeadMACError
+	}
+
+	userID := binary.LittleEndian.Uint32(data[7:11])
+	password, err := a.userPassword(userID)
+	if err != nil {
+		src.Reset()
+		return false, err
+	}
+	userKey := a.Key
+	if password != "" {
+		userKey = a.hashDigest([]byte(password))
+	}
+	decrypted, err := decryptAuthData(data[11:27], userKey, a.salt)
+	if err != nil {
+		src.Reset()
+		return false, err
+	}
+	err = checkAuthTime(binary.LittleEndian.Uint32(decrypted[:4]), time.Now().Unix())
+	if err != nil {
+		src.Reset()
+		return false, err
+	}
+	length := int(binary.LittleEndian.Uint16(decrypted[12:14]))
+	randDataLength := int(binary.LittleEndian.Uint16(decrypted[14:16]))
+	if length < 7+4+16+4+randDataLength+4 || length >= 8192 {
+		src.Reset()
+		return false, errAuthAES128LengthError
+	}
+	if src.Len() < length {
+		return false, nil
+	}
+	if !hmac.Equal(a.hmac(userKey, data[:length-4])[:4], data[length-4:length]) {
+		src.Reset()
+		return false, errAuthAES128ChksumError
+	}
+	dst.Write(data[7+4+16+4+randDataLength : length-4])
+	src.Next(length)
+
+	a.stream = &authAES128{
+		Base:               &Base{Key: a.Key, Overhead: a.Overhead},
+		authData:           &authData{},
+		authAES128Function: a.authAES128Function,
+		userData:           &userData{userKey: userKey},
+		hasSentHeader:      true,
+		packID:             1,
+		recvID:             1,
+	}
+	if password != "" {
+		a.userID = userID
+		a.hasUser = true
+	}
+	return true, nil
+}
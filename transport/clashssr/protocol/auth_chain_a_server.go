@@ -0,0 +1,202 @@
+package protocol
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/binary"
+	"net"
+	"time"
+
+	"github.com/Dreamacro/clash/common/pool"
+	"github.com/Dreamacro/clash/transport/ssr/tools"
+)
+
+// 1460: tcp_mss sent to the client in the first packet
+const authChainServerTCPMSS = 1460
+
+func init() {
+	registerServer("auth_chain_a", newAuthChainAServer, 4)
+}
+
+type authChainAServer struct {
+	*ServerBase
+	salt string
+}
+
+func newAuthChainAServer(b *ServerBase) ServerProtocol {
+	return &authChainAServer{ServerBase: b, salt: "auth_chain_a"}
+}
+
+func (a *authChainAServer) ServerConn(c net.Conn, iv []byte) *ServerConn {
+	return &ServerConn{Conn: c, serverProtocol: &authChainAServerConn{authChainAServer: a, iv: iv}}
+}
+
+type authChainAServerConn struct {
+	*authChainAServer
+	stream  *authChainA
+	iv      []byte
+	userID  uint32
+	hasUser bool
+}
+
+func (a *authChainAServerConn) UserID() (uint32, bool) {
+	return a.userID, a.hasUser
+}
+
+func (a *authChainAServerConn) Decode(dst, src *bytes.Buffer) error {
+	if a.stream == nil {
+		authenticated, err := a.decodeAuthData(src)
+		if err != nil || !authenticated {
+			return err
+		}
+	}
+	s := a.stream
+	for src.Len() > 4 {
+		macKey := pool.Get(len(s.userKey) + 4)
+		defer pool.Put(macKey)
+		copy(macKey, s.userKey)
+		binary.LittleEndian.PutUint32(macKey[len(s.userKey):], s.recvID)
+
+		dataLength := int(binary.LittleEndian.Uint16(src.Bytes()[:2]) ^ binary.LittleEndian.Uint16(s.lastClientHash[14:16]))
+		randDataLength := s.randDataLength(dataLength, s.lastClientHash, &s.randomClient)
+		length := dataLength + randDataLength
+
+		if length >= 4096 {
+			src.Reset()
+			return errAuthChainLengthError
+		}
+
+		if 4+length > src.Len() {
+			break
+		}
+
+		clientHash := tools.HmacMD5(macKey, src.Bytes()[:length+2])
+		if !bytes.Equal(clientHash[:2], src.Bytes()[length+2:length+4]) {
+			src.Reset()
+			return errAuthChainChksumError
+		}
+		s.lastClientHash = clientHash
+
+		pos := 2
+		if dataLength > 0 && randDataLength > 0 {
+			pos += getRandStartPos(randDataLength, &s.randomClient)
+		}
+		wantedData := src.Bytes()[pos : pos+dataLength]
+		s.decrypter.XORKeyStream(wantedData, wantedData)
+		dst.Write(wantedData)
+		s.recvID++
+		src.Next(length + 4)
+	}
+	return nil
+}
+
+func (a *authChainAServerConn) Encode(buf *bytes.Buffer, b []byte) error {
+	if a.stream == nil {
+		return errAuthNotAuthenticated
+	}
+	if !a.stream.hasSentHeader {
+		header := make([]byte, 2, 2+len(b))
+		binary.LittleEndian.PutUint16(header, authChainServerTCPMSS)
+		b = append(header, b...)
+		a.stream.hasSentHeader = true
+	}
+	for len(b) > 2800 {
+		a.packServerData(buf, b[:2800])
+		b = b[2800:]
+	}
+	if len(b) > 0 {
+		a.packServerData(buf, b)
+	}
+	return nil
+}
+
+func (a *authChainAServerConn) packServerData(poolBuf *bytes.Buffer, data []byte) {
+	s := a.stream
+	macKey := pool.Get(len(s.userKey) + 4)
+	defer pool.Put(macKey)
+	copy(macKey, s.userKey)
+	binary.LittleEndian.PutUint32(macKey[len(s.userKey):], s.packID)
+	s.packID++
+
+	length := uint16(len(data)) ^ binary.LittleEndian.Uint16(s.lastServerHash[14:16])
+
+	originalLength := poolBuf.Len()
+	binary.Write(poolBuf, binary.LittleEndian, length)
+	randDataLength := s.randDataLength(len(data), s.lastServerHash, &s.randomServer)
+	startPos := 0
+	if len(data) > 0 && randDataLength > 0 {
+		startPos = getRandStartPos(randDataLength, &s.randomServer)
+	}
+	tools.AppendRandBytes(poolBuf, startPos)
+	dataStart := poolBuf.Len()
+	poolBuf.Write(data)
+	s.encrypter.XORKeyStream(poolBuf.Bytes()[dataStart:], poolBuf.Bytes()[dataStart:])
+	tools.AppendRandBytes(poolBuf, randDataLength-startPos)
+	s.lastServerHash = tools.HmacMD5(macKey, poolBuf.Bytes()[originalLength:])
+	poolBuf.Write(s.lastServerHash[:2])
+}
+
+func (a *authChainAServerConn) decodeAuthData(src *bytes.Buffer) (bool, error) {
+	/*
+		12:	checkHead(4) and hmac of checkHead(8)
+		4:	uint32 LittleEndian uid (uid = userID ^ last client hash)
+		16:	encrypted data of authdata(12), uint16 LittleEndian overhead(2) and uint16 LittleEndian number zero(2)
+		4:	last server hash(4)
+	*/
+	if src.Len() < 12+4+16+4 {
+		return false, nil
+	}
+	data := src.Bytes()
+	macKey := make([]byte, len(a.iv)+len(a.Key))
+	copy(macKey, a.iv)
+	copy(macKey[len(a.iv):], a.Key)
+	lastClientHash := tools.HmacMD5(macKey, data[:4])
+	if !hmac.Equal(lastClientHash[:8], data[4:12]) {
+		src.Reset()
+		return false, errAuthChainHeadMACError
+	}
+
+	userID := binary.LittleEndian.Uint32(data[12:16]) ^ binary.LittleEndian.Uint32(lastClientHash[8:12])
+	password, err := a.userPassword(userID)
+	if err != nil {
+		src.Reset()
+		return false, err
+	}
+	userKey := a.Key
+	if password != "" {
+		userKey = []byte(password)
+	}
+	lastServerHash := tools.HmacMD5(userKey, data[12:32])
+	if !hmac.Equal(lastServerHash[:4], data[32:36]) {
+		src.Reset()
+		return false, errAuthChainHeadMACError
+	}
+	decrypted, err := decryptAuthData(data[16:32], userKey, a.salt)
+	if err != nil {
+		src.Reset()
+		return false, err
+	}
+	err = checkAuthTime(binary.LittleEndian.Uint32(decrypted[:4]), time.Now().Unix())
+	if err != nil {
+		src.Reset()
+		return false, err
+	}
+	src.Next(12 + 4 + 16 + 4)
+
+	a.stream = &authChainA{
+		Base:           &Base{Key: a.Key, Overhead: a.Overhead},
+		userData:       &userData{userKey: userKey},
+		salt:           a.salt,
+		lastClientHash: lastClientHash,
+		lastServerHash: lastServerHash,
+		packID:         1,
+		recvID:         1,
+	}
+	a.stream.randDataLength = a.stream.getRandLength
+	a.stream.initRC4Cipher()
+	if password != "" {
+		a.userID = userID
+		a.hasUser = true
+	}
+	return true, nil
+}
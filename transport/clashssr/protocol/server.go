@@ -0,0 +1,148 @@
+package protocol
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/Dreamacro/clash/common/pool"
+	"github.com/Dreamacro/clash/transport/shadowsocks/core"
+)
+
+var (
+	errAuthAES128HeadMACError = errors.New("auth_aes128 decode head wrong mac")
+	errAuthChainHeadMACError  = errors.New("auth_chain decode head wrong mac")
+	errAuthTimeError          = errors.New("auth client time out of range")
+	errAuthNotAuthenticated   = errors.New("auth encode before authenticated")
+)
+
+// 86400: max time difference between the client and the server in seconds
+const authMaxTimeDiff = 86400
+
+type ServerBase struct {
+	Key      []byte
+	Overhead int
+	// Users returns the password of the user id sent in protocol params,
+	// the connection is authenticated with Key if the password is empty.
+	Users func(userID uint32) (string, error)
+}
+
+func (b *ServerBase) userPassword(userID uint32) (string, error) {
+	if b.Users == nil {
+		return "", nil
+	}
+	return b.Users(userID)
+}
+
+type ServerProtocol interface {
+	ServerConn(net.Conn, []byte) *ServerConn
+}
+
+type serverProtocol interface {
+	Decode(dst, src *bytes.Buffer) error
+	Encode(buf *bytes.Buffer, b []byte) error
+	UserID() (uint32, bool)
+}
+
+type serverProtocolCreator func(b *ServerBase) ServerProtocol
+
+var serverProtocolList = make(map[string]struct {
+	overhead int
+	new      serverProtocolCreator
+})
+
+func registerServer(name string, c serverProtocolCreator, o int) {
+	serverProtocolList[name] = struct {
+		overhead int
+		new      serverProtocolCreator
+	}{overhead: o, new: c}
+}
+
+func PickServerProtocol(name string, b *ServerBase) (ServerProtocol, error) {
+	if choice, ok := serverProtocolList[name]; ok {
+		b.Overhead += choice.overhead
+		return choice.new(b), nil
+	}
+	return nil, fmt.Errorf("protocol %s not supported in server", name)
+}
+
+// ServerConn is the server side of a protocol stream,
+// UserID returns the user authenticated by protocol params after the first read.
+type ServerConn struct {
+	net.Conn
+	serverProtocol
+	decoded      bytes.Buffer
+	underDecoded bytes.Buffer
+}
+
+func (c *ServerConn) Read(b []byte) (int, error) {
+	if c.decoded.Len() > 0 {
+		return c.decoded.Read(b)
+	}
+
+	buf := pool.Get(pool.RelayBufferSize)
+	defer pool.Put(buf)
+	for c.decoded.Len() == 0 {
+		n, err := c.Conn.Read(buf)
+		if err != nil {
+			return 0, err
+		}
+		c.underDecoded.Write(buf[:n])
+		err = c.Decode(&c.decoded, &c.underDecoded)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return c.decoded.Read(b)
+}
+
+func (c *ServerConn) Write(b []byte) (int, error) {
+	bLength := len(b)
+	buf := pool.GetBuffer()
+	defer pool.PutBuffer(buf)
+	err := c.Encode(buf, b)
+	if err != nil {
+		return 0, err
+	}
+	_, err = c.Conn.Write(buf.Bytes())
+	if err != nil {
+		return 0, err
+	}
+	return bLength, nil
+}
+
+func init() {
+	registerServer("origin", newOriginServer, 0)
+}
+
+func newOriginServer(b *ServerBase) ServerProtocol { return &origin{} }
+
+func (o *origin) ServerConn(c net.Conn, iv []byte) *ServerConn {
+	return &ServerConn{Conn: c, serverProtocol: o}
+}
+
+func (o *origin) UserID() (uint32, bool) { return 0, false }
+
+func checkAuthTime(timestamp uint32, now int64) error {
+	timeDiff := now - int64(timestamp)
+	if timeDiff < -authMaxTimeDiff || timeDiff > authMaxTimeDiff {
+		return errAuthTimeError
+	}
+	return nil
+}
+
+func decryptAuthData(encrypted []byte, userKey []byte, salt string) ([]byte, error) {
+	cipherKey := core.Kdf(base64.StdEncoding.EncodeToString(userKey)+salt, 16)
+	block, err := aes.NewCipher(cipherKey)
+	if err != nil {
+		return nil, err
+	}
+	iv := bytes.Repeat([]byte{0}, 16)
+	decrypted := make([]byte, 16)
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, encrypted[:16])
+	return decrypted, nil
+}
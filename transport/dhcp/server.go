@@ -20,6 +20,7 @@ import (
 	"github.com/sagernet/sing/common/buf"
 	"github.com/sagernet/sing/common/control"
 	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
 	"github.com/sagernet/sing/common/logger"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
@@ -27,9 +28,13 @@ import (
 	"github.com/sagernet/sing/common/x/list"
 
 	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/insomniacslk/dhcp/iana"
 	mDNS "github.com/miekg/dns"
 )
 
+var _ adapter.DHCPTransport = (*Transport)(nil)
+
 func init() {
 	dns.RegisterTransport([]string{"dhcp"}, NewTransport)
 }
@@ -45,6 +50,8 @@ type Transport struct {
 	transports        []dns.Transport
 	updateAccess      sync.Mutex
 	updatedAt         time.Time
+	information       adapter.DHCPInformation
+	informationAccess sync.RWMutex
 }
 
 func NewTransport(name string, ctx context.Context, logger logger.ContextLogger, dialer N.Dialer, link string) (dns.Transport, error) {
@@ -166,52 +173,147 @@ func (t *Transport) interfaceUpdated(int) error {
 	return t.updateServers()
 }
 
+type dhcpResponse struct {
+	servers       []netip.Addr
+	searchDomains []string
+	routers       []netip.Addr
+}
+
+type dhcpResult struct {
+	family   string
+	response *dhcpResponse
+	err      error
+}
+
 func (t *Transport) fetchServers0(ctx context.Context, iface *net.Interface) error {
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan dhcpResult, 2)
+	go func() {
+		response, err := t.fetchDHCPv4(fetchCtx, iface)
+		results <- dhcpResult{"DHCPv4", response, err}
+	}()
+	go func() {
+		response, err := t.fetchDHCPv6(fetchCtx, iface)
+		results <- dhcpResult{"DHCPv6", response, err}
+	}()
+	var (
+		responses   []*dhcpResult
+		fetchErrors []error
+		grace       <-chan time.Time
+	)
+	for pending := 2; pending > 0; {
+		select {
+		case result := <-results:
+			pending--
+			if result.err != nil {
+				fetchErrors = append(fetchErrors, E.Cause(result.err, result.family))
+				continue
+			}
+			responses = append(responses, &result)
+			if grace == nil && pending > 0 && len(result.response.servers) > 0 {
+				// do not wait the full timeout for the other family once we have a response
+				timer := time.NewTimer(C.DHCPv6Grace)
+				defer timer.Stop()
+				grace = timer.C
+			}
+		case <-grace:
+			pending = 0
+		}
+	}
+	if len(responses) == 0 {
+		return E.Errors(fetchErrors...)
+	}
+	for _, err := range fetchErrors {
+		t.logger.Debug("dhcp: ", err)
+	}
+	// prefer DHCPv4 information
+	if len(responses) > 1 && responses[0].family != "DHCPv4" {
+		responses[0], responses[1] = responses[1], responses[0]
+	}
+	var merged dhcpResponse
+	for _, result := range responses {
+		merged.servers = append(merged.servers, result.response.servers...)
+		merged.routers = append(merged.routers, result.response.routers...)
+		for _, searchDomain := range result.response.searchDomains {
+			if !common.Contains(merged.searchDomains, searchDomain) {
+				merged.searchDomains = append(merged.searchDomains, searchDomain)
+			}
+		}
+	}
+	if len(merged.servers) == 0 {
+		// keep the previous servers
+		return E.New("dhcp: empty DNS servers response")
+	}
+	return t.recreateServers(iface, &merged)
+}
+
+func (t *Transport) listenPacket(iface *net.Interface, network string, address string) (net.PacketConn, error) {
 	var listener net.ListenConfig
 	listener.Control = control.Append(listener.Control, control.BindToInterfaceFunc(t.router.InterfaceFinder(), func(network string, address string) (interfaceName string, interfaceIndex int) {
 		return iface.Name, iface.Index
 	}))
 	listener.Control = control.Append(listener.Control, control.ReuseAddr())
-	packetConn, err := listener.ListenPacket(t.ctx, "udp4", "0.0.0.0:68")
+	return listener.ListenPacket(t.ctx, network, address)
+}
+
+func (t *Transport) fetchDHCPv4(ctx context.Context, iface *net.Interface) (*dhcpResponse, error) {
+	packetConn, err := t.listenPacket(iface, "udp4", "0.0.0.0:68")
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer packetConn.Close()
 
-	discovery, err := dhcpv4.NewDiscovery(iface.HardwareAddr, dhcpv4.WithBroadcast(true), dhcpv4.WithRequestedOptions(dhcpv4.OptionDomainNameServer))
+	discovery, err := dhcpv4.NewDiscovery(iface.HardwareAddr, dhcpv4.WithBroadcast(true), dhcpv4.WithRequestedOptions(
+		dhcpv4.OptionDomainNameServer,
+		dhcpv4.OptionRouter,
+		dhcpv4.OptionDomainName,
+		dhcpv4.OptionDNSDomainSearchList,
+	))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	_, err = packetConn.WriteTo(discovery.ToBytes(), &net.UDPAddr{IP: net.IPv4bcast, Port: 67})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	var response *dhcpResponse
 	var group task.Group
 	group.Append0(func(ctx context.Context) error {
-		return t.fetchServersResponse(iface, packetConn, discovery.TransactionID)
+		fetchResponse, fetchErr := t.fetchDHCPv4Response(packetConn, discovery.TransactionID)
+		if fetchErr != nil {
+			return fetchErr
+		}
+		response = fetchResponse
+		return nil
 	})
 	group.Cleanup(func() {
 		packetConn.Close()
 	})
-	return group.Run(ctx)
+	err = group.Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
 }
 
-func (t *Transport) fetchServersResponse(iface *net.Interface, packetConn net.PacketConn, transactionID dhcpv4.TransactionID) error {
+func (t *Transport) fetchDHCPv4Response(packetConn net.PacketConn, transactionID dhcpv4.TransactionID) (*dhcpResponse, error) {
 	buffer := buf.NewSize(dhcpv4.MaxMessageSize)
 	defer buffer.Release()
 
 	for {
+		buffer.FullReset()
 		_, _, err := buffer.ReadPacketFrom(packetConn)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		dhcpPacket, err := dhcpv4.FromBytes(buffer.Bytes())
 		if err != nil {
 			t.logger.Trace("dhcp: parse DHCP response: ", err)
-			return err
+			return nil, err
 		}
 
 		if dhcpPacket.MessageType() != dhcpv4.MessageTypeOffer {
@@ -224,25 +326,140 @@ func (t *Transport) fetchServersResponse(iface *net.Interface, packetConn net.Pa
 			continue
 		}
 
-		dns := dhcpPacket.DNS()
-		if len(dns) == 0 {
-			return nil
+		if len(dhcpPacket.DNS()) == 0 {
+			t.logger.Trace("dhcp: OFFER without DNS servers from ", dhcpPacket.ServerIPAddr)
+			continue
+		}
+
+		var response dhcpResponse
+		response.servers = toAddrs(dhcpPacket.DNS())
+		response.routers = toAddrs(dhcpPacket.Router())
+		if domainName := dhcpPacket.DomainName(); domainName != "" {
+			response.searchDomains = append(response.searchDomains, domainName)
 		}
+		if searchList := dhcpPacket.DomainSearch(); searchList != nil {
+			response.searchDomains = append(response.searchDomains, searchList.Labels...)
+		}
+		response.searchDomains = normalizeDomains(response.searchDomains)
+		return &response, nil
+	}
+}
 
-		var addrs []netip.Addr
-		for _, ip := range dns {
-			addr, _ := netip.AddrFromSlice(ip)
+func (t *Transport) fetchDHCPv6(ctx context.Context, iface *net.Interface) (*dhcpResponse, error) {
+	packetConn, err := t.listenPacket(iface, "udp6", "[::]:"+F.ToString(dhcpv6.DefaultClientPort))
+	if err != nil {
+		return nil, err
+	}
+	defer packetConn.Close()
+
+	// stateless DHCPv6 (RFC 8415 section 6.1), only other configuration is requested
+	request, err := dhcpv6.NewMessage()
+	if err != nil {
+		return nil, err
+	}
+	request.MessageType = dhcpv6.MessageTypeInformationRequest
+	request.AddOption(dhcpv6.OptClientID(&dhcpv6.DUIDLL{
+		HWType:        iana.HWTypeEthernet,
+		LinkLayerAddr: iface.HardwareAddr,
+	}))
+	request.AddOption(dhcpv6.OptRequestedOption(
+		dhcpv6.OptionDNSRecursiveNameServer,
+		dhcpv6.OptionDomainSearchList,
+	))
+	request.AddOption(dhcpv6.OptElapsedTime(0))
+
+	_, err = packetConn.WriteTo(request.ToBytes(), &net.UDPAddr{IP: dhcpv6.AllDHCPRelayAgentsAndServers, Port: dhcpv6.DefaultServerPort, Zone: iface.Name})
+	if err != nil {
+		return nil, err
+	}
+
+	var response *dhcpResponse
+	var group task.Group
+	group.Append0(func(ctx context.Context) error {
+		fetchResponse, fetchErr := t.fetchDHCPv6Response(packetConn, request.TransactionID)
+		if fetchErr != nil {
+			return fetchErr
+		}
+		response = fetchResponse
+		return nil
+	})
+	group.Cleanup(func() {
+		packetConn.Close()
+	})
+	err = group.Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (t *Transport) fetchDHCPv6Response(packetConn net.PacketConn, transactionID dhcpv6.TransactionID) (*dhcpResponse, error) {
+	buffer := buf.NewSize(dhcpv4.MaxMessageSize)
+	defer buffer.Release()
+
+	for {
+		buffer.FullReset()
+		_, _, err := buffer.ReadPacketFrom(packetConn)
+		if err != nil {
+			return nil, err
+		}
+
+		dhcpPacket, err := dhcpv6.MessageFromBytes(buffer.Bytes())
+		if err != nil {
+			t.logger.Trace("dhcp: parse DHCPv6 response: ", err)
+			continue
+		}
+
+		if dhcpPacket.MessageType != dhcpv6.MessageTypeReply {
+			t.logger.Trace("dhcp: expected REPLY response, but got ", dhcpPacket.MessageType)
+			continue
+		}
+
+		if dhcpPacket.TransactionID != transactionID {
+			t.logger.Trace("dhcp: expected transaction ID ", transactionID, ", but got ", dhcpPacket.TransactionID)
+			continue
+		}
+
+		var response dhcpResponse
+		response.servers = toAddrs(dhcpPacket.Options.DNS())
+		if searchList := dhcpPacket.Options.DomainSearchList(); searchList != nil {
+			response.searchDomains = normalizeDomains(searchList.Labels)
+		}
+		return &response, nil
+	}
+}
+
+func toAddrs(ips []net.IP) []netip.Addr {
+	var addrs []netip.Addr
+	for _, ip := range ips {
+		addr, ok := netip.AddrFromSlice(ip)
+		if ok {
 			addrs = append(addrs, addr.Unmap())
 		}
-		return t.recreateServers(iface, addrs)
 	}
+	return addrs
+}
+
+func normalizeDomains(domains []string) []string {
+	var normalized []string
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+		if domain != "" && !common.Contains(normalized, domain) {
+			normalized = append(normalized, domain)
+		}
+	}
+	return normalized
 }
 
-func (t *Transport) recreateServers(iface *net.Interface, serverAddrs []netip.Addr) error {
-	if len(serverAddrs) > 0 {
-		t.logger.Info("dhcp: updated DNS servers from ", iface.Name, ": [", strings.Join(common.Map(serverAddrs, func(it netip.Addr) string {
-			return it.String()
-		}), ","), "]")
+func (t *Transport) recreateServers(iface *net.Interface, response *dhcpResponse) error {
+	if len(response.servers) > 0 {
+		t.logger.Info("dhcp: updated DNS servers from ", iface.Name, ": [", strings.Join(common.Map(response.servers, netip.Addr.String), ","), "]")
+	}
+	if len(response.searchDomains) > 0 {
+		t.logger.Info("dhcp: updated search domains from ", iface.Name, ": [", strings.Join(response.searchDomains, ","), "]")
+	}
+	if len(response.routers) > 0 {
+		t.logger.Debug("dhcp: updated routers from ", iface.Name, ": [", strings.Join(common.Map(response.routers, netip.Addr.String), ","), "]")
 	}
 
 	serverDialer := dialer.NewDefault(t.router, option.DialerOptions{
@@ -250,7 +467,7 @@ func (t *Transport) recreateServers(iface *net.Interface, serverAddrs []netip.Ad
 		UDPFragmentDefault: true,
 	})
 	var transports []dns.Transport
-	for _, serverAddr := range serverAddrs {
+	for _, serverAddr := range response.servers {
 		serverTransport, err := dns.NewUDPTransport(t.name, t.ctx, serverDialer, M.Socksaddr{Addr: serverAddr, Port: 53})
 		if err != nil {
 			return err
@@ -258,9 +475,23 @@ func (t *Transport) recreateServers(iface *net.Interface, serverAddrs []netip.Ad
 		transports = append(transports, serverTransport)
 	}
 	t.transports = transports
+	t.informationAccess.Lock()
+	t.information = adapter.DHCPInformation{
+		Interface:     iface.Name,
+		Servers:       response.servers,
+		SearchDomains: response.searchDomains,
+		Routers:       response.routers,
+	}
+	t.informationAccess.Unlock()
 	return nil
 }
 
+func (t *Transport) DHCPInformation() adapter.DHCPInformation {
+	t.informationAccess.RLock()
+	defer t.informationAccess.RUnlock()
+	return t.information
+}
+
 func (t *Transport) Lookup(ctx context.Context, domain string, strategy dns.DomainStrategy) ([]netip.Addr, error) {
 	return nil, os.ErrInvalid
 }
@@ -0,0 +1,191 @@
+package dnstransport
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/netip"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	mDNS "github.com/miekg/dns"
+)
+
+var _ adapter.DNSConnectionTransport = (*HTTPSTransport)(nil)
+
+type HTTPSTransport struct {
+	connectionStatistics
+	name        string
+	logger      logger.ContextLogger
+	destination string
+	http2       *http.Transport
+	http3       http.RoundTripper
+	limiter     queryLimiter
+}
+
+func NewHTTPSTransport(ctx context.Context, logger logger.ContextLogger, name string, dialer N.Dialer, serverURL *url.URL, options option.DNSServerOptions) (*HTTPSTransport, error) {
+	transport := &HTTPSTransport{
+		name:        name,
+		logger:      logger,
+		destination: serverURL.String(),
+		limiter:     newQueryLimiter(options.MaxConcurrentQueries),
+	}
+	tlsConfig, err := newTLSConfig(ctx, serverURL, options.TLS, []string{"h2", "http/1.1"})
+	if err != nil {
+		return nil, err
+	}
+	transport.http2 = &http.Transport{
+		ForceAttemptHTTP2: true,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, M.ParseSocksaddr(addr))
+			if err == nil {
+				transport.connections.Add(1)
+			}
+			return conn, err
+		},
+		TLSClientConfig: tlsConfig,
+		IdleConnTimeout: idleTimeout(options, 90*time.Second),
+	}
+	if options.PreferHTTP3 {
+		http3TLSConfig, err := newTLSConfig(ctx, serverURL, options.TLS, []string{"h3"})
+		if err != nil {
+			return nil, err
+		}
+		transport.http3, err = newHTTP3RoundTripper(dialer, http3TLSConfig, idleTimeout(options, 0), &transport.connectionStatistics)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return transport, nil
+}
+
+func NewHTTP3Transport(ctx context.Context, logger logger.ContextLogger, name string, dialer N.Dialer, serverURL *url.URL, options option.DNSServerOptions) (*HTTPSTransport, error) {
+	if options.PreferHTTP3 {
+		return nil, E.New("prefer_http3 is only supported by https servers")
+	}
+	destinationURL := *serverURL
+	destinationURL.Scheme = "https"
+	transport := &HTTPSTransport{
+		name:        name,
+		logger:      logger,
+		destination: destinationURL.String(),
+		limiter:     newQueryLimiter(options.MaxConcurrentQueries),
+	}
+	tlsConfig, err := newTLSConfig(ctx, serverURL, options.TLS, []string{"h3"})
+	if err != nil {
+		return nil, err
+	}
+	transport.http3, err = newHTTP3RoundTripper(dialer, tlsConfig, idleTimeout(options, 0), &transport.connectionStatistics)
+	if err != nil {
+		return nil, err
+	}
+	return transport, nil
+}
+
+func (t *HTTPSTransport) Name() string {
+	return t.name
+}
+
+func (t *HTTPSTransport) Start() error {
+	return nil
+}
+
+func (t *HTTPSTransport) Close() error {
+	if t.http2 != nil {
+		t.http2.CloseIdleConnections()
+	}
+	return common.Close(t.http3)
+}
+
+func (t *HTTPSTransport) Raw() bool {
+	return true
+}
+
+func (t *HTTPSTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	err := t.limiter.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer t.limiter.release()
+	t.queries.Add(1)
+	var response *mDNS.Msg
+	if t.http3 != nil {
+		response, err = t.exchange(ctx, t.http3, message)
+		if err == nil || t.http2 == nil || ctx.Err() != nil {
+			if err != nil {
+				t.failures.Add(1)
+			}
+			return response, err
+		}
+		t.logger.DebugContext(ctx, "HTTP/3 exchange failed, fallback to HTTP/2: ", err)
+	}
+	response, err = t.exchange(ctx, t.http2, message)
+	if err != nil {
+		t.failures.Add(1)
+	}
+	return response, err
+}
+
+func (t *HTTPSTransport) exchange(ctx context.Context, roundTripper http.RoundTripper, message *mDNS.Msg) (*mDNS.Msg, error) {
+	message = message.Copy()
+	message.Id = 0
+	rawMessage, err := message.Pack()
+	if err != nil {
+		return nil, err
+	}
+	var traced bool
+	connections := t.connections.Load()
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			traced = true
+			if info.Reused {
+				t.reusedQueries.Add(1)
+			}
+		},
+	})
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, t.destination, bytes.NewReader(rawMessage))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("content-type", dns.MimeType)
+	request.Header.Set("accept", dns.MimeType)
+	response, err := roundTripper.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if !traced && t.connections.Load() == connections {
+		// HTTP/3 connections are not traced
+		t.reusedQueries.Add(1)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, E.New("unexpected status: ", response.Status)
+	}
+	rawMessage, err = io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	var responseMessage mDNS.Msg
+	err = responseMessage.Unpack(rawMessage)
+	if err != nil {
+		return nil, err
+	}
+	return &responseMessage, nil
+}
+
+func (t *HTTPSTransport) Lookup(ctx context.Context, domain string, strategy dns.DomainStrategy) ([]netip.Addr, error) {
+	return nil, os.ErrInvalid
+}
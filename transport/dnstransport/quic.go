@@ -0,0 +1,288 @@
+//go:build with_quic
+
+package dnstransport
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sagernet/quic-go"
+	"github.com/sagernet/quic-go/http3"
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	mDNS "github.com/miekg/dns"
+)
+
+func newHTTP3RoundTripper(dialer N.Dialer, tlsConfig *tls.Config, idleTimeout time.Duration, statistics *connectionStatistics) (http.RoundTripper, error) {
+	return &http3.RoundTripper{
+		Dial: func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
+			destinationAddr := M.ParseSocksaddr(addr)
+			conn, err := dialer.DialContext(ctx, N.NetworkUDP, destinationAddr)
+			if err != nil {
+				return nil, err
+			}
+			connection, err := quic.DialEarly(ctx, bufio.NewUnbindPacketConn(conn), conn.RemoteAddr(), tlsCfg, cfg)
+			if err != nil {
+				conn.Close()
+				return nil, err
+			}
+			statistics.connections.Add(1)
+			return connection, nil
+		},
+		TLSClientConfig: tlsConfig,
+		QuicConfig: &quic.Config{
+			MaxIdleTimeout: idleTimeout,
+		},
+	}, nil
+}
+
+var _ adapter.DNSConnectionTransport = (*QUICTransport)(nil)
+
+type QUICTransport struct {
+	connectionStatistics
+	name       string
+	ctx        context.Context
+	dialer     N.Dialer
+	serverAddr M.Socksaddr
+	tlsConfig  *tls.Config
+	quicConfig *quic.Config
+	limiter    queryLimiter
+
+	access     sync.Mutex
+	connection quic.EarlyConnection
+	dialing    *quicDial
+	closed     bool
+}
+
+type quicDial struct {
+	done       chan struct{}
+	connection quic.EarlyConnection
+	err        error
+}
+
+func NewQUICTransport(ctx context.Context, logger logger.ContextLogger, name string, dialer N.Dialer, serverURL *url.URL, options option.DNSServerOptions) (*QUICTransport, error) {
+	if options.PreferHTTP3 {
+		return nil, E.New("prefer_http3 is only supported by https servers")
+	}
+	serverAddr := M.ParseSocksaddr(serverURL.Host)
+	if !serverAddr.IsValid() {
+		return nil, E.New("invalid server address")
+	}
+	if serverAddr.Port == 0 {
+		serverAddr.Port = 853
+	}
+	tlsConfig, err := newTLSConfig(ctx, serverURL, options.TLS, []string{"doq"})
+	if err != nil {
+		return nil, err
+	}
+	return &QUICTransport{
+		name:       name,
+		ctx:        ctx,
+		dialer:     dialer,
+		serverAddr: serverAddr,
+		tlsConfig:  tlsConfig,
+		quicConfig: &quic.Config{
+			MaxIdleTimeout: idleTimeout(options, 0),
+		},
+		limiter: newQueryLimiter(options.MaxConcurrentQueries),
+	}, nil
+}
+
+func (t *QUICTransport) Name() string {
+	return t.name
+}
+
+func (t *QUICTransport) Start() error {
+	return nil
+}
+
+func (t *QUICTransport) Close() error {
+	t.access.Lock()
+	defer t.access.Unlock()
+	t.closed = true
+	if t.connection != nil {
+		t.connection.CloseWithError(0, "")
+	}
+	return nil
+}
+
+func (t *QUICTransport) Raw() bool {
+	return true
+}
+
+func (t *QUICTransport) openConnection(ctx context.Context) (quic.EarlyConnection, bool, error) {
+	t.access.Lock()
+	if t.closed {
+		t.access.Unlock()
+		return nil, false, os.ErrClosed
+	}
+	connection := t.connection
+	if connection != nil && !common.Done(connection.Context()) {
+		t.access.Unlock()
+		return connection, true, nil
+	}
+	// concurrent queries share a single dial, which is not bound to any of them
+	dial := t.dialing
+	if dial == nil {
+		dial = &quicDial{done: make(chan struct{})}
+		t.dialing = dial
+		go t.dial(dial)
+	}
+	t.access.Unlock()
+	select {
+	case <-dial.done:
+		return dial.connection, false, dial.err
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+}
+
+func (t *QUICTransport) dial(dial *quicDial) {
+	defer close(dial.done)
+	ctx, cancel := context.WithTimeout(t.ctx, C.TCPTimeout)
+	defer cancel()
+	dial.connection, dial.err = t.dialConnection(ctx)
+	t.access.Lock()
+	defer t.access.Unlock()
+	t.dialing = nil
+	if dial.err != nil {
+		return
+	}
+	if t.closed {
+		dial.connection.CloseWithError(0, "")
+		dial.connection, dial.err = nil, os.ErrClosed
+		return
+	}
+	t.connection = dial.connection
+	t.connections.Add(1)
+}
+
+func (t *QUICTransport) dialConnection(ctx context.Context) (quic.EarlyConnection, error) {
+	conn, err := t.dialer.DialContext(ctx, N.NetworkUDP, t.serverAddr)
+	if err != nil {
+		return nil, err
+	}
+	earlyConnection, err := quic.DialEarly(ctx, bufio.NewUnbindPacketConn(conn), t.serverAddr.UDPAddr(), t.tlsConfig, t.quicConfig)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return earlyConnection, nil
+}
+
+func (t *QUICTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	err := t.limiter.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer t.limiter.release()
+	t.queries.Add(1)
+	var (
+		conn     quic.Connection
+		reused   bool
+		response *mDNS.Msg
+	)
+	for i := 0; i < 2; i++ {
+		conn, reused, err = t.openConnection(ctx)
+		if err != nil {
+			break
+		}
+		response, err = t.exchange(ctx, message, conn)
+		if err == nil {
+			if reused {
+				t.reusedQueries.Add(1)
+			}
+			return response, nil
+		} else if !isQUICRetryError(err) {
+			break
+		}
+		conn.CloseWithError(quic.ApplicationErrorCode(0), "")
+	}
+	t.failures.Add(1)
+	return nil, err
+}
+
+func (t *QUICTransport) exchange(ctx context.Context, message *mDNS.Msg, conn quic.Connection) (*mDNS.Msg, error) {
+	message = message.Copy()
+	message.Id = 0
+	rawMessage, err := message.Pack()
+	if err != nil {
+		return nil, err
+	}
+	buffer := buf.NewSize(2 + len(rawMessage))
+	defer buffer.Release()
+	common.Must(binary.Write(buffer, binary.BigEndian, uint16(len(rawMessage))))
+	common.Must1(buffer.Write(rawMessage))
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.CancelRead(0)
+	_, err = stream.Write(buffer.Bytes())
+	if err != nil {
+		stream.Close()
+		return nil, err
+	}
+	// the client must close its side of the stream after sending the query
+	stream.Close()
+	var length uint16
+	err = binary.Read(stream, binary.BigEndian, &length)
+	if err != nil {
+		return nil, err
+	}
+	responseBuffer := buf.NewSize(int(length))
+	defer responseBuffer.Release()
+	_, err = responseBuffer.ReadFullFrom(stream, int(length))
+	if err != nil {
+		return nil, err
+	}
+	var responseMessage mDNS.Msg
+	err = responseMessage.Unpack(responseBuffer.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return &responseMessage, nil
+}
+
+func (t *QUICTransport) Lookup(ctx context.Context, domain string, strategy dns.DomainStrategy) ([]netip.Addr, error) {
+	return nil, os.ErrInvalid
+}
+
+// https://github.com/AdguardTeam/dnsproxy/blob/fd1868577652c639cce3da00e12ca548f421baf1/upstream/upstream_quic.go#L394
+func isQUICRetryError(err error) bool {
+	var qAppErr *quic.ApplicationError
+	if errors.As(err, &qAppErr) && qAppErr.ErrorCode == 0 {
+		return true
+	}
+	var qIdleErr *quic.IdleTimeoutError
+	if errors.As(err, &qIdleErr) {
+		return true
+	}
+	var resetErr *quic.StatelessResetError
+	if errors.As(err, &resetErr) {
+		return true
+	}
+	var qTransportError *quic.TransportError
+	if errors.As(err, &qTransportError) && qTransportError.ErrorCode == quic.NoError {
+		return true
+	}
+	return errors.Is(err, quic.Err0RTTRejected)
+}
@@ -0,0 +1,25 @@
+//go:build !with_quic
+
+package dnstransport
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common/logger"
+	N "github.com/sagernet/sing/common/network"
+)
+
+func newHTTP3RoundTripper(dialer N.Dialer, tlsConfig *tls.Config, idleTimeout time.Duration, statistics *connectionStatistics) (http.RoundTripper, error) {
+	return nil, C.ErrQUICNotIncluded
+}
+
+func NewQUICTransport(ctx context.Context, logger logger.ContextLogger, name string, dialer N.Dialer, serverURL *url.URL, options option.DNSServerOptions) (dns.Transport, error) {
+	return nil, C.ErrQUICNotIncluded
+}
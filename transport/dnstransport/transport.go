@@ -0,0 +1,185 @@
+package dnstransport
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common/atomic"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// IsTuned reports whether the server uses options only supported by transports in this package.
+func IsTuned(options option.DNSServerOptions) bool {
+	return options.TLS != nil || options.PreferHTTP3 || options.IdleTimeout > 0 || options.MaxConcurrentQueries > 0
+}
+
+func NewTransport(ctx context.Context, logger logger.ContextLogger, name string, dialer N.Dialer, options option.DNSServerOptions) (dns.Transport, error) {
+	serverURL, err := url.Parse(options.Address)
+	if err != nil {
+		return nil, err
+	}
+	switch serverURL.Scheme {
+	case "https":
+		return NewHTTPSTransport(ctx, logger, name, dialer, serverURL, options)
+	case "h3":
+		return NewHTTP3Transport(ctx, logger, name, dialer, serverURL, options)
+	case "quic":
+		return NewQUICTransport(ctx, logger, name, dialer, serverURL, options)
+	default:
+		return nil, E.New("tls, prefer_http3, idle_timeout and max_concurrent_queries are only supported by https, h3 and quic servers")
+	}
+}
+
+func newTLSConfig(ctx context.Context, serverURL *url.URL, options *option.DNSServerTLSOptions, nextProtos []string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName: serverURL.Hostname(),
+		NextProtos: nextProtos,
+	}
+	if router := adapter.RouterFromContext(ctx); router != nil {
+		tlsConfig.Time = router.TimeFunc()
+	}
+	if options == nil {
+		return tlsConfig, nil
+	}
+	if options.ServerName != "" {
+		tlsConfig.ServerName = options.ServerName
+	}
+	tlsConfig.InsecureSkipVerify = options.Insecure
+	certificate, err := readPEM(options.Certificate, options.CertificatePath)
+	if err != nil {
+		return nil, E.Cause(err, "read certificate")
+	}
+	if len(certificate) > 0 {
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(certificate) {
+			return nil, E.New("failed to parse certificate:\n\n", certificate)
+		}
+		tlsConfig.RootCAs = certPool
+	}
+	clientCertificate, err := readPEM(options.ClientCertificate, options.ClientCertificatePath)
+	if err != nil {
+		return nil, E.Cause(err, "read client certificate")
+	}
+	clientKey, err := readPEM(options.ClientKey, options.ClientKeyPath)
+	if err != nil {
+		return nil, E.Cause(err, "read client key")
+	}
+	if len(clientCertificate) > 0 || len(clientKey) > 0 {
+		keyPair, err := tls.X509KeyPair(clientCertificate, clientKey)
+		if err != nil {
+			return nil, E.Cause(err, "parse client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{keyPair}
+	}
+	if len(options.CertificateSHA256) > 0 {
+		var pinnedHashes [][]byte
+		for _, hashString := range options.CertificateSHA256 {
+			hash, err := parseCertificateHash(hashString)
+			if err != nil {
+				return nil, err
+			}
+			pinnedHashes = append(pinnedHashes, hash)
+		}
+		// pinned certificates replace CA verification
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return E.New("missing peer certificate")
+			}
+			hash := sha256.Sum256(state.PeerCertificates[0].Raw)
+			for _, pinnedHash := range pinnedHashes {
+				if bytes.Equal(hash[:], pinnedHash) {
+					return nil
+				}
+			}
+			return E.New("certificate hash mismatch: ", hex.EncodeToString(hash[:]))
+		}
+	}
+	return tlsConfig, nil
+}
+
+func readPEM(content string, path string) ([]byte, error) {
+	if content != "" {
+		return []byte(content), nil
+	} else if path != "" {
+		return os.ReadFile(path)
+	}
+	return nil, nil
+}
+
+func parseCertificateHash(hashString string) ([]byte, error) {
+	hash, err := hex.DecodeString(strings.ReplaceAll(hashString, ":", ""))
+	if err != nil {
+		hash, err = base64.StdEncoding.DecodeString(hashString)
+	}
+	if err != nil || len(hash) != sha256.Size {
+		return nil, E.New("invalid certificate_sha256: ", hashString)
+	}
+	return hash, nil
+}
+
+// queryLimiter bounds the number of in-flight queries of a transport.
+type queryLimiter chan struct{}
+
+func newQueryLimiter(maxConcurrentQueries int) queryLimiter {
+	if maxConcurrentQueries <= 0 {
+		return nil
+	}
+	return make(queryLimiter, maxConcurrentQueries)
+}
+
+func (l queryLimiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case l <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l queryLimiter) release() {
+	if l == nil {
+		return
+	}
+	<-l
+}
+
+type connectionStatistics struct {
+	connections   atomic.Int64
+	queries       atomic.Int64
+	reusedQueries atomic.Int64
+	failures      atomic.Int64
+}
+
+func (s *connectionStatistics) ConnectionStatistics() adapter.DNSConnectionStatistics {
+	return adapter.DNSConnectionStatistics{
+		Connections:   s.connections.Load(),
+		Queries:       s.queries.Load(),
+		ReusedQueries: s.reusedQueries.Load(),
+		Failures:      s.failures.Load(),
+	}
+}
+
+func idleTimeout(options option.DNSServerOptions, defaultTimeout time.Duration) time.Duration {
+	if options.IdleTimeout > 0 {
+		return time.Duration(options.IdleTimeout)
+	}
+	return defaultTimeout
+}
@@ -0,0 +1,56 @@
+package fakeip
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/domain"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// Exclude matches domains that must always be resolved to real addresses.
+type Exclude struct {
+	matcher  *domain.Matcher
+	keywords []string
+	regexes  []*regexp.Regexp
+}
+
+func NewExclude(options option.DNSFakeIPOptions) (*Exclude, error) {
+	if len(options.ExcludeDomain) == 0 && len(options.ExcludeDomainSuffix) == 0 && len(options.ExcludeDomainKeyword) == 0 && len(options.ExcludeDomainRegex) == 0 {
+		return nil, nil
+	}
+	exclude := &Exclude{
+		keywords: common.Map(options.ExcludeDomainKeyword, strings.ToLower),
+	}
+	if len(options.ExcludeDomain) > 0 || len(options.ExcludeDomainSuffix) > 0 {
+		exclude.matcher = domain.NewMatcher(options.ExcludeDomain, options.ExcludeDomainSuffix)
+	}
+	for i, regex := range options.ExcludeDomainRegex {
+		matcher, err := regexp.Compile(regex)
+		if err != nil {
+			return nil, E.Cause(err, "parse exclude_domain_regex[", i, "]")
+		}
+		exclude.regexes = append(exclude.regexes, matcher)
+	}
+	return exclude, nil
+}
+
+func (e *Exclude) Match(domain string) bool {
+	domain = strings.ToLower(domain)
+	if e.matcher != nil && e.matcher.Match(domain) {
+		return true
+	}
+	for _, keyword := range e.keywords {
+		if strings.Contains(domain, keyword) {
+			return true
+		}
+	}
+	for _, regex := range e.regexes {
+		if regex.MatchString(domain) {
+			return true
+		}
+	}
+	return false
+}
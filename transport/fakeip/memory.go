@@ -37,12 +37,15 @@ func (s *MemoryStorage) FakeIPSaveMetadata(metadata *adapter.FakeIPMetadata) err
 func (s *MemoryStorage) FakeIPStore(address netip.Addr, domain string) error {
 	s.addressAccess.Lock()
 	s.domainAccess.Lock()
-	s.addressCache[address] = domain
-	if address.Is4() {
-		s.domainCache4[domain] = address
-	} else {
-		s.domainCache6[domain] = address
+	domainCache := s.domainCache4
+	if !address.Is4() {
+		domainCache = s.domainCache6
 	}
+	if oldDomain, loaded := s.addressCache[address]; loaded && domainCache[oldDomain] == address {
+		delete(domainCache, oldDomain)
+	}
+	s.addressCache[address] = domain
+	domainCache[domain] = address
 	s.domainAccess.Unlock()
 	s.addressAccess.Unlock()
 	return nil
@@ -71,9 +74,23 @@ func (s *MemoryStorage) FakeIPLoadDomain(domain string, isIPv6 bool) (netip.Addr
 	}
 }
 
+func (s *MemoryStorage) FakeIPMappings() map[netip.Addr]string {
+	s.addressAccess.RLock()
+	defer s.addressAccess.RUnlock()
+	mappings := make(map[netip.Addr]string, len(s.addressCache))
+	for address, domain := range s.addressCache {
+		mappings[address] = domain
+	}
+	return mappings
+}
+
 func (s *MemoryStorage) FakeIPReset() error {
+	s.addressAccess.Lock()
+	s.domainAccess.Lock()
 	s.addressCache = make(map[netip.Addr]string)
 	s.domainCache4 = make(map[string]netip.Addr)
 	s.domainCache6 = make(map[string]netip.Addr)
+	s.domainAccess.Unlock()
+	s.addressAccess.Unlock()
 	return nil
 }
@@ -0,0 +1,88 @@
+package fakeip
+
+import (
+	"math"
+	"net/netip"
+
+	"github.com/sagernet/sing/common/x/list"
+)
+
+// pool allocates addresses sequentially from a range, and recycles the least
+// recently used address once the range is exhausted.
+type pool struct {
+	prefix   netip.Prefix
+	current  netip.Addr
+	capacity int
+	lru      list.List[netip.Addr]
+	elements map[netip.Addr]*list.Element[netip.Addr]
+}
+
+func newPool(prefix netip.Prefix) *pool {
+	p := &pool{
+		prefix:   prefix,
+		elements: make(map[netip.Addr]*list.Element[netip.Addr]),
+	}
+	if prefix.IsValid() {
+		p.current = p.first()
+		hostBits := prefix.Addr().BitLen() - prefix.Bits()
+		if hostBits >= 62 {
+			p.capacity = math.MaxInt
+		} else {
+			p.capacity = 1<<hostBits - 2
+		}
+	}
+	return p
+}
+
+func (p *pool) first() netip.Addr {
+	return p.prefix.Addr().Next().Next()
+}
+
+// allocate returns the next address, which may still be mapped to another domain.
+func (p *pool) allocate() netip.Addr {
+	if p.capacity <= 0 {
+		return netip.Addr{}
+	}
+	next := p.current.Next()
+	if p.prefix.Contains(next) {
+		if _, used := p.elements[next]; !used {
+			p.current = next
+			p.elements[next] = p.lru.PushBack(next)
+			return next
+		}
+	}
+	if len(p.elements) >= p.capacity {
+		element := p.lru.Front()
+		p.lru.MoveToBack(element)
+		return element.Value
+	}
+	// addresses restored from storage are not tracked, reuse them in order
+	for {
+		if !p.prefix.Contains(next) {
+			next = p.first()
+		}
+		if _, used := p.elements[next]; !used {
+			break
+		}
+		next = next.Next()
+	}
+	p.current = next
+	p.elements[next] = p.lru.PushBack(next)
+	return next
+}
+
+func (p *pool) touch(address netip.Addr) {
+	if element, loaded := p.elements[address]; loaded {
+		p.lru.MoveToBack(element)
+	} else if p.prefix.Contains(address) && address.Compare(p.first()) >= 0 {
+		p.elements[address] = p.lru.PushBack(address)
+	}
+}
+
+func (p *pool) reset() {
+	if p.prefix.IsValid() {
+		p.current = p.first()
+	}
+	p.lru.Init()
+	p.elements = make(map[netip.Addr]*list.Element[netip.Addr])
+}
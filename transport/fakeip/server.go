@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/netip"
 	"os"
+	"strings"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-dns"
@@ -19,6 +20,9 @@ var (
 	_ adapter.FakeIPTransport = (*Transport)(nil)
 )
 
+// ErrExcluded is returned for domains that must be resolved to real addresses by another server.
+var ErrExcluded = E.New("domain excluded from fakeip")
+
 func init() {
 	dns.RegisterTransport([]string{"fakeip"}, NewTransport)
 }
@@ -63,10 +67,16 @@ func (s *Transport) Raw() bool {
 }
 
 func (s *Transport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	if len(message.Question) > 0 && s.store.Excluded(strings.TrimSuffix(message.Question[0].Name, ".")) {
+		return nil, ErrExcluded
+	}
 	return nil, os.ErrInvalid
 }
 
 func (s *Transport) Lookup(ctx context.Context, domain string, strategy dns.DomainStrategy) ([]netip.Addr, error) {
+	if s.store.Excluded(domain) {
+		return nil, ErrExcluded
+	}
 	var addresses []netip.Addr
 	if strategy != dns.DomainStrategyUseIPv6 {
 		inet4Address, err := s.store.Create(domain, false)
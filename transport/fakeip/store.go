@@ -2,6 +2,7 @@ package fakeip
 
 import (
 	"net/netip"
+	"sync"
 
 	"github.com/sagernet/sing-box/adapter"
 	E "github.com/sagernet/sing/common/exceptions"
@@ -11,21 +12,26 @@ import (
 var _ adapter.FakeIPStore = (*Store)(nil)
 
 type Store struct {
-	router       adapter.Router
-	logger       logger.Logger
-	inet4Range   netip.Prefix
-	inet6Range   netip.Prefix
-	storage      adapter.FakeIPStorage
-	inet4Current netip.Addr
-	inet6Current netip.Addr
+	router     adapter.Router
+	logger     logger.Logger
+	inet4Range netip.Prefix
+	inet6Range netip.Prefix
+	exclude    *Exclude
+	storage    adapter.FakeIPStorage
+	access     sync.Mutex
+	inet4Pool  *pool
+	inet6Pool  *pool
 }
 
-func NewStore(router adapter.Router, logger logger.Logger, inet4Range netip.Prefix, inet6Range netip.Prefix) *Store {
+func NewStore(router adapter.Router, logger logger.Logger, inet4Range netip.Prefix, inet6Range netip.Prefix, exclude *Exclude) *Store {
 	return &Store{
 		router:     router,
 		logger:     logger,
 		inet4Range: inet4Range,
 		inet6Range: inet6Range,
+		exclude:    exclude,
+		inet4Pool:  newPool(inet4Range),
+		inet6Pool:  newPool(inet6Range),
 	}
 }
 
@@ -41,15 +47,9 @@ func (s *Store) Start() error {
 	}
 	metadata := storage.FakeIPMetadata()
 	if metadata != nil && metadata.Inet4Range == s.inet4Range && metadata.Inet6Range == s.inet6Range {
-		s.inet4Current = metadata.Inet4Current
-		s.inet6Current = metadata.Inet6Current
+		s.inet4Pool.current = metadata.Inet4Current
+		s.inet6Pool.current = metadata.Inet6Current
 	} else {
-		if s.inet4Range.IsValid() {
-			s.inet4Current = s.inet4Range.Addr().Next().Next()
-		}
-		if s.inet6Range.IsValid() {
-			s.inet6Current = s.inet6Range.Addr().Next().Next()
-		}
 		_ = storage.FakeIPReset()
 	}
 	s.storage = storage
@@ -60,52 +60,77 @@ func (s *Store) Contains(address netip.Addr) bool {
 	return s.inet4Range.Contains(address) || s.inet6Range.Contains(address)
 }
 
+func (s *Store) Excluded(domain string) bool {
+	return s.exclude != nil && s.exclude.Match(domain)
+}
+
 func (s *Store) Close() error {
 	if s.storage == nil {
 		return nil
 	}
+	s.access.Lock()
+	defer s.access.Unlock()
 	return s.storage.FakeIPSaveMetadata(&adapter.FakeIPMetadata{
 		Inet4Range:   s.inet4Range,
 		Inet6Range:   s.inet6Range,
-		Inet4Current: s.inet4Current,
-		Inet6Current: s.inet6Current,
+		Inet4Current: s.inet4Pool.current,
+		Inet6Current: s.inet6Pool.current,
 	})
 }
 
 func (s *Store) Create(domain string, isIPv6 bool) (netip.Addr, error) {
+	s.access.Lock()
+	defer s.access.Unlock()
+	var addressPool *pool
+	if !isIPv6 {
+		addressPool = s.inet4Pool
+	} else {
+		addressPool = s.inet6Pool
+	}
 	if address, loaded := s.storage.FakeIPLoadDomain(domain, isIPv6); loaded {
+		addressPool.touch(address)
 		return address, nil
 	}
-	var address netip.Addr
-	if !isIPv6 {
-		if !s.inet4Current.IsValid() {
+	if !addressPool.prefix.IsValid() {
+		if !isIPv6 {
 			return netip.Addr{}, E.New("missing IPv4 fakeip address range")
-		}
-		nextAddress := s.inet4Current.Next()
-		if !s.inet4Range.Contains(nextAddress) {
-			nextAddress = s.inet4Range.Addr().Next().Next()
-		}
-		s.inet4Current = nextAddress
-		address = nextAddress
-	} else {
-		if !s.inet6Current.IsValid() {
+		} else {
 			return netip.Addr{}, E.New("missing IPv6 fakeip address range")
 		}
-		nextAddress := s.inet6Current.Next()
-		if !s.inet6Range.Contains(nextAddress) {
-			nextAddress = s.inet6Range.Addr().Next().Next()
-		}
-		s.inet6Current = nextAddress
-		address = nextAddress
+	}
+	address := addressPool.allocate()
+	if !address.IsValid() {
+		return netip.Addr{}, E.New("fakeip address range too small: ", addressPool.prefix)
+	}
+	if oldDomain, loaded := s.storage.FakeIPLoad(address); loaded && oldDomain != domain {
+		s.logger.Debug("recycle fakeip address ", address, " from ", oldDomain)
 	}
 	s.storage.FakeIPStoreAsync(address, domain, s.logger)
 	return address, nil
 }
 
 func (s *Store) Lookup(address netip.Addr) (string, bool) {
-	return s.storage.FakeIPLoad(address)
+	domain, loaded := s.storage.FakeIPLoad(address)
+	if loaded {
+		s.access.Lock()
+		if address.Is4() {
+			s.inet4Pool.touch(address)
+		} else {
+			s.inet6Pool.touch(address)
+		}
+		s.access.Unlock()
+	}
+	return domain, loaded
+}
+
+func (s *Store) Mappings() map[netip.Addr]string {
+	return s.storage.FakeIPMappings()
 }
 
 func (s *Store) Reset() error {
+	s.access.Lock()
+	defer s.access.Unlock()
+	s.inet4Pool.reset()
+	s.inet6Pool.reset()
 	return s.storage.FakeIPReset()
 }
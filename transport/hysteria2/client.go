@@ -0,0 +1,353 @@
+package hysteria2
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"runtime"
+	"sync"
+
+	"github.com/sagernet/quic-go"
+	"github.com/sagernet/quic-go/http3"
+	"github.com/sagernet/sing-box/common/baderror"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+type ClientOptions struct {
+	Context            context.Context
+	Dialer             N.Dialer
+	Logger             logger.Logger
+	ServerAddress      M.Socksaddr
+	SendBPS            uint64
+	ReceiveBPS         uint64
+	SalamanderPassword string
+	Password           string
+	TLSConfig          *tls.Config
+}
+
+type Client struct {
+	ctx                context.Context
+	dialer             N.Dialer
+	logger             logger.Logger
+	serverAddr         M.Socksaddr
+	sendBPS            uint64
+	receiveBPS         uint64
+	salamanderPassword string
+	password           string
+	tlsConfig          *tls.Config
+	quicConfig         *quic.Config
+
+	connAccess sync.RWMutex
+	conn       *clientQUICConnection
+}
+
+func NewClient(options ClientOptions) (*Client, error) {
+	tlsConfig := options.TLSConfig.Clone()
+	if len(tlsConfig.NextProtos) == 0 {
+		tlsConfig.NextProtos = []string{http3.NextProtoH3}
+	}
+	if tlsConfig.MinVersion < tls.VersionTLS13 {
+		tlsConfig.MinVersion = tls.VersionTLS13
+	}
+	quicConfig := &quic.Config{
+		DisablePathMTUDiscovery: !(runtime.GOOS == "windows" || runtime.GOOS == "linux" || runtime.GOOS == "android"),
+		MaxDatagramFrameSize:    1400,
+		EnableDatagrams:         true,
+	}
+	return &Client{
+		ctx:                options.Context,
+		dialer:             options.Dialer,
+		logger:             options.Logger,
+		serverAddr:         options.ServerAddress,
+		sendBPS:            options.SendBPS,
+		receiveBPS:         options.ReceiveBPS,
+		salamanderPassword: options.SalamanderPassword,
+		password:           options.Password,
+		tlsConfig:          tlsConfig,
+		quicConfig:         quicConfig,
+	}, nil
+}
+
+func (c *Client) offer(ctx context.Context) (*clientQUICConnection, error) {
+	conn := c.conn
+	if conn != nil && conn.active() {
+		return conn, nil
+	}
+	c.connAccess.Lock()
+	defer c.connAccess.Unlock()
+	conn = c.conn
+	if conn != nil && conn.active() {
+		return conn, nil
+	}
+	conn, err := c.offerNew(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (c *Client) offerNew(ctx context.Context) (*clientQUICConnection, error) {
+	udpConn, err := c.dialer.DialContext(ctx, "udp", c.serverAddr)
+	if err != nil {
+		return nil, err
+	}
+	var packetConn net.PacketConn = bufio.NewUnbindPacketConn(udpConn)
+	if c.salamanderPassword != "" {
+		packetConn, err = NewSalamanderConn(packetConn, []byte(c.salamanderPassword))
+		if err != nil {
+			udpConn.Close()
+			return nil, err
+		}
+	}
+	var quicConn quic.EarlyConnection
+	http3Transport := &http3.RoundTripper{
+		TLSClientConfig: c.tlsConfig,
+		QuicConfig:      c.quicConfig,
+		EnableDatagrams: true,
+		Dial: func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
+			earlyConn, err := quic.DialEarly(ctx, packetConn, udpConn.RemoteAddr(), tlsCfg, cfg)
+			if err != nil {
+				return nil, err
+			}
+			quicConn = earlyConn
+			return earlyConn, nil
+		},
+	}
+	request := &http.Request{
+		Method: http.MethodPost,
+		URL: &url.URL{
+			Scheme: "https",
+			Host:   URLHost,
+			Path:   URLPath,
+		},
+		Header: make(http.Header),
+	}
+	writeAuthRequest(request.Header, AuthRequest{Auth: c.password, Rx: c.receiveBPS})
+	response, err := http3Transport.RoundTrip(request.WithContext(ctx))
+	if err != nil {
+		if quicConn != nil {
+			quicConn.CloseWithError(0, "")
+		}
+		udpConn.Close()
+		return nil, E.Cause(err, "authenticate")
+	}
+	response.Body.Close()
+	if response.StatusCode != StatusAuthOK {
+		quicConn.CloseWithError(0, "")
+		udpConn.Close()
+		return nil, E.New("authenticate: unexpected status code: ", response.StatusCode)
+	}
+	authResponse := readAuthResponse(response.Header)
+	actualTx := authResponse.Rx
+	if authResponse.RxAuto {
+		actualTx = 0
+	} else if actualTx == 0 || actualTx > c.sendBPS {
+		actualTx = c.sendBPS
+	}
+	setCongestion(quicConn, actualTx)
+	if actualTx > 0 {
+		c.logger.Debug("use brutal congestion control at ", actualTx, " Bps")
+	} else {
+		c.logger.Debug("use bbr congestion control")
+	}
+	conn := &clientQUICConnection{
+		quicConn:    quicConn,
+		rawConn:     udpConn,
+		connDone:    make(chan struct{}),
+		udpDisabled: !authResponse.UDPEnabled,
+		udpConnMap:  make(map[uint32]*udpPacketConn),
+	}
+	if authResponse.UDPEnabled {
+		go c.loopMessages(conn)
+	}
+	c.conn = conn
+	return conn, nil
+}
+
+func (c *Client) DialConn(ctx context.Context, destination M.Socksaddr) (net.Conn, error) {
+	conn, err := c.offer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := conn.quicConn.OpenStream()
+	if err != nil {
+		return nil, err
+	}
+	err = writeTCPRequest(stream, destination)
+	if err != nil {
+		stream.CancelRead(0)
+		stream.Close()
+		return nil, err
+	}
+	return &clientConn{
+		Stream:      stream,
+		destination: destination,
+	}, nil
+}
+
+func (c *Client) ListenPacket(ctx context.Context) (net.PacketConn, error) {
+	conn, err := c.offer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if conn.udpDisabled {
+		return nil, E.New("UDP disabled by server")
+	}
+	var sessionID uint32
+	clientPacketConn := newUDPPacketConn(c.ctx, conn.quicConn, 0, func() {
+		conn.udpAccess.Lock()
+		delete(conn.udpConnMap, sessionID)
+		conn.udpAccess.Unlock()
+	})
+	conn.udpAccess.Lock()
+	for {
+		sessionID = rand.Uint32()
+		if _, exists := conn.udpConnMap[sessionID]; !exists {
+			clientPacketConn.sessionID = sessionID
+			conn.udpConnMap[sessionID] = clientPacketConn
+			break
+		}
+	}
+	conn.udpAccess.Unlock()
+	return clientPacketConn, nil
+}
+
+func (c *Client) CloseWithError(err error) error {
+	conn := c.conn
+	if conn != nil {
+		conn.closeWithError(err)
+	}
+	return nil
+}
+
+func (c *Client) loopMessages(conn *clientQUICConnection) {
+	for {
+		message, err := conn.quicConn.ReceiveMessage()
+		if err != nil {
+			conn.closeWithError(E.Cause(err, "receive message"))
+			return
+		}
+		hErr := conn.handleMessage(message)
+		if hErr != nil {
+			conn.closeWithError(E.Cause(hErr, "handle message"))
+			return
+		}
+	}
+}
+
+type clientQUICConnection struct {
+	quicConn    quic.Connection
+	rawConn     io.Closer
+	access      sync.Mutex
+	connDone    chan struct{}
+	connErr     error
+	udpDisabled bool
+	udpAccess   sync.RWMutex
+	udpConnMap  map[uint32]*udpPacketConn
+	defragger   defragger
+}
+
+func (c *clientQUICConnection) active() bool {
+	select {
+	case <-c.quicConn.Context().Done():
+		return false
+	default:
+	}
+	c.access.Lock()
+	defer c.access.Unlock()
+	select {
+	case <-c.connDone:
+		return false
+	default:
+	}
+	return true
+}
+
+func (c *clientQUICConnection) handleMessage(data []byte) error {
+	message := udpMessagePool.Get().(*udpMessage)
+	err := decodeUDPMessage(message, data)
+	if err != nil {
+		message.release()
+		return E.Cause(err, "decode UDP message")
+	}
+	c.udpAccess.RLock()
+	udpConn, loaded := c.udpConnMap[message.sessionID]
+	c.udpAccess.RUnlock()
+	if !loaded || common.Done(udpConn.ctx) {
+		message.releaseMessage()
+		return nil
+	}
+	newMessage := c.defragger.feed(message)
+	if newMessage != nil {
+		select {
+		case udpConn.data <- newMessage:
+		default:
+			newMessage.releaseMessage()
+		}
+	}
+	return nil
+}
+
+func (c *clientQUICConnection) closeWithError(err error) {
+	c.access.Lock()
+	defer c.access.Unlock()
+	select {
+	case <-c.connDone:
+		return
+	default:
+	}
+	c.connErr = err
+	close(c.connDone)
+	_ = c.quicConn.CloseWithError(0, "")
+	_ = c.rawConn.Close()
+}
+
+type clientConn struct {
+	quic.Stream
+	destination    M.Socksaddr
+	responseRead   bool
+	responseAccess sync.Mutex
+}
+
+func (c *clientConn) Read(p []byte) (n int, err error) {
+	if !c.responseRead {
+		c.responseAccess.Lock()
+		if !c.responseRead {
+			err = readTCPResponse(c.Stream)
+			c.responseRead = true
+		}
+		c.responseAccess.Unlock()
+		if err != nil {
+			return 0, baderror.WrapQUIC(err)
+		}
+	}
+	n, err = c.Stream.Read(p)
+	return n, baderror.WrapQUIC(err)
+}
+
+func (c *clientConn) Write(p []byte) (n int, err error) {
+	n, err = c.Stream.Write(p)
+	return n, baderror.WrapQUIC(err)
+}
+
+func (c *clientConn) LocalAddr() net.Addr {
+	return M.Socksaddr{}
+}
+
+func (c *clientConn) RemoteAddr() net.Addr {
+	return c.destination
+}
+
+func (c *clientConn) Close() error {
+	c.Stream.CancelRead(0)
+	return c.Stream.Close()
+}
@@ -0,0 +1,24 @@
+package hysteria2
+
+import (
+	"github.com/sagernet/quic-go"
+	"github.com/sagernet/quic-go/congestion"
+	"github.com/sagernet/sing-box/transport/hysteria"
+	tuicCongestion "github.com/sagernet/sing-box/transport/tuic/congestion"
+)
+
+// setCongestion uses Brutal with the negotiated send rate, or BBR if the rate is unknown.
+func setCongestion(connection quic.Connection, sendBPS uint64) {
+	if sendBPS > 0 {
+		connection.SetCongestionControl(hysteria.NewBrutalSender(congestion.ByteCount(sendBPS)))
+	} else {
+		connection.SetCongestionControl(
+			tuicCongestion.NewBBRSender(
+				tuicCongestion.DefaultClock{},
+				tuicCongestion.GetInitialPacketSize(connection.RemoteAddr()),
+				10*tuicCongestion.InitialMaxDatagramSize,
+				tuicCongestion.DefaultBBRMaxCongestionWindow*tuicCongestion.InitialMaxDatagramSize,
+			),
+		)
+	}
+}
@@ -0,0 +1,227 @@
+package hysteria2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+
+	"github.com/sagernet/quic-go/quicvarint"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+const (
+	URLHost = "hysteria"
+	URLPath = "/auth"
+
+	RequestHeaderAuth   = "Hysteria-Auth"
+	ResponseHeaderUDP   = "Hysteria-UDP"
+	CommonHeaderCCRX    = "Hysteria-CC-RX"
+	CommonHeaderPadding = "Hysteria-Padding"
+	CommonValueCCRXAuto = "auto"
+	StatusAuthOK        = 233
+	FrameTypeTCPRequest = 0x401
+	MaxAddressLength    = 2048
+	MaxMessageLength    = 2048
+	MaxPaddingLength    = 4096
+)
+
+const (
+	tcpResponseStatusOK = iota
+	tcpResponseStatusError
+)
+
+type paddingRange struct {
+	min int
+	max int
+}
+
+func (r paddingRange) String() string {
+	return string(r.Bytes())
+}
+
+func (r paddingRange) Bytes() []byte {
+	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	padding := make([]byte, r.min+rand.Intn(r.max-r.min))
+	for i := range padding {
+		padding[i] = letters[rand.Intn(len(letters))]
+	}
+	return padding
+}
+
+var (
+	authRequestPadding  = paddingRange{256, 2048}
+	authResponsePadding = paddingRange{256, 2048}
+	tcpRequestPadding   = paddingRange{64, 512}
+	tcpResponsePadding  = paddingRange{128, 1024}
+)
+
+type AuthRequest struct {
+	Auth string
+	Rx   uint64
+}
+
+type AuthResponse struct {
+	UDPEnabled bool
+	Rx         uint64
+	RxAuto     bool
+}
+
+func readAuthRequest(request *http.Request) AuthRequest {
+	rx, _ := strconv.ParseUint(request.Header.Get(CommonHeaderCCRX), 10, 64)
+	return AuthRequest{
+		Auth: request.Header.Get(RequestHeaderAuth),
+		Rx:   rx,
+	}
+}
+
+func writeAuthRequest(header http.Header, request AuthRequest) {
+	header.Set(RequestHeaderAuth, request.Auth)
+	header.Set(CommonHeaderCCRX, strconv.FormatUint(request.Rx, 10))
+	header.Set(CommonHeaderPadding, authRequestPadding.String())
+}
+
+func readAuthResponse(header http.Header) AuthResponse {
+	var response AuthResponse
+	response.UDPEnabled, _ = strconv.ParseBool(header.Get(ResponseHeaderUDP))
+	rxString := header.Get(CommonHeaderCCRX)
+	if rxString == CommonValueCCRXAuto {
+		response.RxAuto = true
+	} else {
+		response.Rx, _ = strconv.ParseUint(rxString, 10, 64)
+	}
+	return response
+}
+
+func writeAuthResponse(header http.Header, response AuthResponse) {
+	header.Set(ResponseHeaderUDP, strconv.FormatBool(response.UDPEnabled))
+	if response.RxAuto {
+		header.Set(CommonHeaderCCRX, CommonValueCCRXAuto)
+	} else {
+		header.Set(CommonHeaderCCRX, strconv.FormatUint(response.Rx, 10))
+	}
+	header.Set(CommonHeaderPadding, authResponsePadding.String())
+}
+
+// TCP request:
+// [varint frame type 0x401][varint address length][address][varint padding length][padding]
+
+func writeTCPRequest(writer io.Writer, destination M.Socksaddr) error {
+	address := destination.String()
+	padding := tcpRequestPadding.Bytes()
+	buffer := buf.NewSize(int(quicvarint.Len(FrameTypeTCPRequest)+quicvarint.Len(uint64(len(address)))+quicvarint.Len(uint64(len(padding)))) + len(address) + len(padding))
+	defer buffer.Release()
+	buffer.Write(quicvarint.Append(nil, FrameTypeTCPRequest))
+	buffer.Write(quicvarint.Append(nil, uint64(len(address))))
+	buffer.WriteString(address)
+	buffer.Write(quicvarint.Append(nil, uint64(len(padding))))
+	buffer.Write(padding)
+	return common.Error(writer.Write(buffer.Bytes()))
+}
+
+// readTCPRequest reads the request after the frame type, which is consumed by the HTTP/3 server.
+func readTCPRequest(reader io.Reader) (M.Socksaddr, error) {
+	varintReader := quicvarint.NewReader(reader)
+	address, err := readString(varintReader, MaxAddressLength, "address")
+	if err != nil {
+		return M.Socksaddr{}, err
+	}
+	_, err = readString(varintReader, MaxPaddingLength, "padding")
+	if err != nil {
+		return M.Socksaddr{}, err
+	}
+	destination := M.ParseSocksaddr(address)
+	if !destination.IsValid() || destination.Port == 0 {
+		return M.Socksaddr{}, E.New("invalid destination: ", address)
+	}
+	return destination, nil
+}
+
+// TCP response:
+// [uint8 status][varint message length][message][varint padding length][padding]
+
+func writeTCPResponse(writer io.Writer, ok bool, message string) error {
+	padding := tcpResponsePadding.Bytes()
+	var buffer bytes.Buffer
+	if ok {
+		buffer.WriteByte(tcpResponseStatusOK)
+	} else {
+		buffer.WriteByte(tcpResponseStatusError)
+	}
+	buffer.Write(quicvarint.Append(nil, uint64(len(message))))
+	buffer.WriteString(message)
+	buffer.Write(quicvarint.Append(nil, uint64(len(padding))))
+	buffer.Write(padding)
+	return common.Error(writer.Write(buffer.Bytes()))
+}
+
+func readTCPResponse(reader io.Reader) error {
+	varintReader := quicvarint.NewReader(reader)
+	status, err := varintReader.ReadByte()
+	if err != nil {
+		return err
+	}
+	message, err := readString(varintReader, MaxMessageLength, "message")
+	if err != nil {
+		return err
+	}
+	_, err = readString(varintReader, MaxPaddingLength, "padding")
+	if err != nil {
+		return err
+	}
+	if status != tcpResponseStatusOK {
+		return E.New("remote error: ", message)
+	}
+	return nil
+}
+
+func readString(reader quicvarint.Reader, maxLength uint64, name string) (string, error) {
+	length, err := quicvarint.Read(reader)
+	if err != nil {
+		return "", err
+	}
+	if length > maxLength {
+		return "", E.New("invalid ", name, " length: ", length)
+	}
+	content := make([]byte, length)
+	_, err = io.ReadFull(reader, content)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// UDP message:
+// [uint32 session ID][uint16 packet ID][uint8 fragment ID][uint8 fragment count][varint address length][address][payload]
+
+func decodeUDPMessage(message *udpMessage, data []byte) error {
+	reader := bytes.NewReader(data)
+	err := binary.Read(reader, binary.BigEndian, &message.sessionID)
+	if err != nil {
+		return err
+	}
+	err = binary.Read(reader, binary.BigEndian, &message.packetID)
+	if err != nil {
+		return err
+	}
+	err = binary.Read(reader, binary.BigEndian, &message.fragmentID)
+	if err != nil {
+		return err
+	}
+	err = binary.Read(reader, binary.BigEndian, &message.fragmentTotal)
+	if err != nil {
+		return err
+	}
+	address, err := readString(quicvarint.NewReader(reader), MaxAddressLength, "address")
+	if err != nil {
+		return err
+	}
+	message.destination = M.ParseSocksaddr(address)
+	message.data = buf.As(data[len(data)-reader.Len():])
+	return nil
+}
@@ -0,0 +1,88 @@
+package hysteria2
+
+import (
+	"crypto/rand"
+	"net"
+
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	ObfsTypeSalamander = "salamander"
+
+	salamanderSaltLen = 8
+	salamanderMinKey  = 4
+)
+
+// SalamanderPacketConn obfuscates every packet with a random salt and the
+// BLAKE2b-256 hash of the password and the salt.
+type SalamanderPacketConn struct {
+	net.PacketConn
+	password []byte
+}
+
+func NewSalamanderConn(conn net.PacketConn, password []byte) (*SalamanderPacketConn, error) {
+	if len(password) < salamanderMinKey {
+		return nil, E.New("salamander: password too short")
+	}
+	return &SalamanderPacketConn{
+		PacketConn: conn,
+		password:   password,
+	}, nil
+}
+
+func (c *SalamanderPacketConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	buffer := buf.NewSize(len(p) + salamanderSaltLen)
+	defer buffer.Release()
+	for {
+		buffer.FullReset()
+		n, addr, err = c.PacketConn.ReadFrom(buffer.FreeBytes())
+		if err != nil {
+			return
+		}
+		if n <= salamanderSaltLen {
+			// invalid packet, drop silently
+			continue
+		}
+		buffer.Truncate(n)
+		key := c.key(buffer.To(salamanderSaltLen))
+		payload := buffer.From(salamanderSaltLen)
+		n = copy(p, payload)
+		for i := 0; i < n; i++ {
+			p[i] ^= key[i%blake2b.Size256]
+		}
+		return n, addr, nil
+	}
+}
+
+func (c *SalamanderPacketConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
+	buffer := buf.NewSize(len(p) + salamanderSaltLen)
+	defer buffer.Release()
+	salt := buffer.Extend(salamanderSaltLen)
+	common.Must1(rand.Read(salt))
+	key := c.key(salt)
+	payload := buffer.Extend(len(p))
+	for i := range p {
+		payload[i] = p[i] ^ key[i%blake2b.Size256]
+	}
+	_, err = c.PacketConn.WriteTo(buffer.Bytes(), addr)
+	if err != nil {
+		return
+	}
+	return len(p), nil
+}
+
+func (c *SalamanderPacketConn) key(salt []byte) [blake2b.Size256]byte {
+	material := make([]byte, 0, len(c.password)+len(salt))
+	material = append(material, c.password...)
+	material = append(material, salt...)
+	return blake2b.Sum256(material)
+}
+
+func (c *SalamanderPacketConn) Upstream() any {
+	return c.PacketConn
+}
@@ -0,0 +1,396 @@
+package hysteria2
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/sagernet/quic-go"
+	"github.com/sagernet/quic-go/http3"
+	"github.com/sagernet/sing-box/common/baderror"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/auth"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+type ServerOptions struct {
+	Context               context.Context
+	Logger                logger.Logger
+	SendBPS               uint64
+	ReceiveBPS            uint64
+	IgnoreClientBandwidth bool
+	SalamanderPassword    string
+	TLSConfig             *tls.Config
+	Users                 []User
+	Handler               ServerHandler
+	MasqueradeHandler     http.Handler
+}
+
+type User struct {
+	Name     string
+	Password string
+}
+
+type ServerHandler interface {
+	N.TCPConnectionHandler
+	N.UDPConnectionHandler
+}
+
+type Server struct {
+	ctx                   context.Context
+	logger                logger.Logger
+	sendBPS               uint64
+	receiveBPS            uint64
+	ignoreClientBandwidth bool
+	salamanderPassword    string
+	tlsConfig             *tls.Config
+	quicConfig            *quic.Config
+	userAccess            sync.RWMutex
+	userMap               map[string]User
+	handler               ServerHandler
+	masqueradeHandler     http.Handler
+	quicListener          io.Closer
+}
+
+func NewServer(options ServerOptions) (*Server, error) {
+	tlsConfig := options.TLSConfig.Clone()
+	if len(tlsConfig.NextProtos) == 0 {
+		tlsConfig.NextProtos = []string{http3.NextProtoH3}
+	}
+	if tlsConfig.MinVersion < tls.VersionTLS13 {
+		tlsConfig.MinVersion = tls.VersionTLS13
+	}
+	quicConfig := &quic.Config{
+		DisablePathMTUDiscovery: !(runtime.GOOS == "windows" || runtime.GOOS == "linux" || runtime.GOOS == "android"),
+		MaxDatagramFrameSize:    1400,
+		EnableDatagrams:         true,
+		MaxIncomingStreams:      1 << 60,
+		MaxIncomingUniStreams:   1 << 60,
+	}
+	userMap, err := newUserMap(options.Users)
+	if err != nil {
+		return nil, err
+	}
+	if options.MasqueradeHandler == nil {
+		options.MasqueradeHandler = http.NotFoundHandler()
+	}
+	return &Server{
+		ctx:                   options.Context,
+		logger:                options.Logger,
+		sendBPS:               options.SendBPS,
+		receiveBPS:            options.ReceiveBPS,
+		ignoreClientBandwidth: options.IgnoreClientBandwidth,
+		salamanderPassword:    options.SalamanderPassword,
+		tlsConfig:             tlsConfig,
+		quicConfig:            quicConfig,
+		userMap:               userMap,
+		handler:               options.Handler,
+		masqueradeHandler:     options.MasqueradeHandler,
+	}, nil
+}
+
+var ErrUserExists = E.New("user already exists")
+
+func newUserMap(users []User) (map[string]User, error) {
+	if len(users) == 0 {
+		return nil, E.New("missing users")
+	}
+	userMap := make(map[string]User)
+	for _, user := range users {
+		if oldUser, loaded := userMap[user.Password]; loaded {
+			return nil, E.Extend(ErrUserExists, "password used by ", oldUser.Name)
+		}
+		userMap[user.Password] = user
+	}
+	return userMap, nil
+}
+
+// UpdateUsers replaces the users accepted by new sessions.
+func (s *Server) UpdateUsers(users []User) error {
+	userMap, err := newUserMap(users)
+	if err != nil {
+		return err
+	}
+	s.userAccess.Lock()
+	s.userMap = userMap
+	s.userAccess.Unlock()
+	return nil
+}
+
+func (s *Server) Start(conn net.PacketConn) error {
+	if s.salamanderPassword != "" {
+		var err error
+		conn, err = NewSalamanderConn(conn, []byte(s.salamanderPassword))
+		if err != nil {
+			return err
+		}
+	}
+	listener, err := quic.ListenEarly(conn, s.tlsConfig, s.quicConfig)
+	if err != nil {
+		return err
+	}
+	s.quicListener = listener
+	go s.loopConnections(listener)
+	return nil
+}
+
+func (s *Server) Close() error {
+	return common.Close(
+		s.quicListener,
+	)
+}
+
+func (s *Server) loopConnections(listener *quic.EarlyListener) {
+	for {
+		connection, err := listener.Accept(s.ctx)
+		if err != nil {
+			if E.IsClosedOrCanceled(err) || strings.Contains(err.Error(), "server closed") {
+				s.logger.Debug(E.Cause(err, "listener closed"))
+			} else {
+				s.logger.Error(E.Cause(err, "listener closed"))
+			}
+			return
+		}
+		go s.handleConnection(connection)
+	}
+}
+
+func (s *Server) handleConnection(connection quic.Connection) {
+	session := &serverSession{
+		Server:     s,
+		ctx:        s.ctx,
+		quicConn:   connection,
+		source:     M.SocksaddrFromNet(connection.RemoteAddr()),
+		connDone:   make(chan struct{}),
+		authDone:   make(chan struct{}),
+		udpConnMap: make(map[uint32]*udpPacketConn),
+	}
+	httpServer := http3.Server{
+		Handler:         session,
+		StreamHijacker:  session.handleStream,
+		EnableDatagrams: true,
+	}
+	err := httpServer.ServeQUICConn(connection)
+	if err != nil {
+		session.closeWithError(err)
+	} else {
+		session.closeWithError(net.ErrClosed)
+	}
+}
+
+type serverSession struct {
+	*Server
+	ctx        context.Context
+	quicConn   quic.Connection
+	source     M.Socksaddr
+	connAccess sync.Mutex
+	connDone   chan struct{}
+	connErr    error
+	authAccess sync.Mutex
+	authDone   chan struct{}
+	authUser   *User
+	udpAccess  sync.RWMutex
+	udpConnMap map[uint32]*udpPacketConn
+	defragger  defragger
+}
+
+func (s *serverSession) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost || request.Host != URLHost || request.URL.Path != URLPath {
+		s.masqueradeHandler.ServeHTTP(writer, request)
+		return
+	}
+	authRequest := readAuthRequest(request)
+	s.userAccess.RLock()
+	user, loaded := s.userMap[authRequest.Auth]
+	s.userAccess.RUnlock()
+	if !loaded {
+		s.masqueradeHandler.ServeHTTP(writer, request)
+		return
+	}
+	s.authAccess.Lock()
+	defer s.authAccess.Unlock()
+	select {
+	case <-s.authDone:
+	default:
+		var sendBPS uint64
+		if !s.ignoreClientBandwidth && authRequest.Rx > 0 {
+			sendBPS = authRequest.Rx
+			if s.sendBPS > 0 && sendBPS > s.sendBPS {
+				sendBPS = s.sendBPS
+			}
+		}
+		setCongestion(s.quicConn, sendBPS)
+		s.authUser = &user
+		close(s.authDone)
+		go s.loopMessages()
+	}
+	writeAuthResponse(writer.Header(), AuthResponse{
+		UDPEnabled: true,
+		Rx:         s.receiveBPS,
+		RxAuto:     s.ignoreClientBandwidth,
+	})
+	writer.WriteHeader(StatusAuthOK)
+}
+
+func (s *serverSession) handleStream(frameType http3.FrameType, connection quic.Connection, stream quic.Stream, err error) (bool, error) {
+	if err != nil || frameType != FrameTypeTCPRequest {
+		return false, nil
+	}
+	select {
+	case <-s.authDone:
+	default:
+		stream.CancelRead(0)
+		stream.Close()
+		return true, nil
+	}
+	go func() {
+		hErr := s.handleTCPRequest(stream)
+		if hErr != nil {
+			stream.CancelRead(0)
+			stream.Close()
+			s.logger.Error(E.Cause(hErr, "handle stream request"))
+		}
+	}()
+	return true, nil
+}
+
+func (s *serverSession) handleTCPRequest(stream quic.Stream) error {
+	destination, err := readTCPRequest(stream)
+	if err != nil {
+		return E.Cause(err, "read TCP request")
+	}
+	err = writeTCPResponse(stream, true, "")
+	if err != nil {
+		return E.Cause(err, "write TCP response")
+	}
+	ctx := s.ctx
+	if s.authUser.Name != "" {
+		ctx = auth.ContextWithUser(s.ctx, s.authUser.Name)
+	}
+	_ = s.handler.NewConnection(ctx, &serverConn{
+		Stream:      stream,
+		destination: destination,
+	}, M.Metadata{
+		Source:      s.source,
+		Destination: destination,
+	})
+	return nil
+}
+
+func (s *serverSession) loopMessages() {
+	for {
+		message, err := s.quicConn.ReceiveMessage()
+		if err != nil {
+			s.closeWithError(E.Cause(err, "receive message"))
+			return
+		}
+		hErr := s.handleMessage(message)
+		if hErr != nil {
+			s.closeWithError(E.Cause(hErr, "handle message"))
+			return
+		}
+	}
+}
+
+func (s *serverSession) handleMessage(data []byte) error {
+	message := udpMessagePool.Get().(*udpMessage)
+	err := decodeUDPMessage(message, data)
+	if err != nil {
+		message.release()
+		return E.Cause(err, "decode UDP message")
+	}
+	s.handleUDPMessage(message)
+	return nil
+}
+
+func (s *serverSession) handleUDPMessage(message *udpMessage) {
+	s.udpAccess.RLock()
+	udpConn, loaded := s.udpConnMap[message.sessionID]
+	s.udpAccess.RUnlock()
+	if !loaded || common.Done(udpConn.ctx) {
+		sessionID := message.sessionID
+		udpConn = newUDPPacketConn(s.ctx, s.quicConn, sessionID, func() {
+			s.udpAccess.Lock()
+			delete(s.udpConnMap, sessionID)
+			s.udpAccess.Unlock()
+		})
+		s.udpAccess.Lock()
+		s.udpConnMap[sessionID] = udpConn
+		s.udpAccess.Unlock()
+		ctx := s.ctx
+		if s.authUser.Name != "" {
+			ctx = auth.ContextWithUser(s.ctx, s.authUser.Name)
+		}
+		go s.handler.NewPacketConnection(ctx, udpConn, M.Metadata{
+			Source:      s.source,
+			Destination: message.destination,
+		})
+	}
+	newMessage := s.defragger.feed(message)
+	if newMessage != nil {
+		select {
+		case udpConn.data <- newMessage:
+		default:
+			newMessage.releaseMessage()
+		}
+	}
+}
+
+func (s *serverSession) closeWithError(err error) {
+	s.connAccess.Lock()
+	defer s.connAccess.Unlock()
+	select {
+	case <-s.connDone:
+		return
+	default:
+		s.connErr = err
+		close(s.connDone)
+	}
+	if E.IsClosedOrCanceled(err) {
+		s.logger.Debug(E.Cause(err, "connection failed"))
+	} else {
+		s.logger.Error(E.Cause(err, "connection failed"))
+	}
+	s.udpAccess.Lock()
+	for _, udpConn := range s.udpConnMap {
+		udpConn.closeWithError(err)
+	}
+	s.udpAccess.Unlock()
+	_ = s.quicConn.CloseWithError(0, "")
+}
+
+type serverConn struct {
+	quic.Stream
+	destination M.Socksaddr
+}
+
+func (c *serverConn) Read(p []byte) (n int, err error) {
+	n, err = c.Stream.Read(p)
+	return n, baderror.WrapQUIC(err)
+}
+
+func (c *serverConn) Write(p []byte) (n int, err error) {
+	n, err = c.Stream.Write(p)
+	return n, baderror.WrapQUIC(err)
+}
+
+func (c *serverConn) LocalAddr() net.Addr {
+	return c.destination
+}
+
+func (c *serverConn) RemoteAddr() net.Addr {
+	return M.Socksaddr{}
+}
+
+func (c *serverConn) Close() error {
+	c.Stream.CancelRead(0)
+	return c.Stream.Close()
+}
@@ -0,0 +1,120 @@
+package naive
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/transport/v2rayhttp"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"golang.org/x/net/http2"
+)
+
+type ClientOptions struct {
+	Dialer    N.Dialer
+	Server    M.Socksaddr
+	Username  string
+	Password  string
+	Headers   http.Header
+	TLSConfig tls.Config
+	QUIC      bool
+}
+
+// Client dials connections with HTTP/2 or HTTP/3 CONNECT requests as NaiveProxy does,
+// all connections are multiplexed over one connection to the server.
+type Client struct {
+	serverURL     url.URL
+	authorization string
+	headers       http.Header
+	transport     http.RoundTripper
+}
+
+func NewClient(options ClientOptions) (*Client, error) {
+	if options.TLSConfig == nil {
+		return nil, E.New("missing TLS configuration")
+	}
+	client := &Client{
+		serverURL: url.URL{
+			Scheme: "https",
+			Host:   options.Server.String(),
+		},
+		headers: options.Headers,
+	}
+	if client.headers == nil {
+		client.headers = make(http.Header)
+	}
+	if options.Username != "" {
+		client.authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(options.Username+":"+options.Password))
+	}
+	if options.QUIC {
+		if len(options.TLSConfig.NextProtos()) == 0 {
+			options.TLSConfig.SetNextProtos([]string{"h3"})
+		}
+		tlsConfig, err := options.TLSConfig.Config()
+		if err != nil {
+			return nil, err
+		}
+		client.transport, err = newHTTP3RoundTripper(options.Dialer, options.Server, tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if len(options.TLSConfig.NextProtos()) == 0 {
+			options.TLSConfig.SetNextProtos([]string{http2.NextProtoTLS})
+		}
+		client.transport = &http2.Transport{
+			DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.STDConfig) (net.Conn, error) {
+				conn, err := options.Dialer.DialContext(ctx, N.NetworkTCP, options.Server)
+				if err != nil {
+					return nil, err
+				}
+				return tls.ClientHandshake(ctx, conn, options.TLSConfig)
+			},
+		}
+	}
+	return client, nil
+}
+
+func (c *Client) DialContext(ctx context.Context, destination M.Socksaddr) (net.Conn, error) {
+	pipeReader, pipeWriter := io.Pipe()
+	request := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &c.serverURL,
+		Host:   destination.String(),
+		Header: c.headers.Clone(),
+		Body:   pipeReader,
+	}
+	request.Header.Set("Padding", GeneratePaddingHeader())
+	if c.authorization != "" {
+		request.Header.Set("Proxy-Authorization", c.authorization)
+	}
+	response, err := c.transport.RoundTrip(request.WithContext(ctx))
+	if err != nil {
+		pipeWriter.Close()
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		pipeWriter.Close()
+		return nil, E.New("unexpected status: ", response.Status)
+	}
+	if response.Header.Get("Padding") == "" {
+		response.Body.Close()
+		pipeWriter.Close()
+		return nil, E.New("missing naive padding")
+	}
+	return NewHTTPConn(response.Body, pipeWriter, nil), nil
+}
+
+func (c *Client) Close() error {
+	v2rayhttp.CloseIdleConnections(c.transport)
+	return common.Close(c.transport)
+}
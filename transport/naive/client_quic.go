@@ -0,0 +1,33 @@
+//go:build with_quic
+
+package naive
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/sagernet/quic-go"
+	"github.com/sagernet/quic-go/http3"
+	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing/common/bufio"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+func newHTTP3RoundTripper(dialer N.Dialer, serverAddr M.Socksaddr, tlsConfig *tls.STDConfig) (http.RoundTripper, error) {
+	return &http3.RoundTripper{
+		Dial: func(ctx context.Context, addr string, tlsCfg *tls.STDConfig, cfg *quic.Config) (quic.EarlyConnection, error) {
+			conn, err := dialer.DialContext(ctx, N.NetworkUDP, serverAddr)
+			if err != nil {
+				return nil, err
+			}
+			connection, err := quic.DialEarly(ctx, bufio.NewUnbindPacketConn(conn), conn.RemoteAddr(), tlsCfg, cfg)
+			if err != nil {
+				conn.Close()
+				return nil, err
+			}
+			return connection, nil
+		},
+		TLSClientConfig: tlsConfig,
+	}, nil
+}
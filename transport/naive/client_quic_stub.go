@@ -0,0 +1,16 @@
+//go:build !with_quic
+
+package naive
+
+import (
+	"net/http"
+
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+func newHTTP3RoundTripper(dialer N.Dialer, serverAddr M.Socksaddr, tlsConfig *tls.STDConfig) (http.RoundTripper, error) {
+	return nil, C.ErrQUICNotIncluded
+}
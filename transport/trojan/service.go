@@ -3,6 +3,7 @@ package trojan
 import (
 	"context"
 	"net"
+	"sync"
 
 	"github.com/sagernet/sing/common/auth"
 	"github.com/sagernet/sing/common/buf"
@@ -20,6 +21,7 @@ type Handler interface {
 }
 
 type Service[K comparable] struct {
+	userAccess      sync.RWMutex
 	users           map[K][56]byte
 	keys            map[[56]byte]K
 	handler         Handler
@@ -51,8 +53,10 @@ func (s *Service[K]) UpdateUsers(userList []K, passwordList []string) error {
 		users[user] = key
 		keys[key] = user
 	}
+	s.userAccess.Lock()
 	s.users = users
 	s.keys = keys
+	s.userAccess.Unlock()
 	return nil
 }
 
@@ -65,7 +69,10 @@ func (s *Service[K]) NewConnection(ctx context.Context, conn net.Conn, metadata
 		return s.fallback(ctx, conn, metadata, key[:n], E.New("bad request size"))
 	}
 
-	if user, loaded := s.keys[key]; loaded {
+	s.userAccess.RLock()
+	user, loaded := s.keys[key]
+	s.userAccess.RUnlock()
+	if loaded {
 		ctx = auth.ContextWithUser(ctx, user)
 	} else {
 		return s.fallback(ctx, conn, metadata, key[:], E.New("bad request"))
@@ -55,6 +55,7 @@ type Server struct {
 	tlsConfig         *tls.Config
 	heartbeat         time.Duration
 	quicConfig        *quic.Config
+	userAccess        sync.RWMutex
 	userMap           map[uuid.UUID]User
 	congestionControl string
 	authTimeout       time.Duration
@@ -85,12 +86,9 @@ func NewServer(options ServerOptions) (*Server, error) {
 	default:
 		return nil, E.New("unknown congestion control algorithm: ", options.CongestionControl)
 	}
-	if len(options.Users) == 0 {
-		return nil, E.New("missing users")
-	}
-	userMap := make(map[uuid.UUID]User)
-	for _, user := range options.Users {
-		userMap[user.UUID] = user
+	userMap, err := newUserMap(options.Users)
+	if err != nil {
+		return nil, err
 	}
 	return &Server{
 		ctx:               options.Context,
@@ -105,6 +103,29 @@ func NewServer(options ServerOptions) (*Server, error) {
 	}, nil
 }
 
+func newUserMap(users []User) (map[uuid.UUID]User, error) {
+	if len(users) == 0 {
+		return nil, E.New("missing users")
+	}
+	userMap := make(map[uuid.UUID]User)
+	for _, user := range users {
+		userMap[user.UUID] = user
+	}
+	return userMap, nil
+}
+
+// UpdateUsers replaces the users accepted by new sessions.
+func (s *Server) UpdateUsers(users []User) error {
+	userMap, err := newUserMap(users)
+	if err != nil {
+		return err
+	}
+	s.userAccess.Lock()
+	s.userMap = userMap
+	s.userAccess.Unlock()
+	return nil
+}
+
 func (s *Server) Start(conn net.PacketConn) error {
 	if !s.quicConfig.Allow0RTT {
 		listener, err := quic.Listen(conn, s.tlsConfig, s.quicConfig)
@@ -254,7 +275,9 @@ func (s *serverSession) handleUniStream(stream quic.ReceiveStream) error {
 			}
 		}
 		userUUID := uuid.FromBytesOrNil(buffer.Range(2, 2+16))
+		s.userAccess.RLock()
 		user, loaded := s.userMap[userUUID]
+		s.userAccess.RUnlock()
 		if !loaded {
 			return E.New("authentication: unknown user ", userUUID)
 		}
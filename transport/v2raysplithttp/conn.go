@@ -0,0 +1,190 @@
+package v2raysplithttp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/common/baderror"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+const (
+	maxUploadSize     = 1024 * 1024
+	maxPendingUploads = 32
+)
+
+// clientConn reads the response of a long-running GET request and writes with sequenced POST requests.
+type clientConn struct {
+	client     *Client
+	ctx        context.Context
+	cancel     context.CancelFunc
+	sessionURL url.URL
+	body       io.ReadCloser
+	access     sync.Mutex
+	seq        uint64
+}
+
+func newClientConn(client *Client, sessionURL url.URL) *clientConn {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &clientConn{
+		client:     client,
+		ctx:        ctx,
+		cancel:     cancel,
+		sessionURL: sessionURL,
+	}
+}
+
+func (c *clientConn) download(ctx context.Context) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.cancel()
+		case <-done:
+		}
+	}()
+	response, err := c.client.transport.RoundTrip(c.client.newRequest(c.ctx, http.MethodGet, &c.sessionURL))
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return E.New("unexpected status: ", response.Status)
+	}
+	c.body = response.Body
+	return nil
+}
+
+func (c *clientConn) Read(b []byte) (n int, err error) {
+	n, err = c.body.Read(b)
+	return n, baderror.WrapH2(err)
+}
+
+func (c *clientConn) Write(b []byte) (n int, err error) {
+	c.access.Lock()
+	defer c.access.Unlock()
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > maxUploadSize {
+			chunk = chunk[:maxUploadSize]
+		}
+		err = c.upload(chunk)
+		if err != nil {
+			return
+		}
+		n += len(chunk)
+		b = b[len(chunk):]
+	}
+	return
+}
+
+func (c *clientConn) upload(payload []byte) error {
+	uploadURL := c.sessionURL
+	uploadURL.Path += "/" + strconv.FormatUint(c.seq, 10)
+	request := c.client.newRequest(c.ctx, http.MethodPost, &uploadURL)
+	request.Body = io.NopCloser(bytes.NewReader(payload))
+	request.ContentLength = int64(len(payload))
+	response, err := c.client.transport.RoundTrip(request)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return E.New("unexpected upload status: ", response.Status)
+	}
+	c.seq++
+	return nil
+}
+
+func (c *clientConn) Close() error {
+	c.cancel()
+	if c.body != nil {
+		return c.body.Close()
+	}
+	return nil
+}
+
+func (c *clientConn) LocalAddr() net.Addr {
+	return nil
+}
+
+func (c *clientConn) RemoteAddr() net.Addr {
+	return nil
+}
+
+func (c *clientConn) SetDeadline(t time.Time) error {
+	return os.ErrInvalid
+}
+
+func (c *clientConn) SetReadDeadline(t time.Time) error {
+	return os.ErrInvalid
+}
+
+func (c *clientConn) SetWriteDeadline(t time.Time) error {
+	return os.ErrInvalid
+}
+
+func (c *clientConn) NeedAdditionalReadDeadline() bool {
+	return true
+}
+
+// session reorders uploads of a connection by sequence number.
+type session struct {
+	reader *io.PipeReader
+	writer *io.PipeWriter
+
+	access  sync.Mutex
+	nextSeq uint64
+	pending map[uint64][]byte
+}
+
+func newSession() *session {
+	reader, writer := io.Pipe()
+	return &session{
+		reader:  reader,
+		writer:  writer,
+		pending: make(map[uint64][]byte),
+	}
+}
+
+func (s *session) push(seq uint64, payload []byte) error {
+	s.access.Lock()
+	defer s.access.Unlock()
+	if seq < s.nextSeq {
+		return E.New("duplicate upload: ", seq)
+	}
+	if _, loaded := s.pending[seq]; loaded {
+		return E.New("duplicate upload: ", seq)
+	}
+	if len(s.pending) >= maxPendingUploads {
+		return E.New("too many pending uploads")
+	}
+	s.pending[seq] = payload
+	for {
+		payload, loaded := s.pending[s.nextSeq]
+		if !loaded {
+			return nil
+		}
+		delete(s.pending, s.nextSeq)
+		s.nextSeq++
+		_, err := s.writer.Write(payload)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *session) Close() error {
+	s.reader.Close()
+	return s.writer.Close()
+}
@@ -0,0 +1,224 @@
+package v2raysplithttp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/v2rayhttp"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	aTLS "github.com/sagernet/sing/common/tls"
+	sHttp "github.com/sagernet/sing/protocol/http"
+)
+
+var _ adapter.V2RayServerTransport = (*Server)(nil)
+
+type Server struct {
+	ctx        context.Context
+	tlsConfig  tls.ServerConfig
+	handler    adapter.V2RayServerTransportHandler
+	httpServer *http.Server
+	host       string
+	path       string
+	headers    http.Header
+
+	sessionAccess sync.Mutex
+	sessions      map[string]*session
+}
+
+func NewServer(ctx context.Context, options option.V2RaySplitHTTPOptions, tlsConfig tls.ServerConfig, handler adapter.V2RayServerTransportHandler) (*Server, error) {
+	server := &Server{
+		ctx:       ctx,
+		tlsConfig: tlsConfig,
+		handler:   handler,
+		host:      options.Host,
+		path:      normalizePath(options.Path),
+		headers:   make(http.Header),
+		sessions:  make(map[string]*session),
+	}
+	for key, value := range options.Headers {
+		server.headers[key] = value
+	}
+	server.httpServer = &http.Server{
+		Handler:           server,
+		ReadHeaderTimeout: C.TCPTimeout,
+		MaxHeaderBytes:    http.DefaultMaxHeaderBytes,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+	return server, nil
+}
+
+func (s *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if s.host != "" && request.Host != s.host {
+		s.fallbackRequest(request.Context(), writer, request, http.StatusBadRequest, E.New("bad host: ", request.Host))
+		return
+	}
+	if !strings.HasPrefix(request.URL.Path, s.path) {
+		s.fallbackRequest(request.Context(), writer, request, http.StatusNotFound, E.New("bad path: ", request.URL.Path))
+		return
+	}
+	pathParts := strings.Split(request.URL.Path[len(s.path):], "/")
+	switch {
+	case request.Method == http.MethodGet && len(pathParts) == 1 && pathParts[0] != "":
+		s.serveDownload(writer, request, pathParts[0])
+	case request.Method == http.MethodPost && len(pathParts) == 2 && pathParts[0] != "":
+		seq, err := strconv.ParseUint(pathParts[1], 10, 64)
+		if err != nil {
+			s.fallbackRequest(request.Context(), writer, request, http.StatusNotFound, E.New("bad sequence: ", pathParts[1]))
+			return
+		}
+		s.serveUpload(writer, request, pathParts[0], seq)
+	default:
+		s.fallbackRequest(request.Context(), writer, request, http.StatusNotFound, E.New("bad request: ", request.Method, " ", request.URL.Path))
+	}
+}
+
+func (s *Server) serveDownload(writer http.ResponseWriter, request *http.Request, sessionID string) {
+	session, loaded := s.startDownload(sessionID)
+	if !loaded {
+		writer.WriteHeader(http.StatusConflict)
+		s.handler.NewError(request.Context(), E.New("process connection from ", request.RemoteAddr, ": duplicate session"))
+		return
+	}
+	defer s.closeSession(sessionID, session)
+	flusher, isFlusher := writer.(http.Flusher)
+	if !isFlusher {
+		writer.WriteHeader(http.StatusInternalServerError)
+		s.handler.NewError(request.Context(), E.New("process connection from ", request.RemoteAddr, ": response does not support flushing"))
+		return
+	}
+	for key, values := range s.headers {
+		for _, value := range values {
+			writer.Header().Set(key, value)
+		}
+	}
+	writer.Header().Set("Cache-Control", "no-store")
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("X-Accel-Buffering", "no")
+	writer.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	go func() {
+		<-request.Context().Done()
+		session.Close()
+	}()
+	var metadata M.Metadata
+	metadata.Source = sHttp.SourceAddress(request)
+	conn := v2rayhttp.NewHTTP2Wrapper(&v2rayhttp.ServerHTTPConn{
+		HTTP2Conn: v2rayhttp.NewHTTPConn(session.reader, writer),
+		Flusher:   flusher,
+	})
+	s.handler.NewConnection(request.Context(), conn, metadata)
+	conn.CloseWrapper()
+}
+
+func (s *Server) serveUpload(writer http.ResponseWriter, request *http.Request, sessionID string, seq uint64) {
+	session, loaded := s.session(sessionID)
+	if !loaded {
+		writer.WriteHeader(http.StatusNotFound)
+		s.handler.NewError(request.Context(), E.New("process upload from ", request.RemoteAddr, ": unknown session"))
+		return
+	}
+	payload, err := io.ReadAll(io.LimitReader(request.Body, maxUploadSize+1))
+	if err != nil {
+		s.handler.NewError(request.Context(), E.Cause(err, "read upload from ", request.RemoteAddr))
+		return
+	}
+	if len(payload) > maxUploadSize {
+		writer.WriteHeader(http.StatusRequestEntityTooLarge)
+		s.handler.NewError(request.Context(), E.New("process upload from ", request.RemoteAddr, ": request body too large"))
+		return
+	}
+	err = session.push(seq, payload)
+	if err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		s.handler.NewError(request.Context(), E.Cause(err, "process upload from ", request.RemoteAddr))
+		return
+	}
+	writer.Header().Set("Cache-Control", "no-store")
+	writer.WriteHeader(http.StatusOK)
+}
+
+// session returns the session with id, uploads are only accepted while its download request is active,
+// so that unauthenticated clients can not make the server buffer data.
+func (s *Server) session(sessionID string) (*session, bool) {
+	s.sessionAccess.Lock()
+	defer s.sessionAccess.Unlock()
+	currentSession, loaded := s.sessions[sessionID]
+	return currentSession, loaded
+}
+
+func (s *Server) startDownload(sessionID string) (*session, bool) {
+	s.sessionAccess.Lock()
+	defer s.sessionAccess.Unlock()
+	if _, loaded := s.sessions[sessionID]; loaded {
+		return nil, false
+	}
+	currentSession := newSession()
+	s.sessions[sessionID] = currentSession
+	return currentSession, true
+}
+
+func (s *Server) closeSession(sessionID string, currentSession *session) {
+	s.sessionAccess.Lock()
+	if s.sessions[sessionID] == currentSession {
+		delete(s.sessions, sessionID)
+	}
+	s.sessionAccess.Unlock()
+	currentSession.Close()
+}
+
+func (s *Server) fallbackRequest(ctx context.Context, writer http.ResponseWriter, request *http.Request, statusCode int, err error) {
+	conn := v2rayhttp.NewHTTPConn(request.Body, writer)
+	fErr := s.handler.FallbackConnection(ctx, &conn, M.Metadata{})
+	if fErr == nil {
+		return
+	} else if fErr == os.ErrInvalid {
+		fErr = nil
+	}
+	if statusCode > 0 {
+		writer.WriteHeader(statusCode)
+	}
+	s.handler.NewError(request.Context(), E.Cause(E.Errors(err, E.Cause(fErr, "fallback connection")), "process connection from ", request.RemoteAddr))
+}
+
+func (s *Server) Network() []string {
+	return []string{N.NetworkTCP}
+}
+
+func (s *Server) Serve(listener net.Listener) error {
+	if s.tlsConfig != nil {
+		if len(s.tlsConfig.NextProtos()) == 0 {
+			s.tlsConfig.SetNextProtos([]string{"http/1.1"})
+		}
+		listener = aTLS.NewListener(listener, s.tlsConfig)
+	}
+	return s.httpServer.Serve(listener)
+}
+
+func (s *Server) ServePacket(listener net.PacketConn) error {
+	return os.ErrInvalid
+}
+
+func (s *Server) Close() error {
+	s.sessionAccess.Lock()
+	for sessionID, currentSession := range s.sessions {
+		delete(s.sessions, sessionID)
+		currentSession.Close()
+	}
+	s.sessionAccess.Unlock()
+	return common.Close(common.PtrOrNil(s.httpServer))
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"io"
 	"net"
+	"sync"
 
 	"github.com/sagernet/sing-vmess"
 	"github.com/sagernet/sing/common/auth"
@@ -19,10 +20,11 @@ import (
 )
 
 type Service[T comparable] struct {
-	userMap  map[[16]byte]T
-	userFlow map[T]string
-	logger   logger.Logger
-	handler  Handler
+	userAccess sync.RWMutex
+	userMap    map[[16]byte]T
+	userFlow   map[T]string
+	logger     logger.Logger
+	handler    Handler
 }
 
 type Handler interface {
@@ -49,8 +51,10 @@ func (s *Service[T]) UpdateUsers(userList []T, userUUIDList []string, userFlowLi
 		userMap[userID] = userName
 		userFlowMap[userName] = userFlowList[i]
 	}
+	s.userAccess.Lock()
 	s.userMap = userMap
 	s.userFlow = userFlowMap
+	s.userAccess.Unlock()
 }
 
 var _ N.TCPConnectionHandler = (*Service[int])(nil)
@@ -60,14 +64,16 @@ func (s *Service[T]) NewConnection(ctx context.Context, conn net.Conn, metadata
 	if err != nil {
 		return err
 	}
+	s.userAccess.RLock()
 	user, loaded := s.userMap[request.UUID]
+	userFlow := s.userFlow[user]
+	s.userAccess.RUnlock()
 	if !loaded {
 		return E.New("unknown UUID: ", uuid.FromBytesOrNil(request.UUID[:]))
 	}
 	ctx = auth.ContextWithUser(ctx, user)
 	metadata.Destination = request.Destination
 
-	userFlow := s.userFlow[user]
 	if request.Flow == FlowVision && request.Command == vmess.NetworkUDP {
 		return E.New(FlowVision, " flow does not support UDP")
 	} else if request.Flow != userFlow {
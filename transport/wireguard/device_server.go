@@ -0,0 +1,64 @@
+//go:build with_gvisor
+
+package wireguard
+
+import (
+	"os"
+
+	"github.com/sagernet/gvisor/pkg/tcpip/stack"
+	"github.com/sagernet/sing-tun"
+	"github.com/sagernet/sing/common/buf"
+	wgTun "github.com/sagernet/wireguard-go/tun"
+)
+
+var _ wgTun.Device = (*ServerDevice)(nil)
+
+// ServerDevice is a WireGuard device without a network stack of its own,
+// its link endpoint is served by a tun stack accepting all decrypted flows.
+type ServerDevice struct {
+	StackDevice
+}
+
+func NewServerDevice(mtu uint32) (*ServerDevice, error) {
+	return &ServerDevice{StackDevice{
+		mtu:            mtu,
+		events:         make(chan wgTun.Event, 1),
+		outbound:       make(chan stack.PacketBufferPtr, 256),
+		packetOutbound: make(chan *buf.Buffer, 256),
+		done:           make(chan struct{}),
+	}}, nil
+}
+
+func (w *ServerDevice) Tun() tun.Tun {
+	return (*serverTun)(w)
+}
+
+func (w *ServerDevice) Close() error {
+	select {
+	case <-w.done:
+		return os.ErrClosed
+	default:
+	}
+	close(w.done)
+	return nil
+}
+
+var _ tun.GVisorTun = (*serverTun)(nil)
+
+type serverTun ServerDevice
+
+func (t *serverTun) Read(p []byte) (n int, err error) {
+	return 0, os.ErrInvalid
+}
+
+func (t *serverTun) Write(p []byte) (n int, err error) {
+	return 0, os.ErrInvalid
+}
+
+func (t *serverTun) Close() error {
+	return nil
+}
+
+func (t *serverTun) NewEndpoint() (stack.LinkEndpoint, error) {
+	return (*wireEndpoint)(&t.StackDevice), nil
+}
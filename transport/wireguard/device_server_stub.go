@@ -0,0 +1,19 @@
+//go:build !with_gvisor
+
+package wireguard
+
+import (
+	"github.com/sagernet/sing-tun"
+)
+
+type ServerDevice struct {
+	Device
+}
+
+func NewServerDevice(mtu uint32) (*ServerDevice, error) {
+	return nil, tun.ErrGVisorNotIncluded
+}
+
+func (w *ServerDevice) Tun() tun.Tun {
+	return nil
+}
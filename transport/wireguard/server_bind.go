@@ -0,0 +1,104 @@
+package wireguard
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/wireguard-go/conn"
+)
+
+var _ conn.Bind = (*ServerBind)(nil)
+
+type ServerBind struct {
+	conn   net.PacketConn
+	access sync.Mutex
+	done   chan struct{}
+}
+
+func NewServerBind(conn net.PacketConn) *ServerBind {
+	return &ServerBind{
+		conn: conn,
+	}
+}
+
+func (s *ServerBind) Open(port uint16) (fns []conn.ReceiveFunc, actualPort uint16, err error) {
+	s.access.Lock()
+	defer s.access.Unlock()
+	if s.done != nil {
+		select {
+		case <-s.done:
+		default:
+			return nil, 0, conn.ErrBindAlreadyOpen
+		}
+	}
+	s.done = make(chan struct{})
+	err = s.conn.SetReadDeadline(time.Time{})
+	if err != nil {
+		return
+	}
+	return []conn.ReceiveFunc{s.receive}, M.SocksaddrFromNet(s.conn.LocalAddr()).Port, nil
+}
+
+func (s *ServerBind) receive(packets [][]byte, sizes []int, eps []conn.Endpoint) (count int, err error) {
+	n, addr, err := s.conn.ReadFrom(packets[0])
+	if err != nil {
+		select {
+		case <-s.done:
+			err = net.ErrClosed
+		default:
+		}
+		return
+	}
+	sizes[0] = n
+	// clear the reserved bytes so that clients with `reserved` set are accepted
+	if n > 3 {
+		b := packets[0]
+		b[1] = 0
+		b[2] = 0
+		b[3] = 0
+	}
+	eps[0] = Endpoint(M.SocksaddrFromNet(addr).Unwrap())
+	count = 1
+	return
+}
+
+// Close interrupts pending reads without closing the listener, which is owned by the inbound.
+func (s *ServerBind) Close() error {
+	s.access.Lock()
+	defer s.access.Unlock()
+	if s.done == nil {
+		return nil
+	}
+	select {
+	case <-s.done:
+		return nil
+	default:
+		close(s.done)
+	}
+	return s.conn.SetReadDeadline(time.Now())
+}
+
+func (s *ServerBind) SetMark(mark uint32) error {
+	return nil
+}
+
+func (s *ServerBind) Send(bufs [][]byte, ep conn.Endpoint) error {
+	destination := M.Socksaddr(ep.(Endpoint)).UDPAddr()
+	for _, b := range bufs {
+		_, err := s.conn.WriteTo(b, destination)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ServerBind) ParseEndpoint(endpoint string) (conn.Endpoint, error) {
+	return Endpoint(M.ParseSocksaddr(endpoint)), nil
+}
+
+func (s *ServerBind) BatchSize() int {
+	return 1
+}